@@ -1,104 +1,151 @@
 package main
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"strings"
 
-	Oauth "github.com/dunebi/myapi-oauth"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type Account struct {
 	gorm.Model
-	Email string `json:"email"`
-	CA    string
+	Email        string `json:"email"`
+	CA           string
+	Role         string `gorm:"size:32;default:viewer" json:"role"`
+	PasswordHash string `json:"-"` // CA가 LOCAL인 계정만 사용
 }
 
-func (account *Account) DbProcess(c *gin.Context) {
+/* OAuth로 받은 계정이 DB에 없으면 생성(Register)하고, 바로 JWT 토큰 발행(Login). verified는 제공자가 email을 확인했는지 */
+func (account *Account) DbProcess(c *gin.Context, verified bool) {
 	var dbAccount Account
 	db.Where("Email = ? AND CA = ?", account.Email, account.CA).Find(&dbAccount)
 
-	// DB계정이 없다면 Register
-	if dbAccount.ID == 0 {
+	created := false
+	if dbAccount.ID == 0 { // DB계정이 없다면 Register
+		account.Role = RoleViewer
+		if verified && IsBootstrapAdmin(account.Email, account.CA) {
+			account.Role = RoleAdmin
+		}
+
 		result := db.Create(&account)
 		if result.Error != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"msg": "Error on Creating Account",
-			})
+			AbortWithInternalError(c, result.Error, "Error on Creating Account")
 			return
 		}
+		dbAccount = *account
+		created = true
+	} else if dbAccount.Role != RoleAdmin && verified && IsBootstrapAdmin(dbAccount.Email, dbAccount.CA) {
+		// ADMIN_EMAILS에 등록되고 ADMIN_CA가 확인한 계정은 로그인 시 admin으로 승격
+		db.Model(&dbAccount).Update("role", RoleAdmin)
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"msg":         "New account created. Please re-login",
-			"accountInfo": account,
-		})
+	IssueSession(c, http.StatusOK, dbAccount, created)
+}
+
+/* 로그인 성공 시 Access Token(JWT)과 Refresh Token을 발급해서 응답 */
+func IssueSession(c *gin.Context, status int, account Account, created bool) {
+	jwtToken, err := GenerateToken(account.Email, account.CA, account.Role)
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on Create JWT token")
 		return
 	}
 
-	// DB에 계정이 있다면 Login(JWT토큰 발행)
-	jwtToken, err := GenerateToken(account.Email, account.CA)
+	refreshToken, _, err := IssueRefreshToken(db, account.ID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-			"msg": "Error on Create JWT token",
-		})
+		AbortWithInternalError(c, err, "Error on Create refresh token")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"JWT":     jwtToken,
-		"account": dbAccount,
+	c.JSON(status, gin.H{
+		"JWT":           jwtToken,
+		"refresh_token": refreshToken,
+		"account":       newAccountResponse(account),
+		"new_account":   created,
 	})
 }
 
+/* OAuth 로그인 페이지로 이동. state(와 PKCE verifier)는 서명된 cookie에 저장 */
 func Login() gin.HandlerFunc {
-	oauthFunc := Oauth.LoginProcess()
-
 	return func(c *gin.Context) {
-		newCA := c.Param("CA")
-		newCA = strings.ToUpper(newCA)
+		provider, err := GetOAuthProvider(c.Param("CA"))
+		if err != nil {
+			AbortWithError(c, InvalidParameter("CA", err.Error()))
+			return
+		}
 
-		url, err := oauthFunc(newCA)
+		url, signedState, err := provider.AuthCodeURL()
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"msg": err.Error(),
-			})
+			AbortWithInternalError(c, err, "Error on Create oauth state")
 			return
 		}
-		c.Redirect(http.StatusPermanentRedirect, url)
+
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     oauthStateCookie,
+			Value:    signedState,
+			Path:     "/auth/callback",
+			MaxAge:   int(oauthStateTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   c.Request.TLS != nil,
+			SameSite: http.SameSiteLaxMode, // 제공자에서 돌아오는 redirect에도 cookie가 전달되어야 함
+		})
+		c.Redirect(http.StatusFound, url)
 	}
 }
 
-func LoginCallback() gin.HandlerFunc {
-	oauthFunc := Oauth.LoginCallbackProcess(&Account{})
-
+/* 호출된 callback 경로(/auth/callback/google 등)의 CA로 로그인 처리 */
+func LoginCallback(ca string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		code := c.Query("code")
-		accountResp, err := oauthFunc(code)
+		provider, err := GetOAuthProvider(ca)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"msg": err.Error(),
-			})
+			AbortWithInternalError(c, err, "Unknown oauth provider")
 			return
 		}
 
-		account := accountResp.Interface().(*Account)
-		account.CA = os.Getenv("CA")
+		signedState, err := c.Cookie(oauthStateCookie)
+		if err != nil {
+			AbortWithError(c, NewApiError(http.StatusBadRequest, CodeInvalidOAuthState, "No oauth state. Please login again"))
+			return
+		}
+		// state는 한 번만 사용
+		http.SetCookie(c.Writer, &http.Cookie{Name: oauthStateCookie, Path: "/auth/callback", MaxAge: -1})
+
+		state, err := provider.CheckState(signedState, c.Query("state"))
+		if err != nil {
+			AbortWithError(c, NewApiError(http.StatusBadRequest, CodeInvalidOAuthState, err.Error()))
+			return
+		}
+
+		email, verified, err := provider.FetchEmail(c.Request.Context(), c.Query("code"), state.Verifier)
+		if errors.Is(err, errEmailNotVerified) {
+			AbortWithError(c, NewApiError(http.StatusForbidden, CodeEmailNotVerified, ca+" email is not verified"))
+			return
+		} else if err != nil {
+			AbortWithError(c, NewApiError(http.StatusBadGateway, CodeUpstreamError, err.Error()))
+			return
+		} else if email == "" {
+			AbortWithError(c, NewApiError(http.StatusUnprocessableEntity, CodeUpstreamError, "No email in "+ca+" account"))
+			return
+		}
 
-		account.DbProcess(c)
+		account := &Account{Email: email, CA: provider.CA}
+		account.DbProcess(c, verified)
 	}
 }
 
-/* 토큰을 사용한 미들웨어에서의 계정 검증 */
+/* 토큰 또는 API Key를 사용한 미들웨어에서의 계정 검증 */
 func AuthorizeAccount() gin.HandlerFunc {
 	return func(c *gin.Context) { // Handler를 return
+		// 스크립트, CI 등은 Bearer 토큰 대신 X-API-Key header 사용 가능
+		if apiKey := c.Request.Header.Get("X-API-Key"); apiKey != "" {
+			authorizeApiKey(c, apiKey)
+			return
+		}
+
 		clientToken := c.Request.Header.Get("Authorization") // Context의 header 내용 중 key가 "Authorization"인 내용의 value를 가져옴 --> 이게 Token이 됨!
 		if clientToken == "" {                               // No Header
-			c.JSON(http.StatusForbidden, gin.H{
-				"msg": "No Authorization header provided",
-			})
-			c.Abort()
+			abortUnauthorized(c, "No Authorization header provided")
 			return
 		}
 
@@ -107,21 +154,26 @@ func AuthorizeAccount() gin.HandlerFunc {
 		if len(extractedToken) == 2 { // {"Bearer ", [토큰 내용 string]}
 			clientToken = strings.TrimSpace(extractedToken[1])
 		} else { // Invalid Token Format
-			c.JSON(http.StatusBadRequest, gin.H{
-				"msg": "Incorrect Format of Authorization Token",
-			})
-			c.Abort()
+			abortUnauthorized(c, "Incorrect Format of Authorization Token")
 			return
 		}
 
 		claims, err := ValidateToken(clientToken)
 		if err != nil { // Invalid Token
-			c.JSON(http.StatusUnauthorized, err.Error())
-			c.Abort()
+			abortUnauthorized(c, err.Error())
+			return
+		}
+
+		if IsTokenRevoked(claims.Id) { // 로그아웃된 토큰
+			abortUnauthorized(c, "jwt is revoked")
 			return
 		}
 
 		c.Set("email", claims.Email)
+		c.Set("ca", claims.CA)
+		c.Set("role", claims.Role)
+		c.Set("jti", claims.Id)
+		c.Set("exp", claims.ExpiresAt)
 
 		c.Next()
 	}
@@ -1,13 +1,23 @@
 package main
 
-/*
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
 func TestCheckPassword(t *testing.T) {
 	pwd, err := HashPassword("password")
 	assert.NoError(t, err)
 
 	account := Account{
-		Account_Id:  "gotest",
-		Account_Pwd: pwd,
+		Email:        "gotest",
+		PasswordHash: pwd,
 	}
 	err = account.CheckPassword("password")
 	assert.NoError(t, err)
@@ -18,40 +28,76 @@ func TestCheckPasswordInvalid(t *testing.T) {
 	assert.NoError(t, err)
 
 	account := Account{
-		Account_Id:  "gotest",
-		Account_Pwd: pwd,
+		Email:        "gotest",
+		PasswordHash: pwd,
 	}
 	err = account.CheckPassword("passwordss")
 	assert.Equal(t, "crypto/bcrypt: hashedPassword is not the hash of the given password", err.Error())
 }
 
 func TestRegister(t *testing.T) {
-	var result Account
-	account := Account{
-		Account_Id:  "gotest",
-		Account_Pwd: "1234",
+	var result map[string]interface{}
+	data := passwordData{
+		Email:    "register@test.com",
+		Password: "12345678",
 	}
 
-	payload, err := json.Marshal(&account)
+	payload, err := json.Marshal(&data)
 	assert.NoError(t, err)
 
-	request, err := http.NewRequest("POST", "/api/public/register/", bytes.NewBuffer(payload))
+	request, err := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
 	assert.NoError(t, err)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = request
 
-	err = InitDB() // 전역변수 db를 여기서 초기화해야 main.go 코드의 db변수가 문제없이 작동(없으면 runtime error가 났음)
+	Register(c) // 회원가입 후 토큰을 반환
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	err = json.Unmarshal(w.Body.Bytes(), &result)
 	assert.NoError(t, err)
 
-	Register(c) // 회원가입 후 JSON을 반환
-	assert.Equal(t, http.StatusOK, w.Code)
+	claims, err := ValidateToken(result["JWT"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, data.Email, claims.Email)
+	assert.Equal(t, LocalCA, claims.CA)
 
-	err = json.Unmarshal(w.Body.Bytes(), &result)
+	// 같은 email로 다시 가입
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
+	Register(c)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var account Account
+	db.Where("Email = ? AND CA = ?", data.Email, LocalCA).Find(&account)
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestRegisterBootstrapAdmin(t *testing.T) {
+	t.Setenv("ADMIN_EMAILS", "bootstrap@test.com")
+	router := gin.Default()
+	router.POST("/auth/register", Register)
+
+	// 누구나 가입할 수 있으므로 ADMIN_EMAILS의 email이어도 admin이 되지 않음
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/register", bytes.NewBufferString(`{"email": "bootstrap@test.com", "password": "12345678"}`))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	claims, err := ValidateToken(result["JWT"].(string))
 	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, claims.Role)
 
-	assert.Equal(t, account.Account_Id, result.Account_Id)
+	var account Account
+	db.Where("Email = ? AND CA = ?", "bootstrap@test.com", LocalCA).Find(&account)
+	assert.Equal(t, RoleViewer, account.Role)
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
 }
 
 func TestRegisterInvalidJSON(t *testing.T) {
@@ -60,7 +106,7 @@ func TestRegisterInvalidJSON(t *testing.T) {
 	payload, err := json.Marshal(&account)
 	assert.NoError(t, err)
 
-	request, err := http.NewRequest("POST", "/api/public/register/", bytes.NewBuffer(payload))
+	request, err := http.NewRequest("POST", "/auth/register", bytes.NewBuffer(payload))
 	assert.NoError(t, err)
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -69,4 +115,26 @@ func TestRegisterInvalidJSON(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
-*/
+
+func TestPasswordLogin(t *testing.T) {
+	pwd, err := HashPassword("password")
+	assert.NoError(t, err)
+	account := Account{Email: "login@test.com", CA: LocalCA, Role: RoleViewer, PasswordHash: pwd}
+	db.Create(&account)
+
+	router := gin.Default()
+	router.POST("/auth/token", PasswordLogin)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/token", bytes.NewBufferString(`{"email": "login@test.com", "password": "password"}`))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/auth/token", bytes.NewBufferString(`{"email": "login@test.com", "password": "wrong password"}`))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
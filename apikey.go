@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiKeyPrefix = "myapi_"
+
+// Account별 API Key. 원문은 발급 시 한 번만 보여주고 hash만 저장
+type ApiKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	AccountID  uint       `gorm:"index" json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `gorm:"size:16" json:"prefix"` // 목록에서 키를 구분하기 위한 앞부분
+	KeyHash    string     `gorm:"size:64;uniqueIndex" json:"-"`
+	Role       string     `gorm:"size:32" json:"role"` // 키의 권한. 계정 권한보다 높을 수 없음
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type apiKeyData struct {
+	Name          string `json:"name" binding:"required"`
+	Role          string `json:"role"`                                     // 없으면 계정 권한
+	ExpiresInDays int    `json:"expires_in_days" binding:"min=0,max=3650"` // 0이면 만료 없음
+}
+
+/* X-API-Key header로 계정 검증. AuthorizeAccount에서 호출 */
+func authorizeApiKey(c *gin.Context, key string) {
+	var apiKey ApiKey
+	db.Where("key_hash = ?", hashToken(key)).Find(&apiKey)
+
+	now := time.Now()
+	if apiKey.ID == 0 || apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(now)) {
+		abortUnauthorized(c, "invalid api key")
+		return
+	}
+
+	var account Account
+	db.Where("id = ?", apiKey.AccountID).Find(&account)
+	if account.ID == 0 {
+		abortUnauthorized(c, "invalid api key")
+		return
+	}
+
+	// 계정 권한이 낮아졌다면 낮은 쪽을 사용
+	role := apiKey.Role
+	if !HasRole(account.Role, role) {
+		role = account.Role
+	}
+
+	db.Model(&apiKey).Update("last_used_at", now)
+
+	c.Set("email", account.Email)
+	c.Set("ca", account.CA)
+	c.Set("role", role)
+	c.Set("api_key_id", apiKey.ID)
+
+	c.Next()
+}
+
+/* 토큰의 email, CA로 현재 로그인한 Account 조회 */
+func currentAccount(c *gin.Context) (account Account) {
+	db.Where("Email = ? AND CA = ?", c.GetString("email"), c.GetString("ca")).Find(&account)
+	return
+}
+
+/* 내 API Key 목록 */
+func ReadApiKey(c *gin.Context) {
+	account := currentAccount(c)
+	if account.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeAccountNotFound, "No such account"))
+		return
+	}
+
+	var apiKeys []ApiKey
+	result := db.Where("account_id = ?", account.ID).Order("id asc").Find(&apiKeys)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+/* API Key 발급. 응답의 key는 다시 조회할 수 없음 */
+func AddApiKey(c *gin.Context) {
+	var data apiKeyData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+
+	// API Key로 다른 API Key를 만들 수 없음
+	if _, ok := c.Get("api_key_id"); ok {
+		AbortWithError(c, NewApiError(http.StatusForbidden, CodeForbidden, "Login with JWT to create api key"))
+		return
+	}
+
+	account := currentAccount(c)
+	if account.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeAccountNotFound, "No such account"))
+		return
+	}
+
+	if data.Role == "" {
+		data.Role = account.Role
+	}
+	if _, ok := roleLevels[data.Role]; !ok {
+		AbortWithError(c, invalidRole("No such role: "+data.Role))
+		return
+	} else if !HasRole(account.Role, data.Role) {
+		AbortWithError(c, NewApiError(http.StatusForbidden, CodeForbidden, "Cannot create api key with role: "+data.Role))
+		return
+	}
+
+	random, err := newRandomToken(32)
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on Create api key")
+		return
+	}
+	key := apiKeyPrefix + random
+
+	apiKey := ApiKey{
+		AccountID: account.ID,
+		Name:      data.Name,
+		Prefix:    key[:len(apiKeyPrefix)+6],
+		KeyHash:   hashToken(key),
+		Role:      data.Role,
+	}
+	if data.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, data.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	result := db.Create(&apiKey)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Error on Create api key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"key":    key,
+		"apiKey": apiKey,
+	})
+}
+
+/* API Key 폐기 */
+func DeleteApiKey(c *gin.Context) {
+	account := currentAccount(c)
+
+	var apiKey ApiKey
+	db.Where("id = ? AND account_id = ?", c.Param("id"), account.ID).Find(&apiKey)
+	if account.ID == 0 || apiKey.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeApiKeyNotFound, "No such api key"))
+		return
+	}
+
+	if apiKey.RevokedAt == nil {
+		db.Model(&apiKey).Update("revoked_at", time.Now())
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "Api Key Revoked",
+	})
+}
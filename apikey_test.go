@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApiKey(t *testing.T) {
+	account := Account{Email: "apikey@test.com", CA: LocalCA, Role: RoleEditor}
+	db.Create(&account)
+	token, err := GenerateToken(account.Email, account.CA, account.Role)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/auth/apikeys/", AddApiKey)
+	router.DELETE("/auth/apikeys/:id", DeleteApiKey)
+	router.GET("/api/employee/", RequireRole(RoleViewer), ReadEmployee)
+	router.POST("/api/employee/", RequireRole(RoleEditor), AddEmployee)
+
+	// 계정보다 높은 권한의 키는 만들 수 없음
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/apikeys/", bytes.NewBufferString(`{"name": "ci", "role": "admin"}`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var result struct {
+		Key    string `json:"key"`
+		ApiKey ApiKey `json:"apiKey"`
+	}
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/auth/apikeys/", bytes.NewBufferString(`{"name": "ci", "role": "viewer"}`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	// viewer 키로 조회는 가능, 추가는 불가
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", "/api/employee/", nil)
+	request.Header.Add("X-API-Key", result.Key)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/api/employee/", bytes.NewBufferString(`[{"ename": "Key Test"}]`))
+	request.Header.Add("X-API-Key", result.Key)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 폐기한 키는 사용 불가
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("DELETE", "/auth/apikeys/"+strconv.FormatUint(uint64(result.ApiKey.ID), 10), nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", "/api/employee/", nil)
+	request.Header.Add("X-API-Key", result.Key)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	db.Where("account_id = ?", account.ID).Delete(&ApiKey{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
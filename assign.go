@@ -1,183 +1,489 @@
 package main
 
 import (
-	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-/* 기존 사원에게 부서 추가(이름을 Param으로 받아옴) */
-func AddEmployeeDepartment(c *gin.Context) {
-	eName := c.Param("name")
-	dName := c.Param("department")
+// 사원의 부서 소속 (employee_departments). 소속이 끝나도 삭제하지 않고 end_date를 기록
+type Assignment struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	EmployeeID   uint           `gorm:"index" json:"employeeId"`
+	DepartmentID uint           `gorm:"index" json:"departmentId"`
+	Department   *Department    `json:"department,omitempty"`
+	Role         string         `gorm:"size:64" json:"role"`
+	Allocation   int            `gorm:"default:100" json:"allocation"` // 업무 비중(%)
+	IsPrimary    bool           `json:"isPrimary"`
+	StartDate    time.Time      `json:"startDate"`
+	EndDate      gorm.DeletedAt `gorm:"index" json:"endDate"` // null이면 현재 소속. 끝난 소속은 기본 조회(Preload 등)에서 제외
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+func (Assignment) TableName() string { return "employee_departments" }
+
+/* Association Append 등으로 만들어진 소속도 시작일과 비중을 가지도록 */
+func (assignment *Assignment) BeforeCreate(tx *gorm.DB) error {
+	if assignment.StartDate.IsZero() {
+		assignment.StartDate = time.Now()
+	}
+	if assignment.Allocation == 0 {
+		assignment.Allocation = 100
+	}
+	return nil
+}
+
+type assignData struct {
+	Role       string `json:"role" binding:"max=64"`
+	Allocation int    `json:"allocation" binding:"min=0,max=100"` // 0이면 100
+	IsPrimary  bool   `json:"is_primary"`
+	StartDate  string `json:"start_date"` // 2006-01-02 또는 RFC3339. 없으면 지금
+}
 
+// 소속 정보 수정. 보낸 항목만 수정
+type assignUpdateData struct {
+	Role       *string `json:"role" binding:"omitempty,max=64"`
+	Allocation *int    `json:"allocation" binding:"omitempty,min=1,max=100"`
+	IsPrimary  *bool   `json:"is_primary"`
+}
+
+/* 날짜(2006-01-02)나 RFC3339 시간 */
+func parseDate(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+/* 이름으로 사원 조회. 같은 이름이 여러 명이면 409 */
+func findAssignEmployeeByName(c *gin.Context) (employee Employee, ok bool) {
 	var employees []Employee
-	db.Where("Employee_Name = ?", eName).Preload("Employee_Departments").Find(&employees)
+	db.Where("Employee_Name = ?", c.Param("name")).Preload("Employee_Departments").Find(&employees)
 	if len(employees) > 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg":     "There're employees with same name",
-			"can use": "/api/assign/:id/:department",
-			"data":    employees,
-		})
-		c.Abort()
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeAmbiguousEmployee, "There're employees with same name").WithDetails(gin.H{
+			"can use": "/api/assign/id/:eid/:department",
+			"data":    newEmployeeResponses(employees),
+		}))
 		return
 	} else if len(employees) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "No such employee",
-		})
-		c.Abort()
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
 		return
 	}
+	return employees[0], true
+}
 
+func findAssignEmployeeById(c *gin.Context) (employee Employee, ok bool) {
+	db.Where("id = ?", c.Param("eid")).Find(&employee)
+	if employee.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
+		return
+	}
+	return employee, true
+}
+
+/* 현재 소속 조회. 없으면 404 */
+func findActiveAssignment(c *gin.Context, employee Employee, dName string) (assignment Assignment, ok bool) {
 	var department Department
 	db.Where("Department_Name = ?", dName).Find(&department)
-	if department.ID == 0 {
-		if dName == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"msg": "No Department Name",
-			})
-			c.Abort()
-			return
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"msg": "No such department",
-			})
-			c.Abort()
+	if department.ID != 0 {
+		db.Where("employee_id = ? AND department_id = ?", employee.ID, department.ID).Find(&assignment)
+	}
+	if assignment.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeAssignmentNotFound, "This Employee is not in such Department or No such department"))
+		return
+	}
+	assignment.Department = &department
+	return assignment, true
+}
+
+/* 기존 사원에게 부서 추가(이름을 Param으로 받아옴) */
+func AddEmployeeDepartment(c *gin.Context) {
+	employee, ok := findAssignEmployeeByName(c)
+	if !ok {
+		return
+	}
+	assignEmployee(c, employee)
+}
+
+/* 사원에게 부서 만들어주기(ID) */
+func AddEmployeeDepartmentById(c *gin.Context) {
+	employee, ok := findAssignEmployeeById(c)
+	if !ok {
+		return
+	}
+	assignEmployee(c, employee)
+}
+
+/* 사원을 부서에 소속시킴. body(role, allocation, is_primary, start_date)는 선택 */
+func assignEmployee(c *gin.Context, employee Employee) {
+	var data assignData
+	if c.Request.ContentLength > 0 {
+		err := c.ShouldBindJSON(&data)
+		if err != nil {
+			AbortWithBindError(c, err)
 			return
 		}
 	}
+	startDate, ok := parseStartDate(c, data.StartDate, "start_date")
+	if !ok {
+		return
+	}
 
-	db.Model(&employees[0]).Association("Employee_Departments").Append(&department)
+	assignment, ok := createAssignment(c, employee, data, startDate)
+	if !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"employee":   eName,
-		"department": dName,
+		"employee":   employee.Employee_Name,
+		"department": assignment.Department.Department_Name,
+		"assignment": newAssignmentResponse(assignment),
 	})
 }
 
-/* 사원에게 부서 만들어주기(ID) */
-func AddEmployeeDepartmentById(c *gin.Context) {
-	eid := c.Param("eid")
-	department_name := c.Param("department")
+// v2 소속 추가 body. 모두 선택
+type assignRequest struct {
+	Role       string `json:"role" binding:"max=64"`
+	Allocation int    `json:"allocation" binding:"min=0,max=100"` // 0이면 100
+	IsPrimary  bool   `json:"isPrimary"`
+	StartDate  string `json:"startDate"` // 2006-01-02 또는 RFC3339. 없으면 지금
+}
 
-	var employee Employee
-	var department Department
+/* v2 사원(:eid)을 부서에 소속시킴. 만든 소속을 201로 응답 */
+func AssignEmployeeV2(c *gin.Context) {
+	var data assignRequest
+	if c.Request.ContentLength > 0 {
+		err := c.ShouldBindJSON(&data)
+		if err != nil {
+			AbortWithBindError(c, err)
+			return
+		}
+	}
+	startDate, ok := parseStartDate(c, data.StartDate, "startDate")
+	if !ok {
+		return
+	}
 
-	db.Where("id = ?", eid).Find(&employee)
-	db.Where("Department_Name = ?", department_name).Find(&department)
+	employee, ok := findAssignEmployeeById(c)
+	if !ok {
+		return
+	}
+	assignment, ok := createAssignment(c, employee, assignData{Role: data.Role, Allocation: data.Allocation, IsPrimary: data.IsPrimary}, startDate)
+	if !ok {
+		return
+	}
 
-	if employee.ID == 0 {
-		log.Println("Id error at employee", employee.ID, department.ID)
+	c.JSON(http.StatusCreated, newAssignmentResponse(assignment))
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "error allocate department to employee",
-		})
-		c.Abort()
-		return
+/* 시작일. 없으면 지금 */
+func parseStartDate(c *gin.Context, value string, field string) (time.Time, bool) {
+	if value == "" {
+		return time.Now(), true
 	}
+	startDate, err := parseDate(value)
+	if err != nil {
+		AbortWithError(c, fieldError(field, "date", field+" should be 2006-01-02 or RFC3339"))
+		return startDate, false
+	}
+	return startDate, true
+}
 
+/* :department 부서에 소속을 만들고 부서를 채워서 돌려줌. 실패하면 에러 응답 후 false */
+func createAssignment(c *gin.Context, employee Employee, data assignData, startDate time.Time) (Assignment, bool) {
+	dName := c.Param("department")
+
+	var department Department
+	db.Where("Department_Name = ?", dName).Find(&department)
 	if department.ID == 0 {
-		if department_name == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"msg": "No Department Name",
-			})
-			c.Abort()
-			return
+		if dName == "" {
+			AbortWithError(c, InvalidParameter("department", "No Department Name"))
 		} else {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-				"msg": "Use Correct Department Name",
-			})
-			return
+			AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "Use Correct Department Name"))
 		}
+		return Assignment{}, false
 	}
 
-	db.Model(&employee).Association("Employee_Departments").Append(&department)
-	c.JSON(http.StatusOK, gin.H{
-		"employee":   employee.Employee_Name,
-		"department": department.Department_Name,
+	var existing Assignment
+	db.Where("employee_id = ? AND department_id = ?", employee.ID, department.ID).Find(&existing)
+	if existing.ID != 0 {
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeAssignmentExists, "Employee is already in this department"))
+		return existing, false
+	}
+
+	assignment := Assignment{
+		EmployeeID:   employee.ID,
+		DepartmentID: department.ID,
+		Role:         data.Role,
+		Allocation:   data.Allocation,
+		IsPrimary:    data.IsPrimary,
+		StartDate:    startDate,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 첫 소속은 주 소속
+		var active int64
+		tx.Model(&Assignment{}).Where("employee_id = ?", employee.ID).Count(&active)
+		assignment.IsPrimary = assignment.IsPrimary || active == 0
+		if assignment.IsPrimary {
+			err := clearPrimary(tx, employee.ID)
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Create(&assignment).Error
+		if err != nil {
+			return err
+		}
+		return recordAudit(c, tx, AuditAssign, EntityAssignment, assignment.ID, nil, assignment)
 	})
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on Assign Employee")
+		return assignment, false
+	}
+	assignment.Department = &department
+	return assignment, true
 }
 
+/* 주 소속은 한 개만 */
+func clearPrimary(tx *gorm.DB, employeeID uint) error {
+	return tx.Model(&Assignment{}).Where("employee_id = ? AND is_primary = ?", employeeID, true).Update("is_primary", false).Error
+}
+
+/* 사원을 부서에서 제외시키기(이름). 소속 기록은 남김 */
 func DeleteEmployeeDepartment(c *gin.Context) {
-	eName := c.Param("name")
-	dName := c.Param("department")
+	employee, ok := findAssignEmployeeByName(c)
+	if !ok {
+		return
+	}
+	endAssignment(c, employee)
+}
 
-	var employees []Employee
-	db.Where("Employee_Name = ?", eName).Preload("Employee_Departments").Find(&employees)
-	if len(employees) > 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg":     "There're employees with same name",
-			"can use": "/api/assign/:id/:department",
-			"data":    employees,
-		})
-		c.Abort()
+/* 사원을 부서에서 제외시키기(ID) */
+func DeleteEmployeeDepartmentById(c *gin.Context) {
+	employee, ok := findAssignEmployeeById(c)
+	if !ok {
 		return
-	} else if len(employees) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "No such employee",
-		})
-		c.Abort()
+	}
+	endAssignment(c, employee)
+}
+
+/* 소속 종료 후 응답 */
+func endAssignment(c *gin.Context, employee Employee) {
+	ended, ok := finishAssignment(c, employee)
+	if !ok {
 		return
 	}
 
-	var departments []Department
-	db.Model(&employees[0]).Association("Employee_Departments").Find(&departments)
+	c.JSON(http.StatusOK, gin.H{
+		"msg":        "employee exited by department",
+		"employee":   employee.Employee_Name,
+		"department": c.Param("department"),
+		"assignment": newAssignmentResponse(ended),
+	})
+}
 
-	for i := 0; i < len(departments); i++ {
-		if departments[i].Department_Name == dName {
-			db.Model(&employees[0]).Association("Employee_Departments").Delete(&departments[i])
-			c.JSON(http.StatusOK, gin.H{
-				"msg":        "employee exited by department",
-				"employee":   eName,
-				"department": dName,
-			})
-			return
+/* v2 사원(:eid)의 소속 종료. 끝난 소속을 응답 */
+func EndAssignmentV2(c *gin.Context) {
+	employee, ok := findAssignEmployeeById(c)
+	if !ok {
+		return
+	}
+	ended, ok := finishAssignment(c, employee)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newAssignmentResponse(ended))
+}
+
+/* :department 소속 종료. ?end_date= 로 종료일 지정 가능 (기본 지금). 실패하면 에러 응답 후 false */
+func finishAssignment(c *gin.Context, employee Employee) (Assignment, bool) {
+	dName := c.Param("department")
+
+	endDate := time.Now()
+	if value := c.Query("end_date"); value != "" {
+		var err error
+		endDate, err = parseDate(value)
+		if err != nil || endDate.After(time.Now()) {
+			AbortWithError(c, InvalidParameter("end_date", "end_date should be a past date (2006-01-02 or RFC3339)"))
+			return Assignment{}, false
 		}
 	}
 
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"msg": "This Employee is not in such Department or No such department",
+	assignment, ok := findActiveAssignment(c, employee, dName)
+	if !ok {
+		return assignment, false
+	}
+	if endDate.Before(assignment.StartDate) {
+		AbortWithError(c, InvalidParameter("end_date", "end_date should be after start_date"))
+		return assignment, false
+	}
+
+	ended := assignment
+	ended.EndDate = gorm.DeletedAt{Time: endDate, Valid: true}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Assignment{}).Where("id = ?", assignment.ID).Update("end_date", endDate).Error
+		if err != nil {
+			return err
+		}
+		err = recordAudit(c, tx, AuditUnassign, EntityAssignment, assignment.ID, assignment, ended)
+		if err != nil {
+			return err
+		}
+		if !assignment.IsPrimary {
+			return nil
+		}
+
+		// 주 소속이 끝나면 가장 오래된 다른 소속이 주 소속
+		var next Assignment
+		tx.Where("employee_id = ?", employee.ID).Order("start_date asc, id asc").Limit(1).Find(&next)
+		if next.ID == 0 {
+			return nil
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
 	})
-	c.Abort()
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on End Assignment")
+		return assignment, false
+	}
+	return ended, true
 }
 
-/* 사원을 부서에서 제외시키기(ID) */
-func DeleteEmployeeDepartmentById(c *gin.Context) {
-	eid := c.Param("eid")
-	dName := c.Param("department")
+/* 현재 소속의 role, allocation, is_primary 수정 */
+func UpdateAssignment(c *gin.Context) {
+	var data assignUpdateData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	updated, ok := updateAssignment(c, data)
+	if !ok {
+		return
+	}
 
-	var employee Employee
-	db.Where("id = ?", eid).Find(&employee)
+	c.JSON(http.StatusOK, gin.H{
+		"msg":        "Assignment Update Complete",
+		"assignment": newAssignmentResponse(updated),
+	})
+}
 
-	if employee.ID == 0 {
-		log.Println("Id error at employee", employee.ID)
+// v2 소속 정보 수정 body. 보낸 항목만 수정
+type assignUpdateRequest struct {
+	Role       *string `json:"role" binding:"omitempty,max=64"`
+	Allocation *int    `json:"allocation" binding:"omitempty,min=1,max=100"`
+	IsPrimary  *bool   `json:"isPrimary"`
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "error input on department or employee",
-		})
-		c.Abort()
+/* v2 현재 소속 수정. 수정된 소속을 응답 */
+func UpdateAssignmentV2(c *gin.Context) {
+	var data assignUpdateRequest
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	updated, ok := updateAssignment(c, assignUpdateData{Role: data.Role, Allocation: data.Allocation, IsPrimary: data.IsPrimary})
+	if !ok {
 		return
 	}
 
-	var departments []Department
-	db.Model(&employee).Association("Employee_Departments").Find(&departments)
+	c.JSON(http.StatusOK, newAssignmentResponse(updated))
+}
 
-	for i := 0; i < len(departments); i++ {
-		if departments[i].Department_Name == dName {
-			db.Model(&employee).Association("Employee_Departments").Delete(&departments[i])
-			c.JSON(http.StatusOK, gin.H{
-				"msg":        "employee exited by department",
-				"employee":   employee.Employee_Name,
-				"department": dName,
-			})
-			return
-		}
+/* :eid 사원의 :department 소속 수정. 실패하면 에러 응답 후 false */
+func updateAssignment(c *gin.Context, data assignUpdateData) (Assignment, bool) {
+	employee, ok := findAssignEmployeeById(c)
+	if !ok {
+		return Assignment{}, false
+	}
+	assignment, ok := findActiveAssignment(c, employee, c.Param("department"))
+	if !ok {
+		return assignment, false
+	}
+
+	updates := map[string]interface{}{}
+	if data.Role != nil {
+		updates["role"] = *data.Role
+	}
+	if data.Allocation != nil {
+		updates["allocation"] = *data.Allocation
+	}
+	if data.IsPrimary != nil {
+		updates["is_primary"] = *data.IsPrimary
+	}
+	if len(updates) == 0 {
+		AbortWithError(c, NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed, "Nothing to update"))
+		return assignment, false
+	}
+	// 주 소속을 해제하면 현재 소속이 있는데 주 소속이 없어짐. 다른 소속을 주 소속으로 지정해야 함
+	if data.IsPrimary != nil && !*data.IsPrimary && assignment.IsPrimary {
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeConflict, "Cannot unset the primary assignment. Set another assignment as primary instead"))
+		return assignment, false
 	}
 
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"msg": "This Employee is not in such Department or No such department",
+	var updated Assignment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if data.IsPrimary != nil && *data.IsPrimary {
+			err := clearPrimary(tx, employee.ID)
+			if err != nil {
+				return err
+			}
+		}
+		err := tx.Model(&Assignment{}).Where("id = ?", assignment.ID).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+
+		var after Assignment
+		tx.Where("id = ?", assignment.ID).Preload("Department").Find(&after)
+		err = recordAudit(c, tx, AuditUpdate, EntityAssignment, assignment.ID, assignment, after)
+		updated = after
+		return err
 	})
-	c.Abort()
+	if err != nil {
+		AbortWithInternalError(c, err, "Update error")
+		return assignment, false
+	}
+	return updated, true
+}
+
+/* 사원의 소속 목록. ?include_ended=true면 끝난 소속도 포함. ?include=, ?fields=로 응답 항목 선택 */
+func ReadAssignment(c *gin.Context) {
+	includeEnded, err := strconv.ParseBool(c.DefaultQuery("include_ended", "false"))
+	if err != nil {
+		AbortWithError(c, InvalidParameter("include_ended", "include_ended should be true or false"))
+		return
+	}
+
+	var employee Employee
+	db.Where("id = ?", c.Param("id")).Find(&employee)
+	if employee.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
+		return
+	}
+
+	options, apiErr := parseReadOptions(c, &AssignmentResponse{}, assignmentIncludes, "department")
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	query := db
+	if includeEnded {
+		query = db.Unscoped()
+	}
+	var assignments []Assignment
+	result := options.Preload(query.Where("employee_id = ?", employee.ID)).Order("start_date asc, id asc").Find(&assignments)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
 
+	options.JSON(c, http.StatusOK, newAssignmentResponses(assignments))
 }
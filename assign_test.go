@@ -16,12 +16,12 @@ func TestAddEmployeeDepartmentById(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.POST("/api/assign/:eid/:did", AddEmployeeDepartmentById)
+	router.POST("/api/assign/id/:eid/:department", AddEmployeeDepartmentById)
 
 	// Create Test Data
 	var result map[string]interface{}
@@ -36,7 +36,7 @@ func TestAddEmployeeDepartmentById(t *testing.T) {
 	db.Create(&newDepartment)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/assign/" + strconv.FormatUint(uint64(newEmployee.ID), 10) + "/" + strconv.FormatUint(uint64(newDepartment.ID), 10)
+	requrl := "/api/assign/id/" + strconv.FormatUint(uint64(newEmployee.ID), 10) + "/" + newDepartment.Department_Name
 	request, _ := http.NewRequest("POST", requrl, nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -58,32 +58,32 @@ func TestAddEmployeeDepartmentInvalidId(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.POST("/api/assign/:eid/:did", AddEmployeeDepartmentById)
+	router.POST("/api/assign/id/:eid/:department", AddEmployeeDepartmentById)
 
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("POST", "/api/assign/-1/-1", nil)
+	request, _ := http.NewRequest("POST", "/api/assign/id/-1/-1", nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestDeleteEmployeeDepartment(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.DELETE("/api/assign/:eid/:did", DeleteEmployeeDepartmentById)
+	router.DELETE("/api/assign/id/:eid/:department", DeleteEmployeeDepartmentById)
 
 	// Create Test Data
 	var result map[string]interface{}
@@ -99,7 +99,7 @@ func TestDeleteEmployeeDepartment(t *testing.T) {
 	db.Model(&newEmployee).Association("Employee_Departments").Append(&newDepartment)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/assign/" + strconv.FormatUint(uint64(newEmployee.ID), 10) + "/" + strconv.FormatUint(uint64(newDepartment.ID), 10)
+	requrl := "/api/assign/id/" + strconv.FormatUint(uint64(newEmployee.ID), 10) + "/" + newDepartment.Department_Name
 	request, _ := http.NewRequest("DELETE", requrl, nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -119,32 +119,32 @@ func TestDeleteEmployeeDepartmentInvalidId(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.DELETE("/api/assign/:eid/:did", DeleteEmployeeDepartmentById)
+	router.DELETE("/api/assign/id/:eid/:department", DeleteEmployeeDepartmentById)
 
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("DELETE", "/api/assign/-1/-1", nil)
+	request, _ := http.NewRequest("DELETE", "/api/assign/id/-1/-1", nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestReadEmployeeInDepartment(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.GET("/api/assign/:did", ReadEmployeeInDepartment)
+	router.GET("/api/department/:name/employee", ReadEmployeeInDepartment)
 
 	// Create Test Data
 	newEmployee := Employee{
@@ -159,7 +159,7 @@ func TestReadEmployeeInDepartment(t *testing.T) {
 	db.Model(&newEmployee).Association("Employee_Departments").Append(&newDepartment)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/assign/" + strconv.FormatUint(uint64(newDepartment.ID), 10)
+	requrl := "/api/department/" + newDepartment.Department_Name + "/employee"
 	request, _ := http.NewRequest("GET", requrl, nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -176,18 +176,218 @@ func TestReadEmployeeInDepartmentInvalidId(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.GET("/api/assign/:did", ReadEmployeeInDepartment)
+	router.GET("/api/department/:name/employee", ReadEmployeeInDepartment)
 
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("GET", "/api/assign/-1", nil) // did=24000.. 인 Department가 없다고 가정
+	request, _ := http.NewRequest("GET", "/api/department/-1/employee", nil) // 이름이 -1인 Department가 없다고 가정
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func assignmentTestData(t *testing.T) (*gin.Engine, string, Employee, []Department) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/assign/id/:eid/:department", AddEmployeeDepartmentById)
+	router.PUT("/api/assign/id/:eid/:department", UpdateAssignment)
+	router.DELETE("/api/assign/id/:eid/:department", DeleteEmployeeDepartmentById)
+	router.GET("/api/employee/:id/assignments", ReadAssignment)
+
+	employee := Employee{Employee_Name: "Assignment Employee"}
+	db.Create(&employee)
+	departments := []Department{{Department_Name: "Assignment A"}, {Department_Name: "Assignment B"}}
+	db.Create(&departments)
+	t.Cleanup(func() {
+		db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+		db.Unscoped().Delete(&employee)
+		db.Unscoped().Delete(&departments)
+	})
+
+	return router, token, employee, departments
+}
+
+func TestAssignmentMetadata(t *testing.T) {
+	router, token, employee, departments := assignmentTestData(t)
+	url := fmt.Sprintf("/api/assign/id/%d/%s", employee.ID, departments[0].Department_Name)
+
+	w := orgRequest(router, token, "POST", url, `{"role": "lead", "allocation": 60, "start_date": "2020-03-01"}`)
+	var result struct {
+		Assignment Assignment `json:"assignment"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "lead", result.Assignment.Role)
+	assert.Equal(t, 60, result.Assignment.Allocation)
+	assert.True(t, result.Assignment.IsPrimary) // 첫 소속
+	assert.Equal(t, "2020-03-01", result.Assignment.StartDate.Format("2006-01-02"))
+
+	// 이미 소속된 부서
+	w = orgRequest(router, token, "POST", url, "")
+	var apiErr ApiError
+	err = json.Unmarshal(w.Body.Bytes(), &apiErr)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodeAssignmentExists, apiErr.Code)
+
+	w = orgRequest(router, token, "POST", fmt.Sprintf("/api/assign/id/%d/%s", employee.ID, departments[1].Department_Name), `{"allocation": 150}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = orgRequest(router, token, "PUT", url, `{"role": "manager", "allocation": 40}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated Assignment
+	db.Where("id = ?", result.Assignment.ID).Find(&updated)
+	assert.Equal(t, "manager", updated.Role)
+	assert.Equal(t, 40, updated.Allocation)
+
+	w = orgRequest(router, token, "PUT", url, `{}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestAssignmentPrimary(t *testing.T) {
+	router, token, employee, departments := assignmentTestData(t)
+	urlA := fmt.Sprintf("/api/assign/id/%d/%s", employee.ID, departments[0].Department_Name)
+	urlB := fmt.Sprintf("/api/assign/id/%d/%s", employee.ID, departments[1].Department_Name)
+
+	w := orgRequest(router, token, "POST", urlA, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = orgRequest(router, token, "POST", urlB, `{"is_primary": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 주 소속은 하나
+	var primaries []Assignment
+	db.Where("employee_id = ? AND is_primary = ?", employee.ID, true).Find(&primaries)
+	if assert.Equal(t, 1, len(primaries)) {
+		assert.Equal(t, departments[1].ID, primaries[0].DepartmentID)
+	}
+
+	// 주 소속이 끝나면 남은 소속이 주 소속
+	w = orgRequest(router, token, "DELETE", urlB, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var remaining []Assignment
+	db.Where("employee_id = ? AND is_primary = ?", employee.ID, true).Find(&remaining)
+	if assert.Equal(t, 1, len(remaining)) {
+		assert.Equal(t, departments[0].ID, remaining[0].DepartmentID)
+	}
+
+	// 하나 남은 주 소속은 해제할 수 없음
+	w = orgRequest(router, token, "PUT", urlA, `{"is_primary": false}`)
+	var apiErr ApiError
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodeConflict, apiErr.Code)
+	db.Where("employee_id = ? AND is_primary = ?", employee.ID, true).Find(&remaining)
+	assert.Equal(t, 1, len(remaining))
+
+	// 다른 소속이 있어도 주 소속은 옮기는 방법으로만 바꿈
+	w = orgRequest(router, token, "POST", urlB, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = orgRequest(router, token, "PUT", urlA, `{"is_primary": false}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = orgRequest(router, token, "PUT", urlB, `{"is_primary": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Where("employee_id = ? AND is_primary = ?", employee.ID, true).Find(&remaining)
+	if assert.Equal(t, 1, len(remaining)) {
+		assert.Equal(t, departments[1].ID, remaining[0].DepartmentID)
+	}
+}
+
+func TestEndAssignmentKeepsHistory(t *testing.T) {
+	router, token, employee, departments := assignmentTestData(t)
+	url := fmt.Sprintf("/api/assign/id/%d/%s", employee.ID, departments[0].Department_Name)
+
+	w := orgRequest(router, token, "POST", url, `{"start_date": "2020-03-01"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = orgRequest(router, token, "DELETE", url+"?end_date=2019-01-01", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = orgRequest(router, token, "DELETE", url+"?end_date=2999-01-01", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = orgRequest(router, token, "DELETE", url+"?end_date=2021-06-30", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 끝난 소속은 현재 소속에서 빠지고 기록은 남음
+	var active, all int64
+	db.Model(&Assignment{}).Where("employee_id = ?", employee.ID).Count(&active)
+	db.Unscoped().Model(&Assignment{}).Where("employee_id = ?", employee.ID).Count(&all)
+	assert.Equal(t, int64(0), active)
+	assert.Equal(t, int64(1), all)
+
+	w = orgRequest(router, token, "DELETE", url, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = orgRequest(router, token, "GET", fmt.Sprintf("/api/employee/%d/assignments?include_ended=true", employee.ID), "")
+	var assignments []AssignmentResponse
+	err := json.Unmarshal(w.Body.Bytes(), &assignments)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(assignments)) {
+		assert.Equal(t, "2021-06-30", assignments[0].EndDate.Format("2006-01-02"))
+		assert.Equal(t, departments[0].Department_Name, assignments[0].Department.Name)
+	}
+
+	w = orgRequest(router, token, "GET", fmt.Sprintf("/api/employee/%d/assignments", employee.ID), "")
+	assignments = nil
+	err = json.Unmarshal(w.Body.Bytes(), &assignments)
+	assert.NoError(t, err)
+	assert.Empty(t, assignments)
+
+	// 다시 소속 가능
+	w = orgRequest(router, token, "POST", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAssignmentV2(t *testing.T) {
+	router, token, employee, departments := assignmentTestData(t)
+	router.POST("/api/v2/assign/:eid/:department", AssignEmployeeV2)
+	router.PUT("/api/v2/assign/:eid/:department", UpdateAssignmentV2)
+	router.DELETE("/api/v2/assign/:eid/:department", EndAssignmentV2)
+	url := fmt.Sprintf("/api/v2/assign/%d/%s", employee.ID, departments[0].Department_Name)
+
+	w := orgRequest(router, token, "POST", url, `{"role": "lead", "allocation": 60, "startDate": "2020-03-01"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var assignment AssignmentResponse
+	err := json.Unmarshal(w.Body.Bytes(), &assignment)
+	assert.NoError(t, err)
+	assert.Equal(t, "lead", assignment.Role)
+	assert.Equal(t, 60, assignment.Allocation)
+	assert.True(t, assignment.IsPrimary)
+	assert.Equal(t, "2020-03-01", assignment.StartDate.Format("2006-01-02"))
+	if assert.NotNil(t, assignment.Department) {
+		assert.Equal(t, departments[0].Department_Name, assignment.Department.Name)
+	}
+
+	w = orgRequest(router, token, "POST", fmt.Sprintf("/api/v2/assign/%d/%s", employee.ID, departments[1].Department_Name), `{"startDate": "March"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var apiErr ApiError
+	json.Unmarshal(w.Body.Bytes(), &apiErr)
+	if assert.Equal(t, 1, len(apiErr.Errors)) {
+		assert.Equal(t, "startDate", apiErr.Errors[0].Field)
+	}
+
+	w = orgRequest(router, token, "PUT", url, `{"allocation": 80, "isPrimary": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &assignment)
+	assert.NoError(t, err)
+	assert.Equal(t, 80, assignment.Allocation)
+
+	w = orgRequest(router, token, "DELETE", url, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &assignment)
+	assert.NoError(t, err)
+	assert.NotNil(t, assignment.EndDate)
+
+	w = orgRequest(router, token, "DELETE", url, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 감사 기록의 동작
+const (
+	AuditCreate   = "create"
+	AuditUpdate   = "update"
+	AuditDelete   = "delete"
+	AuditAssign   = "assign"
+	AuditUnassign = "unassign"
+	AuditRestore  = "restore"
+	AuditPurge    = "purge"
+)
+
+// 저장될 수 있는 모든 동작 (?action= 문서의 enum)
+var auditActions = []string{AuditCreate, AuditUpdate, AuditDelete, AuditAssign, AuditUnassign, AuditRestore, AuditPurge}
+
+// 감사 기록 대상
+const (
+	EntityEmployee   = "employee"
+	EntityDepartment = "department"
+	EntityAssignment = "assignment"
+)
+
+// 모든 감사 기록 대상 (?entity_type= 문서의 enum)
+var auditEntities = []string{EntityEmployee, EntityDepartment, EntityAssignment}
+
+// 데이터를 바꾼 API 호출 기록. 누가, 언제, 무엇을 어떻게 바꿨는지
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"createdAt"`
+	ActorEmail string    `gorm:"size:255;index" json:"actorEmail"`
+	ActorCA    string    `gorm:"size:32" json:"actorCA"`
+	Action     string    `gorm:"size:16" json:"action"`
+	EntityType string    `gorm:"size:32;index:idx_audit_logs_entity" json:"entityType"`
+	EntityID   uint      `gorm:"index:idx_audit_logs_entity" json:"entityId"`
+	Before     JSONMap   `json:"before,omitempty"`
+	After      JSONMap   `json:"after,omitempty"`
+	Changes    JSONMap   `json:"changes,omitempty"` // 항목별 {"from", "to"}
+	RequestID  string    `gorm:"size:64" json:"requestId"`
+}
+
+var auditSortFields = []string{"id", "created_at"}
+
+const auditSystemActor = "system" // API 요청이 아닌 작업(purge 명령 등)
+
+/* 구조체를 json 기준의 map으로. model은 응답 타입 기준. nil이면 nil */
+func toJSONMap(value interface{}) (JSONMap, error) {
+	if value == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(toResponse(value))
+	if err != nil {
+		return nil, err
+	}
+	var m JSONMap
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+/* 요청한 계정과 바뀌기 전후의 내용을 기록. 변경과 같은 transaction에서 호출. c가 nil이면 명령어로 실행한 작업 */
+func recordAudit(c *gin.Context, tx *gorm.DB, action string, entityType string, entityID uint, before interface{}, after interface{}) error {
+	log := AuditLog{
+		ActorEmail: auditSystemActor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if c != nil {
+		log.ActorEmail = c.GetString("email")
+		log.ActorCA = c.GetString("ca")
+		log.RequestID = c.GetString("request_id")
+	}
+
+	var err error
+	log.Before, err = toJSONMap(before)
+	if err != nil {
+		return err
+	}
+	log.After, err = toJSONMap(after)
+	if err != nil {
+		return err
+	}
+	if log.Before != nil && log.After != nil {
+		log.Changes = diffMaps(log.Before, log.After)
+	}
+
+	return tx.Create(&log).Error
+}
+
+/* 감사 기록 조회. ?actor=, ?entity_type=, ?entity_id=, ?action=, ?from=, ?to= 로 거름. 기본은 최신순 */
+func ReadAudit(c *gin.Context) {
+	pagination, pagingErr := Paging(c, &AuditLog{}, auditSortFields...)
+	if pagingErr != nil {
+		AbortWithError(c, pagingErr)
+		return
+	}
+	if c.Query("sort") == "" {
+		pagination.Sort[0].Desc = true // primary key
+	}
+
+	query := db.Model(&AuditLog{})
+	if actor := c.Query("actor"); actor != "" {
+		query = query.Where("actor_email = ?", actor)
+	}
+	if entityType := c.Query("entity_type"); entityType != "" {
+		query = query.Where("entity_type = ?", entityType)
+	}
+	if value := c.Query("entity_id"); value != "" {
+		entityID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			AbortWithError(c, InvalidParameter("entity_id", "entity_id should be a number"))
+			return
+		}
+		query = query.Where("entity_id = ?", entityID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseDate(value)
+		if err != nil {
+			AbortWithError(c, InvalidParameter("from", "from should be 2006-01-02 or RFC3339"))
+			return
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseDate(value)
+		if err != nil {
+			AbortWithError(c, InvalidParameter("to", "to should be 2006-01-02 or RFC3339"))
+			return
+		}
+		if len(value) == len("2006-01-02") { // 날짜만 보내면 그 날까지 포함
+			to = to.AddDate(0, 0, 1)
+		}
+		query = query.Where("created_at < ?", to)
+	}
+
+	var logs []AuditLog
+	response, err := pagination.Find(c, query, &logs)
+	if err != nil {
+		AbortWithInternalError(c, err, "Read Error")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
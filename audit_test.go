@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func auditTestRouter(t *testing.T) (*gin.Engine, string) {
+	token, err := GenerateToken("audit@test.com", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	// 지워진 Row의 id가 다시 쓰일 수 있어서 다른 테스트의 기록은 지움
+	db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&AuditLog{})
+
+	router := gin.Default()
+	router.Use(RequestID(), AuthorizeAccount())
+	router.POST("/api/department/", AddDepartment)
+	router.PUT("/api/department/", UpdateDepartment)
+	router.DELETE("/api/department/:name", DeleteDepartment)
+	router.GET("/api/audit/", ReadAudit)
+	return router, token
+}
+
+func readAudit(t *testing.T, router *gin.Engine, token string, query string) (int, []AuditLog) {
+	var logs []AuditLog
+	w := orgRequest(router, token, "GET", "/api/audit/?"+query, "")
+	if w.Code == http.StatusOK {
+		err := json.Unmarshal(w.Body.Bytes(), &PageResponse{Data: &logs})
+		assert.NoError(t, err)
+	}
+	return w.Code, logs
+}
+
+func TestAuditDepartment(t *testing.T) {
+	router, token := auditTestRouter(t)
+
+	w := orgRequest(router, token, "POST", "/api/department/", `{"dname": ["Audit Before"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var department Department
+	db.Where("Department_Name = ?", "Audit Before").Find(&department)
+
+	w = orgRequest(router, token, "PUT", "/api/department/", `{"prev": "Audit Before", "new": "Audit After"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	requestID := w.Header().Get("X-Request-ID")
+
+	w = orgRequest(router, token, "DELETE", "/api/department/Audit After", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 최신순
+	code, logs := readAudit(t, router, token, fmt.Sprintf("entity_type=department&entity_id=%d", department.ID))
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Equal(t, 3, len(logs)) {
+		assert.Equal(t, []string{AuditDelete, AuditUpdate, AuditCreate}, []string{logs[0].Action, logs[1].Action, logs[2].Action})
+
+		update := logs[1]
+		assert.Equal(t, "audit@test.com", update.ActorEmail)
+		assert.Equal(t, "myCA", update.ActorCA)
+		assert.Equal(t, requestID, update.RequestID)
+		assert.Equal(t, map[string]interface{}{"from": "Audit Before", "to": "Audit After"}, update.Changes["name"])
+
+		assert.Nil(t, logs[2].Before)
+		assert.Equal(t, "Audit Before", logs[2].After["name"])
+		assert.Nil(t, logs[0].After)
+	}
+
+	code, logs = readAudit(t, router, token, fmt.Sprintf("entity_type=department&entity_id=%d&action=update&actor=audit@test.com&from=2000-01-01", department.ID))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, len(logs))
+
+	code, logs = readAudit(t, router, token, fmt.Sprintf("entity_id=%d&actor=nobody@test.com", department.ID))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, logs)
+
+	code, logs = readAudit(t, router, token, fmt.Sprintf("entity_id=%d&to=2000-01-01", department.ID))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, logs)
+
+	for _, query := range []string{"from=yesterday", "to=1/1", "entity_id=abc"} {
+		code, _ = readAudit(t, router, token, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestAuditRequiresAdmin(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleEditor)
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/api/audit/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	SetupRouter().ServeHTTP(w, request)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuditEmployeeAndAssignment(t *testing.T) {
+	router, _ := auditTestRouter(t)
+	_, token, id := historyTestData(t)
+
+	code, logs := readAudit(t, router, token, fmt.Sprintf("entity_type=employee&entity_id=%d&sort=id", id))
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Equal(t, 2, len(logs)) {
+		assert.Equal(t, AuditCreate, logs[0].Action)
+		assert.Equal(t, "gotest", logs[0].ActorEmail)
+		assert.Equal(t, map[string]interface{}{"from": "Engineer", "to": "Lead"}, logs[1].Changes["jobTitle"])
+	}
+
+	var assignment Assignment
+	db.Unscoped().Where("employee_id = ?", id).Find(&assignment)
+	code, logs = readAudit(t, router, token, fmt.Sprintf("entity_type=assignment&entity_id=%d&sort=id", assignment.ID))
+	assert.Equal(t, http.StatusOK, code)
+	// 사원을 만들면서 정한 부서는 사원 create 기록에 포함
+	if assert.Equal(t, 1, len(logs)) {
+		assert.Equal(t, AuditUnassign, logs[0].Action)
+		assert.Contains(t, logs[0].Changes, "endDate")
+	}
+}
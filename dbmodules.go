@@ -1,81 +1,421 @@
 package main
 
 import (
-	"log"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-/* DB를 생성 */
+// DB_DSN이 없을 때 사용하는 기본값 (docker-compose의 db 서비스)
+const (
+	defaultMysqlDSN  = "root:1234@tcp(db:3306)/myapi?charset=utf8mb4&parseTime=True&loc=Local"
+	defaultSqliteDSN = "myapi.db"
+)
+
+/* DB를 생성. DB_DRIVER(mysql, sqlite)와 DB_DSN 환경변수(.env)로 설정 */
 func InitDB() (err error) {
-	dsn := "root:1234@tcp(db:3306)/myapi?charset=utf8mb4&parseTime=True&loc=Local"
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dialector, err := openDialector(os.Getenv("DB_DRIVER"), os.Getenv("DB_DSN"))
+	if err != nil {
+		return
+	}
 
+	db, err = gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		return
 	}
 
+	// 사원과 부서의 many2many 연결 Table은 Assignment. Association으로 지우면 end_date만 기록됨
+	err = db.SetupJoinTable(&Employee{}, "Employee_Departments", &Assignment{})
+	if err != nil {
+		return
+	}
+	err = db.SetupJoinTable(&Department{}, "Department_Employees", &Assignment{})
+
 	return
 }
 
-/* Table 생성 */
-func InitTable(c *gin.Context) {
-	err := db.AutoMigrate(&Account{}, &Department{}, &Employee{}) // DB Table 생성
+/* driver 이름에 맞는 gorm Dialector 반환. driver가 비어있으면 mysql */
+func openDialector(driver string, dsn string) (gorm.Dialector, error) {
+	switch strings.ToLower(driver) {
+	case "", "mysql", "mariadb":
+		if dsn == "" {
+			dsn = defaultMysqlDSN
+		}
+		return mysql.Open(dsn), nil
+	case "sqlite", "sqlite3":
+		if dsn == "" {
+			dsn = defaultSqliteDSN
+		}
+		return sqlite.Open(dsn), nil
+	}
+
+	return nil, fmt.Errorf("unsupported DB_DRIVER: %s", driver)
+}
+
+var schemaCache sync.Map // Paging에서 파싱한 model schema
+
+// 목록 조회 limit의 기본값과 최대값
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// 목록 조회의 paging, 정렬 조건. page 방식과 cursor 방식 중 하나를 사용
+type Pagination struct {
+	Limit     int
+	Page      int
+	Sort      []SortField
+	UseCursor bool        // cursor query가 있으면 cursor 방식
+	Cursor    *pageCursor // 첫 페이지면 nil
+
+	schema *schema.Schema
+}
+
+// 정렬할 column과 방향
+type SortField struct {
+	Field *schema.Field
+	Desc  bool
+}
+
+// cursor 내용. 기준 row의 정렬 column 값
+type pageCursor struct {
+	After  []json.RawMessage `json:"a,omitempty"`
+	Before []json.RawMessage `json:"b,omitempty"`
+}
+
+// 목록 응답 envelope
+type PageResponse struct {
+	Data  interface{} `json:"data"`
+	Total *int64      `json:"total,omitempty"` // cursor 방식에서는 count하지 않음
+	Limit int         `json:"limit"`
+	Page  int         `json:"page,omitempty"`
+	Next  string      `json:"next,omitempty"`
+	Prev  string      `json:"prev,omitempty"`
+}
+
+/* 페이징 처리 부분. HTTP Request에 Query를 통해서 변수를 받아온다. sortable은 정렬 가능한 column 이름 */
+func Paging(c *gin.Context, model interface{}, sortable ...string) (p *Pagination, apiErr *ApiError) {
+	s, err := schema.Parse(model, &schemaCache, db.NamingStrategy)
 	if err != nil {
-		log.Println(err)
+		return nil, NewApiError(http.StatusInternalServerError, CodeInternalError, err.Error())
+	}
+	p = &Pagination{Limit: defaultPageLimit, Page: 1, schema: s}
+	query := c.Request.URL.Query()
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "Cannot Init Table",
-		})
-		c.Abort()
-		return
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, InvalidParameter("limit", "limit should be a positive number")
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		p.Limit = limit
+	}
+
+	if value := query.Get("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return nil, InvalidParameter("page", "page should be a positive number")
+		}
+		p.Page = page
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"msg": "Table Init",
-	})
+	// sort=-entry_time,employee_name 처럼 ,로 구분하고 -는 내림차순
+	if value := query.Get("sort"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			sortField := SortField{}
+			if strings.HasPrefix(name, "-") {
+				sortField.Desc = true
+				name = name[1:]
+			}
+			if !containsString(sortable, name) {
+				return nil, InvalidParameter("sort", "Cannot sort by "+name+". Use one of "+strings.Join(sortable, ", "))
+			}
+			sortField.Field = s.LookUpField(name)
+			p.Sort = append(p.Sort, sortField)
+		}
+	}
+	// 같은 값이 있어도 순서가 정해지도록 마지막은 항상 primary key
+	primary := s.PrioritizedPrimaryField
+	if len(p.Sort) == 0 || p.Sort[len(p.Sort)-1].Field != primary {
+		p.Sort = append(p.Sort, SortField{Field: primary})
+	}
+
+	if _, ok := query["cursor"]; ok {
+		if _, ok := query["page"]; ok {
+			return nil, InvalidParameter("cursor", "Use either page or cursor")
+		}
+		p.UseCursor = true
+		if value := query.Get("cursor"); value != "" {
+			p.Cursor, err = decodeCursor(value, len(p.Sort))
+			if err != nil {
+				return nil, InvalidParameter("cursor", "Invalid cursor")
+			}
+		}
+	}
+
+	return
 }
 
-/* Table 전체삭제 */
-func DeleteTable(c *gin.Context) {
-	err := db.Migrator().DropTable(&Department{}, &Employee{}, "employee_departments") // DB Table 삭제
+/* ?include_deleted=true면 삭제(soft delete)된 Row도 조회 */
+func includeDeleted(c *gin.Context, query *gorm.DB) (*gorm.DB, *ApiError) {
+	include, err := strconv.ParseBool(c.DefaultQuery("include_deleted", "false"))
 	if err != nil {
-		log.Println(err)
+		return nil, InvalidParameter("include_deleted", "include_deleted should be true or false")
+	}
+	if include {
+		return query.Unscoped(), nil
+	}
+	return query, nil
+}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "Cannot Delete Table",
-		})
-		c.Abort()
-		return
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* 조건을 적용해서 dest(slice pointer)에 조회하고 응답 envelope을 만듦. count에는 Preload를 쓸 수 없으므로 따로 받음 */
+func (p *Pagination) Find(c *gin.Context, query *gorm.DB, dest interface{}, preloads ...string) (*PageResponse, error) {
+	response := &PageResponse{Data: dest, Limit: p.Limit}
+	if p.UseCursor {
+		return response, p.findByCursor(c, withPreloads(query, preloads), dest, response)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"msg": "Table Delete",
-	})
+	var total int64
+	result := query.Session(&gorm.Session{}).Count(&total)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	response.Total = &total
+	response.Page = p.Page
+
+	result = withPreloads(query, preloads).Order(p.orderBy(false)).
+		Limit(p.Limit).Offset((p.Page - 1) * p.Limit).Find(dest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if int64(p.Page*p.Limit) < total {
+		response.Next = p.link(c, "page", strconv.Itoa(p.Page+1))
+	}
+	if p.Page > 1 {
+		response.Prev = p.link(c, "page", strconv.Itoa(p.Page-1))
+	}
+	setLinkHeader(c, response)
+	return response, nil
 }
 
-/* 페이징 처리 부분. HTTP Request에 Query를 통해서 변수를 받아온다 */
-func Paging(c *gin.Context) (limit int, page int, sort string) { // return은 limit, page, sort
-	sort = "id asc" // id는 모든 table에 있다는 점 이용해서 일단 id로 설정 후 오름차순으로
-	query := c.Request.URL.Query()
+func withPreloads(query *gorm.DB, preloads []string) *gorm.DB {
+	tx := query.Session(&gorm.Session{})
+	for _, preload := range preloads {
+		tx = tx.Preload(preload)
+	}
+	return tx
+}
+
+/* keyset 방식 조회. offset 없이 기준 row 다음(또는 이전) limit개를 가져옴 */
+func (p *Pagination) findByCursor(c *gin.Context, query *gorm.DB, dest interface{}, response *PageResponse) error {
+	backward := p.Cursor != nil && p.Cursor.Before != nil
 
-	for key, value := range query {
-		//fmt.Println(key, value)
-		queryValue := value[len(value)-1]
-		switch key {
-		case "limit":
-			limit, _ = strconv.Atoi(queryValue)
-			break
-		case "page":
-			page, _ = strconv.Atoi(queryValue)
-			break
-			//case "sort":
+	tx := query.Session(&gorm.Session{})
+	if p.Cursor != nil {
+		values := p.Cursor.After
+		if backward {
+			values = p.Cursor.Before
 		}
+		where, args, err := p.keysetCondition(values, backward)
+		if err != nil {
+			return err
+		}
+		tx = tx.Where(where, args...)
 	}
 
-	return
+	// 한 개 더 조회해서 다음 페이지가 있는지 확인
+	result := tx.Order(p.orderBy(backward)).Limit(p.Limit + 1).Find(dest)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	hasMore := rows.Len() > p.Limit
+	if hasMore {
+		rows.Set(rows.Slice(0, p.Limit))
+	}
+	if backward { // 역순으로 조회했으므로 원래 순서로 되돌림
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if rows.Len() > 0 {
+		if hasMore || backward {
+			response.Next = p.link(c, "cursor", p.encodeCursor(c, rows.Index(rows.Len()-1), false))
+		}
+		if (hasMore && backward) || (!backward && p.Cursor != nil) {
+			response.Prev = p.link(c, "cursor", p.encodeCursor(c, rows.Index(0), true))
+		}
+	}
+	setLinkHeader(c, response)
+	return nil
+}
+
+/* 정렬 순서대로 size개씩 끝까지 dest에 조회하고 fn 호출. export처럼 Table 전체를 메모리에 올리지 않을 때 사용 */
+func (p *Pagination) Each(c *gin.Context, query *gorm.DB, dest interface{}, size int, fn func() error) error {
+	var last []json.RawMessage
+	for {
+		tx := query.Session(&gorm.Session{})
+		if last != nil {
+			where, args, err := p.keysetCondition(last, false)
+			if err != nil {
+				return err
+			}
+			tx = tx.Where(where, args...)
+		}
+		result := tx.Order(p.orderBy(false)).Limit(size).Find(dest)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		rows := reflect.ValueOf(dest).Elem()
+		if rows.Len() == 0 {
+			return nil
+		}
+		err := fn()
+		if err != nil {
+			return err
+		}
+		if rows.Len() < size {
+			return nil
+		}
+		last = p.sortValues(c, rows.Index(rows.Len()-1))
+	}
+}
+
+/* ORDER BY 절. backward면 방향을 뒤집음 */
+func (p *Pagination) orderBy(backward bool) string {
+	orders := make([]string, 0, len(p.Sort))
+	for _, sortField := range p.Sort {
+		order := p.column(sortField) + " ASC"
+		if sortField.Desc != backward {
+			order = p.column(sortField) + " DESC"
+		}
+		orders = append(orders, order)
+	}
+	return strings.Join(orders, ", ")
+}
+
+func (p *Pagination) column(sortField SortField) string {
+	return p.schema.Table + "." + sortField.Field.DBName
+}
+
+/* (a > ?) OR (a = ? AND b > ?) OR ... 형태의 keyset 조건 */
+func (p *Pagination) keysetCondition(raw []json.RawMessage, backward bool) (string, []interface{}, error) {
+	values := make([]interface{}, len(raw))
+	for i, sortField := range p.Sort {
+		value := reflect.New(sortField.Field.FieldType)
+		err := json.Unmarshal(raw[i], value.Interface())
+		if err != nil {
+			return "", nil, err
+		}
+		values[i] = value.Elem().Interface()
+	}
+
+	var conditions []string
+	var args []interface{}
+	for i, sortField := range p.Sort {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, p.column(p.Sort[j])+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if sortField.Desc != backward {
+			op = " < ?"
+		}
+		parts = append(parts, p.column(sortField)+op)
+		args = append(args, values[i])
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+}
+
+/* row의 정렬 column 값. cursor와 keyset 조건에 사용 */
+func (p *Pagination) sortValues(c *gin.Context, row reflect.Value) []json.RawMessage {
+	values := make([]json.RawMessage, 0, len(p.Sort))
+	for _, sortField := range p.Sort {
+		value, _ := sortField.Field.ValueOf(c.Request.Context(), reflect.Indirect(row))
+		data, _ := json.Marshal(value)
+		values = append(values, data)
+	}
+	return values
+}
+
+/* row의 정렬 column 값으로 cursor 문자열 생성 */
+func (p *Pagination) encodeCursor(c *gin.Context, row reflect.Value, before bool) string {
+	values := p.sortValues(c, row)
+
+	cursor := pageCursor{After: values}
+	if before {
+		cursor = pageCursor{Before: values}
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string, size int) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor pageCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, err
+	}
+	if (cursor.After == nil) == (cursor.Before == nil) || len(cursor.After)+len(cursor.Before) != size {
+		return nil, errors.New("cursor does not match sort")
+	}
+	return &cursor, nil
+}
+
+/* 현재 요청 URL에서 key의 값만 바꾼 링크 */
+func (p *Pagination) link(c *gin.Context, key string, value string) string {
+	query := c.Request.URL.Query()
+	query.Set(key, value)
+	query.Set("limit", strconv.Itoa(p.Limit))
+	return c.Request.URL.Path + "?" + query.Encode()
+}
+
+/* next, prev 링크를 Link header(RFC 8288)로도 보냄 */
+func setLinkHeader(c *gin.Context, response *PageResponse) {
+	var links []string
+	if response.Next != "" {
+		links = append(links, "<"+response.Next+`>; rel="next"`)
+	}
+	if response.Prev != "" {
+		links = append(links, "<"+response.Prev+`>; rel="prev"`)
+	}
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func pagingTestData(t *testing.T) (*gin.Engine, string, []Department) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/department/only", ReadDepartmentOnly)
+
+	var created []Department
+	for _, name := range []string{"Paging C", "Paging A", "Paging E", "Paging B", "Paging D"} {
+		department := Department{Department_Name: name}
+		db.Create(&department)
+		created = append(created, department)
+	}
+	t.Cleanup(func() {
+		for _, department := range created {
+			db.Unscoped().Delete(&department)
+		}
+	})
+
+	return router, token, created
+}
+
+func getDepartmentPage(t *testing.T, router *gin.Engine, token string, url string) (int, []DepartmentResponse, PageResponse, http.Header) {
+	var departments []DepartmentResponse
+	result := PageResponse{Data: &departments}
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", url, nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	if w.Code == http.StatusOK {
+		err := json.Unmarshal(w.Body.Bytes(), &result)
+		assert.NoError(t, err)
+	}
+	return w.Code, departments, result, w.Header()
+}
+
+func TestPagingDefaults(t *testing.T) {
+	router, token, _ := pagingTestData(t)
+
+	code, departments, result, header := getDepartmentPage(t, router, token, "/api/department/only?limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, len(departments))
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Page)
+	if assert.NotNil(t, result.Total) {
+		assert.GreaterOrEqual(t, *result.Total, int64(5))
+	}
+	assert.Equal(t, "/api/department/only?limit=2&page=2", result.Next)
+	assert.Empty(t, result.Prev)
+	assert.Contains(t, header.Get("Link"), `rel="next"`)
+
+	// 최대값보다 큰 limit은 최대값으로
+	code, _, result, _ = getDepartmentPage(t, router, token, "/api/department/only?limit=1000")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, maxPageLimit, result.Limit)
+
+	for _, query := range []string{"limit=0", "limit=abc", "page=0", "sort=name", "page=1&cursor="} {
+		code, _, _, _ = getDepartmentPage(t, router, token, "/api/department/only?"+query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestPagingSort(t *testing.T) {
+	router, token, _ := pagingTestData(t)
+
+	code, departments, _, _ := getDepartmentPage(t, router, token, "/api/department/only?limit=100&sort=-department_name")
+	assert.Equal(t, http.StatusOK, code)
+
+	names := make([]string, 0, len(departments))
+	for _, department := range departments {
+		names = append(names, department.Name)
+	}
+	assert.True(t, sort.SliceIsSorted(names, func(i, j int) bool { return names[i] > names[j] }))
+}
+
+func TestPagingCursor(t *testing.T) {
+	router, token, _ := pagingTestData(t)
+
+	code, all, _, _ := getDepartmentPage(t, router, token, "/api/department/only?limit=100&sort=department_name")
+	assert.Equal(t, http.StatusOK, code)
+
+	// next 링크를 따라가면 전체 목록과 같은 순서
+	var visited []DepartmentResponse
+	var pages []PageResponse
+	url := "/api/department/only?limit=2&sort=department_name&cursor="
+	for url != "" {
+		code, departments, result, _ := getDepartmentPage(t, router, token, url)
+		if !assert.Equal(t, http.StatusOK, code) {
+			return
+		}
+		assert.Nil(t, result.Total)
+		visited = append(visited, departments...)
+		pages = append(pages, result)
+		url = result.Next
+	}
+	assert.Equal(t, all, visited)
+	assert.Empty(t, pages[0].Prev)
+
+	// 두 번째 페이지의 prev는 첫 페이지
+	if assert.True(t, len(pages) > 1) {
+		code, departments, _, _ := getDepartmentPage(t, router, token, pages[1].Prev)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, all[:2], departments)
+	}
+
+	code, _, _, _ = getDepartmentPage(t, router, token, "/api/department/only?cursor=invalid")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPagingEach(t *testing.T) {
+	_, _, created := pagingTestData(t)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/?sort=-department_name", nil)
+	pagination, apiErr := Paging(c, &Department{}, departmentSortFields...)
+	assert.Nil(t, apiErr)
+
+	// batch 크기로 나눠서 조회해도 정렬 순서대로 모두 방문
+	var batch []Department
+	var names []string
+	batches := 0
+	err := pagination.Each(c, db.Where("Department_Name LIKE ?", "Paging %"), &batch, 2, func() error {
+		batches++
+		for _, department := range batch {
+			names = append(names, department.Department_Name)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, len(created), len(names))
+	assert.Equal(t, 3, batches)
+	assert.True(t, sort.SliceIsSorted(names, func(i, j int) bool { return names[i] > names[j] }))
+}
@@ -1,22 +1,38 @@
 package main
 
 import (
-	"fmt"
-	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Department Table
 type Department struct {
-	ID                   uint        `gorm:"primaryKey"`
-	Department_Name      string      `gorm:"unique"`
-	Department_Employees []*Employee `gorm:"many2many:employee_departments"`
+	ID                   uint           `gorm:"primaryKey"`
+	Department_Name      string         `gorm:"unique"` // 삭제된 부서도 이름을 차지함
+	ParentID             *uint          `gorm:"index"`  // 상위 부서. 최상위(본부 등)면 null
+	DeletedAt            gorm.DeletedAt `gorm:"index"`
+	Department_Employees []*Employee    `gorm:"many2many:employee_departments"`
 }
 
+// 하위 부서가 있는 부서를 삭제하는 방법
+const (
+	DeleteReparent = "reparent" // 하위 부서를 삭제되는 부서의 상위 부서로 옮김
+	DeleteCascade  = "cascade"  // 하위 부서까지 모두 삭제
+)
+
+// 부서 목록에서 sort에 사용할 수 있는 column
+var departmentSortFields = []string{"id", "department_name"}
+
 type dData struct {
-	DName []string `json:"dname" binding:"required"`
+	DName  []string `json:"dname" binding:"required"`
+	Parent string   `json:"parent"` // 상위 부서 이름. 없으면 최상위
+}
+
+type parentData struct {
+	Parent string `json:"parent"` // 비어있으면 최상위로 이동
 }
 
 type UpdateData struct {
@@ -27,37 +43,16 @@ type UpdateData struct {
 /* 새로운 Department를 추가(C) */
 func AddDepartment(c *gin.Context) {
 	var data dData
-	var department Department
-	msg := make([]string, 0, 3)
-	var temp string
 	err := c.ShouldBindJSON(&data)
 
 	if err != nil {
-		log.Println(err)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "invalid json",
-		})
-		c.Abort()
+		AbortWithBindError(c, err)
 		return
 	}
 
-	for i := 0; i < len(data.DName); i++ {
-		department.Department_Name = data.DName[i]
-		result := db.Create(&Department{Department_Name: data.DName[i]})
-		if result.Error != nil {
-			log.Println(result.Error)
-			temp = data.DName[i] + ": Create Fail!"
-			msg = append(msg, temp)
-
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-				"msg":           msg,
-				"not processed": data.DName[i:],
-			})
-			return
-		}
-		temp = data.DName[i] + ": Create Success"
-		msg = append(msg, temp)
+	_, msg, ok := addDepartments(c, data.DName, data.Parent)
+	if !ok {
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -65,71 +60,136 @@ func AddDepartment(c *gin.Context) {
 	})
 }
 
-/* Department Table 불러오기(R)_Paging 추가 */
-func ReadDepartment(c *gin.Context) { // localhost:8080/api/department/?page= & limit= (GET)
-	var departments []Department
-	limit, page, sort := Paging(c)
-	offset := (page - 1) * limit
+// v2 부서 추가 body
+type departmentCreateRequest struct {
+	Names  []string `json:"names" binding:"required,min=1"`
+	Parent string   `json:"parent"` // 상위 부서 이름. 없으면 최상위
+}
 
-	result := db.Limit(limit).Offset(offset).Order(sort).Preload("Department_Employees").Find(&departments)
-	//result := db.Find(&departments)
+/* v2 부서 추가. 만든 부서를 201로 응답 */
+func AddDepartmentV2(c *gin.Context) {
+	var data departmentCreateRequest
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
 
-	if result.Error != nil {
-		log.Println(result.Error)
+	created, _, ok := addDepartments(c, data.Names, data.Parent)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": newDepartmentResponses(created),
+	})
+}
+
+/* names 순서대로 부서 생성. 같은 이름이 있으면 그 앞까지만 만들고 409. 실패하면 에러 응답 후 false */
+func addDepartments(c *gin.Context, names []string, parentName string) (created []Department, msg []string, ok bool) {
+	var department Department
+	var temp string
+	msg = make([]string, 0, 3)
+
+	var parentID *uint
+	if parentName != "" {
+		parent, apiErr := findParentDepartment(db, parentName)
+		if apiErr != nil {
+			AbortWithError(c, apiErr)
+			return
+		}
+		parentID = &parent.ID
+	}
+
+	for i := 0; i < len(names); i++ {
+		// 같은 이름의 부서가 이미 있으면 409. 삭제된 부서도 이름이 겹치면 안 됨
+		department = Department{}
+		db.Unscoped().Where("Department_Name = ?", names[i]).Find(&department)
+		if department.ID != 0 {
+			temp = names[i] + ": Create Fail! Department already exists"
+			if department.DeletedAt.Valid {
+				temp = names[i] + ": Create Fail! Department is deleted. Restore or purge it"
+			}
+			msg = append(msg, temp)
+
+			AbortWithError(c, NewApiError(http.StatusConflict, CodeDepartmentExists, temp).WithDetails(gin.H{
+				"msg":           msg,
+				"not processed": names[i:],
+			}))
+			return
+		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "READ error",
+		department = Department{Department_Name: names[i], ParentID: parentID}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Create(&department).Error
+			if err != nil {
+				return err
+			}
+			return recordAudit(c, tx, AuditCreate, EntityDepartment, department.ID, nil, department)
 		})
-		c.Abort()
-		return
+		if err != nil {
+			AbortWithInternalError(c, err, names[i]+": Create Fail!")
+			return
+		}
+		created = append(created, department)
+		temp = names[i] + ": Create Success"
+		msg = append(msg, temp)
 	}
+	return created, msg, true
+}
 
-	c.JSON(http.StatusOK, departments)
+/* Department Table 불러오기(R)_Paging 추가. 기본으로 소속 사원 포함. ?include=, ?fields=로 응답 항목 선택 */
+func ReadDepartment(c *gin.Context) { // localhost:8080/api/department/?page= & limit= (GET)
+	readDepartments(c, "employees")
 }
 
+/* 소속 사원 없이 부서만. ?include= 없는 ReadDepartment와 같음 (이전 route 호환) */
 func ReadDepartmentOnly(c *gin.Context) {
+	readDepartments(c)
+}
+
+func readDepartments(c *gin.Context, defaultIncludes ...string) {
 	var departments []Department
-	limit, page, sort := Paging(c)
-	offset := (page - 1) * limit
+	pagination, pagingErr := Paging(c, &Department{}, departmentSortFields...)
+	if pagingErr != nil {
+		AbortWithError(c, pagingErr)
+		return
+	}
+	query, apiErr := includeDeleted(c, db.Model(&Department{}))
+	if apiErr == nil {
+		query, apiErr = applyFilter(c, query, departmentFilterFields)
+	}
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	options, apiErr := parseReadOptions(c, &DepartmentResponse{}, departmentIncludes, defaultIncludes...)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
 
-	result := db.Limit(limit).Offset(offset).Order(sort).Find(&departments)
+	response, err := pagination.Find(c, query, &departments, options.Preloads...)
 	//result := db.Find(&departments)
 
-	if result.Error != nil {
-		log.Println(result.Error)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "READ error",
-		})
-		c.Abort()
+	if err != nil {
+		AbortWithInternalError(c, err, "READ error")
 		return
 	}
+	response.Data = newDepartmentResponses(departments)
 
-	c.JSON(http.StatusOK, departments)
+	options.JSON(c, http.StatusOK, response)
 }
 
 /* 기존의 Department 내용 수정(U) */
 func UpdateDepartment(c *gin.Context) { // localhost:8080/api/department
-	var department Department
 	var data UpdateData
 	err := c.ShouldBindJSON(&data)
 	if err != nil {
-		log.Println(err)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "invalid json",
-		})
-		c.Abort()
+		AbortWithBindError(c, err)
 		return
 	}
-
-	result := db.Model(&department).Where("Department_Name = ?", data.PrevName).Update("Department_Name", data.NewName)
-	if result.Error != nil {
-		log.Println(result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "UPDATE error",
-		})
-		c.Abort()
+	if _, ok := renameDepartment(c, data.PrevName, data.NewName); !ok {
 		return
 	}
 
@@ -138,77 +198,471 @@ func UpdateDepartment(c *gin.Context) { // localhost:8080/api/department
 	})
 }
 
-/* 기존의 Department 삭제(D) */
+// v2 부서 이름 변경 body
+type departmentUpdateRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+/* v2 부서 이름 변경. 바꿀 부서는 path(:name)로 받고 바뀐 부서를 응답 */
+func RenameDepartmentV2(c *gin.Context) {
+	var data departmentUpdateRequest
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	department, ok := renameDepartment(c, c.Param("name"), data.Name)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newDepartmentResponse(department))
+}
+
+/* 부서 이름 변경. 새 이름이 이미 있으면 409. 실패하면 에러 응답 후 false */
+func renameDepartment(c *gin.Context, prevName string, newName string) (Department, bool) {
+	var department Department
+	if prevName != newName {
+		db.Unscoped().Where("Department_Name = ?", newName).Find(&department)
+		if department.ID != 0 {
+			detail := "Department " + newName + " already exists"
+			if department.DeletedAt.Valid {
+				detail = "Department " + newName + " is deleted. Restore or purge it"
+			}
+			AbortWithError(c, NewApiError(http.StatusConflict, CodeDepartmentExists, detail))
+			return department, false
+		}
+	}
+
+	var before Department
+	db.Where("Department_Name = ?", prevName).Find(&before)
+	if before.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return before, false
+	}
+
+	after := before
+	after.Department_Name = newName
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Department{}).Where("id = ?", before.ID).Update("Department_Name", newName).Error
+		if err != nil {
+			return err
+		}
+		return recordAudit(c, tx, AuditUpdate, EntityDepartment, before.ID, before, after)
+	})
+	if err != nil {
+		AbortWithInternalError(c, err, "UPDATE error")
+		return before, false
+	}
+	return after, true
+}
+
+/* parentID 부서의 하위 부서를 newParentID 아래로 옮기고 부서마다 감사 기록. withDeleted면 삭제된 하위 부서도 */
+func reparentChildren(c *gin.Context, tx *gorm.DB, parentID uint, newParentID *uint, withDeleted bool) error {
+	query := tx.Where("parent_id = ?", parentID)
+	if withDeleted {
+		query = query.Unscoped()
+	}
+	var children []Department
+	err := query.Find(&children).Error
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		err = tx.Unscoped().Model(&Department{}).Where("id = ?", child.ID).Update("parent_id", newParentID).Error
+		if err != nil {
+			return err
+		}
+		after := child
+		after.ParentID = newParentID
+		err = recordAudit(c, tx, AuditUpdate, EntityDepartment, child.ID, child, after)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* 기존의 Department 삭제(D). 하위 부서가 있으면 ?strategy=reparent|cascade 필요 */
 func DeleteDepartment(c *gin.Context) {
 	name := c.Param("name")
+	strategy := c.Query("strategy")
+	if strategy != "" && strategy != DeleteReparent && strategy != DeleteCascade {
+		AbortWithError(c, InvalidParameter("strategy", "strategy should be reparent or cascade"))
+		return
+	}
 
 	var department Department
 
 	// Find Department
 	db.Where("Department_Name = ?", name).Find(&department)
-	fmt.Println(department)
 	if department.ID == 0 { // 테이블에 이름이 일치하는 Department가 없으면 ID = 0 으로 반환
-		log.Println("Department name incorrect")
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "error deleting department",
-		})
-		c.Abort()
+	descendants, err := findDescendants(db, department.ID)
+	if err != nil {
+		AbortWithInternalError(c, err, "Delete error")
+		return
+	}
+	if len(descendants) > 0 && strategy == "" {
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeDepartmentHasChildren,
+			"Department has sub-departments. Use ?strategy=reparent or ?strategy=cascade").WithDetails(gin.H{
+			"descendants": newDepartmentResponses(descendants),
+		}))
+		return
+	}
+
+	deleted := []Department{department}
+	if strategy == DeleteCascade {
+		deleted = append(deleted, descendants...)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if strategy == DeleteReparent {
+			err := reparentChildren(c, tx, department.ID, department.ParentID, false)
+			if err != nil {
+				return err
+			}
+		}
+		// 소속은 그대로 두고 부서만 삭제(soft delete)
+		for i := range deleted {
+			err := tx.Delete(&deleted[i]).Error
+			if err != nil {
+				return err
+			}
+			err = recordAudit(c, tx, AuditDelete, EntityDepartment, deleted[i].ID, deleted[i], nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		AbortWithInternalError(c, err, "Delete error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":     "Delete Complete",
+		"deleted": len(deleted),
+	})
+}
+
+/* 부서를 다른 부서 아래로 이동. 자기 하위 부서 아래로는 이동할 수 없음 */
+func MoveDepartment(c *gin.Context) {
+	var data parentData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+
+	var department Department
+	db.Where("Department_Name = ?", c.Param("name")).Find(&department)
+	if department.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return
+	}
+
+	var parentID interface{}
+	after := department
+	after.ParentID = nil
+	if data.Parent != "" {
+		parent, apiErr := findParentDepartment(db, data.Parent)
+		if apiErr != nil {
+			AbortWithError(c, apiErr)
+			return
+		}
+
+		// 새 상위 부서부터 위로 올라가면서 자기 자신이 나오면 순환
+		visited := map[uint]bool{}
+		for current := &parent; current.ID != 0 && !visited[current.ID]; {
+			if current.ID == department.ID {
+				AbortWithError(c, NewApiError(http.StatusConflict, CodeDepartmentCycle, "Cannot move department under itself or its sub-department"))
+				return
+			}
+			visited[current.ID] = true
+
+			next := Department{}
+			if current.ParentID != nil {
+				db.Where("id = ?", *current.ParentID).Find(&next)
+			}
+			current = &next
+		}
+		parentID = parent.ID
+		after.ParentID = &parent.ID
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&department).Update("parent_id", parentID).Error
+		if err != nil {
+			return err
+		}
+		return recordAudit(c, tx, AuditUpdate, EntityDepartment, department.ID, department, after)
+	})
+	if err != nil {
+		AbortWithInternalError(c, err, "UPDATE error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":    "Department Move Complete",
+		"parent": data.Parent,
+	})
+}
+
+/* 삭제된 부서 되돌리기. 상위 부서가 삭제되어 있으면 상위 부서부터 */
+func RestoreDepartment(c *gin.Context) {
+	var department Department
+	db.Unscoped().Where("Department_Name = ?", c.Param("name")).Find(&department)
+	if department.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return
+	} else if !department.DeletedAt.Valid {
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeConflict, "Department is not deleted"))
 		return
 	}
 
-	db.Model(&department).Association("Department_Employees").Clear()
-	db.Delete(&department)
+	if department.ParentID != nil {
+		var parent Department
+		db.Unscoped().Where("id = ?", *department.ParentID).Find(&parent)
+		if parent.DeletedAt.Valid {
+			AbortWithError(c, NewApiError(http.StatusConflict, CodeConflict, "Parent department "+parent.Department_Name+" is deleted. Restore it first"))
+			return
+		}
+	}
+
+	restored := department
+	restored.DeletedAt = gorm.DeletedAt{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&Department{}).Where("id = ?", department.ID).Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+		return recordAudit(c, tx, AuditRestore, EntityDepartment, department.ID, department, restored)
+	})
+	if err != nil {
+		AbortWithInternalError(c, err, "Restore error")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"msg": "Delete Complete",
+		"msg":        "Department Restore Complete",
+		"department": newDepartmentResponse(restored),
 	})
 }
 
+/* 바로 아래 하위 부서 목록 */
+func ReadDepartmentChildren(c *gin.Context) {
+	var department Department
+	db.Where("Department_Name = ?", c.Param("name")).Find(&department)
+	if department.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return
+	}
+
+	var children []Department
+	result := db.Where("parent_id = ?", department.ID).Order("department_name asc").Find(&children)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "READ error")
+		return
+	}
+
+	c.JSON(http.StatusOK, newDepartmentResponses(children))
+}
+
+/* 모든 하위 부서 목록 (위 단계부터) */
+func ReadDepartmentDescendants(c *gin.Context) {
+	var department Department
+	db.Where("Department_Name = ?", c.Param("name")).Find(&department)
+	if department.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return
+	}
+
+	descendants, err := findDescendants(db, department.ID)
+	if err != nil {
+		AbortWithInternalError(c, err, "READ error")
+		return
+	}
+
+	c.JSON(http.StatusOK, newDepartmentResponses(descendants))
+}
+
+func findParentDepartment(tx *gorm.DB, name string) (parent Department, apiErr *ApiError) {
+	tx.Where("Department_Name = ?", name).Find(&parent)
+	if parent.ID == 0 {
+		apiErr = NewApiError(http.StatusUnprocessableEntity, CodeDepartmentNotFound, "No such parent department: "+name)
+		apiErr.Errors = []FieldError{{Field: "parent", Rule: "exists", Message: "parent should be an existing department"}}
+	}
+	return
+}
+
+/* 하위 부서를 단계별로 조회. 데이터가 잘못되어 순환이 있어도 멈추도록 visited 사용 */
+func findDescendants(tx *gorm.DB, id uint) ([]Department, error) {
+	descendants := make([]Department, 0)
+	visited := map[uint]bool{id: true}
+	level := []uint{id}
+
+	for len(level) > 0 {
+		var children []Department
+		err := tx.Where("parent_id IN ?", level).Order("department_name asc").Find(&children).Error
+		if err != nil {
+			return nil, err
+		}
+
+		level = level[:0]
+		for _, child := range children {
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			descendants = append(descendants, child)
+			level = append(level, child.ID)
+		}
+	}
+	return descendants, nil
+}
+
 /* 해당 이름의 모든 부서 조회 */
 func SearchDepartmentByName(c *gin.Context) {
 	name := c.Param("name")
 
 	var departments []Department
 
-	result := db.Where("Department_Name = ?", name).Preload("Department_Employees").Find(&departments)
-	if result.Error != nil {
-		log.Println(result.Error)
+	asOf, apiErr := parseAsOf(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	options, apiErr := parseReadOptions(c, &DepartmentResponse{}, departmentIncludes, "employees")
+	if apiErr == nil && asOf != nil {
+		apiErr = options.checkAsOf("employees")
+	}
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	if asOf != nil {
+		result := db.Where("Department_Name = ?", name).Find(&departments)
+		if result.Error != nil {
+			AbortWithInternalError(c, result.Error, "Database error")
+			return
+		}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": result.Error.Error(),
-		})
-		c.Abort()
+		// 그 시점에 소속된 사원의 그 시점 정보
+		for i := range departments {
+			if !options.Has("employees") {
+				break
+			}
+			departments[i].Department_Employees = []*Employee{}
+			result = employeesAsOf(db, *asOf).Where("employees.id IN (?)",
+				assignmentsAsOf(db, *asOf).Select("employee_id").Where("department_id = ?", departments[i].ID)).
+				Order("employees.id asc").Find(&departments[i].Department_Employees)
+			if result.Error != nil {
+				AbortWithInternalError(c, result.Error, "Database error")
+				return
+			}
+		}
+		options.JSON(c, http.StatusOK, newDepartmentResponses(departments))
+		return
+	}
+
+	result := options.Preload(db.Where("Department_Name = ?", name)).Find(&departments)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Database error")
 		return
 	}
 
-	c.JSON(http.StatusOK, departments)
+	options.JSON(c, http.StatusOK, newDepartmentResponses(departments))
 }
 
-/* 부서 내 소속된 사원 목록 출력 */
+/* 부서 내 소속된 사원 목록 출력. ?recursive=true면 하위 부서의 사원도 포함, ?as_of=면 그 시점의 사원, ?include_deleted=true면 삭제된 사원 포함 */
 func ReadEmployeeInDepartment(c *gin.Context) {
 	dname := c.Param("name")
 
 	var employees []Employee
 	var department Department
 
-	result := db.Where("Department_Name=?", dname).Find(&department)
+	// include_deleted면 삭제된 부서와 사원도 조회
+	scope, apiErr := includeDeleted(c, db)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	result := scope.Where("Department_Name=?", dname).Find(&department)
 
-	limit, page, sort := Paging(c)
-	offset := (page - 1) * limit
-	fmt.Println(department)
+	recursive, err := strconv.ParseBool(c.DefaultQuery("recursive", "false"))
+	if err != nil {
+		AbortWithError(c, InvalidParameter("recursive", "recursive should be true or false"))
+		return
+	}
 
-	if (result.Error != nil) || (department.ID == 0) {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "Error on Read Employees in Department",
-		})
-		c.Abort()
+	pagination, pagingErr := Paging(c, &Employee{}, employeeSortFields...)
+	if pagingErr != nil {
+		AbortWithError(c, pagingErr)
+		return
+	}
+	asOf, apiErr := parseAsOf(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	options, apiErr := parseReadOptions(c, &EmployeeResponse{}, employeeIncludes)
+	if apiErr == nil && asOf != nil {
+		apiErr = options.checkAsOf("departments")
+	}
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
 		return
 	}
-	log.Println(department)
 
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Error on Read Employees in Department")
+		return
+	} else if department.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+		return
+	}
+
+	departmentIDs := []uint{department.ID}
+	if recursive {
+		descendants, err := findDescendants(db, department.ID)
+		if err != nil {
+			AbortWithInternalError(c, err, "Error on Read Employees in Department")
+			return
+		}
+		for _, descendant := range descendants {
+			departmentIDs = append(departmentIDs, descendant.ID)
+		}
+	}
+
+	// 여러 하위 부서에 속한 사원이 중복되지 않도록 IN subquery 사용. 끝난 소속은 제외
+	// as_of면 그 시점의 소속과 사원 정보. 부서 구조는 현재 기준
 	//db.Model(&department).Association("Department_Employees").Find(&employees)
-	db.Limit(limit).Offset(offset).Order(sort).Model(&department).Association("Department_Employees").Find(&employees)
+	employeeQuery := scope.Model(&Employee{})
+	assignmentQuery := db.Model(&Assignment{})
+	if asOf != nil {
+		employeeQuery = employeesAsOf(db, *asOf)
+		assignmentQuery = assignmentsAsOf(db, *asOf)
+	}
+	preloads := options.Preloads
+	if asOf != nil {
+		preloads = nil
+	}
+	response, err := pagination.Find(c, employeeQuery.Where("employees.id IN (?)",
+		assignmentQuery.Select("employee_id").Where("department_id IN ?", departmentIDs)), &employees, preloads...)
+	if err == nil && asOf != nil && options.Has("departments") {
+		err = fillDepartmentsAsOf(db, employees, *asOf)
+	}
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on Read Employees in Department")
+		return
+	}
+	response.Data = newEmployeeResponses(employees)
 
-	c.JSON(http.StatusOK, employees)
+	options.JSON(c, http.StatusOK, response)
 }
@@ -1,11 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"strconv"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -17,15 +17,16 @@ func TestAddDepartment(t *testing.T) {
 	assert.NoError(t, err)
 
 	var result map[string]interface{}
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.POST("/api/department/:name", AddDepartment)
+	router.POST("/api/department/", AddDepartment)
 
+	payload, _ := json.Marshal(dData{DName: []string{"Test Department"}})
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("POST", "/api/department/Test Department", nil)
+	request, _ := http.NewRequest("POST", "/api/department/", bytes.NewBuffer(payload))
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
@@ -34,7 +35,7 @@ func TestAddDepartment(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Equal(t, "Test Department", result["msg"])
+	assert.Equal(t, []interface{}{"Test Department: Create Success"}, result["msg"])
 
 	db.Unscoped().Where("Department_Name = ?", "Test Department").Delete(&Department{})
 }
@@ -43,8 +44,9 @@ func TestReadDepartment(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	var results []Department
-	token, err := GenerateToken("gotest", "myCA")
+	var departments []DepartmentResponse
+	results := PageResponse{Data: &departments}
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -68,8 +70,9 @@ func TestReadDepartmentPaging(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	var results []Department
-	token, err := GenerateToken("gotest", "myCA")
+	var departments []DepartmentResponse
+	results := PageResponse{Data: &departments}
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -92,7 +95,7 @@ func TestReadDepartmentInvalidPaging(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -105,7 +108,7 @@ func TestReadDepartmentInvalidPaging(t *testing.T) {
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestUpdateDepartment(t *testing.T) {
@@ -113,12 +116,12 @@ func TestUpdateDepartment(t *testing.T) {
 	assert.NoError(t, err)
 	var result Department
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.PUT("/api/department/:id/:new", UpdateDepartment)
+	router.PUT("/api/department/", UpdateDepartment)
 
 	// Create Data for Test
 	newName := "New Name"
@@ -128,9 +131,8 @@ func TestUpdateDepartment(t *testing.T) {
 	db.Create(&newDepartment)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/department/" + strconv.FormatUint(uint64(newDepartment.ID), 10) + "/" + newName
-	fmt.Println(requrl)
-	request, _ := http.NewRequest("PUT", requrl, nil)
+	payload, _ := json.Marshal(UpdateData{PrevName: newDepartment.Department_Name, NewName: newName})
+	request, _ := http.NewRequest("PUT", "/api/department/", bytes.NewBuffer(payload))
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
@@ -147,12 +149,12 @@ func TestDeleteDepartment(t *testing.T) {
 	assert.NoError(t, err)
 	var result Department
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.DELETE("/api/department/:id", DeleteDepartment)
+	router.DELETE("/api/department/:name", DeleteDepartment)
 
 	test := Department{ // 지울 data 정보
 		Department_Name: "deleteTest",
@@ -161,7 +163,7 @@ func TestDeleteDepartment(t *testing.T) {
 	assert.NoError(t, createResult.Error)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/department/" + strconv.FormatUint(uint64(test.ID), 10)
+	requrl := "/api/department/" + test.Department_Name
 	request, _ := http.NewRequest("DELETE", requrl, nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -177,12 +179,12 @@ func TestDeleteDepartmentInvalidDid(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.DELETE("/api/department/:id", DeleteDepartment)
+	router.DELETE("/api/department/:name", DeleteDepartment)
 
 	w := httptest.NewRecorder()
 	request, _ := http.NewRequest("DELETE", "/api/department/-1", nil) // Use invalid department id
@@ -190,14 +192,14 @@ func TestDeleteDepartmentInvalidDid(t *testing.T) {
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestSearchDepartmentByName(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -212,3 +214,245 @@ func TestSearchDepartmentByName(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+/* Division -> Team A -> Squad, Division -> Team B */
+func departmentTreeData(t *testing.T) (*gin.Engine, string, map[string]*Department) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/department/", AddDepartment)
+	router.GET("/api/department/:name/children", ReadDepartmentChildren)
+	router.GET("/api/department/:name/descendants", ReadDepartmentDescendants)
+	router.GET("/api/department/:name/employee", ReadEmployeeInDepartment)
+	router.PUT("/api/department/:name/parent", MoveDepartment)
+	router.DELETE("/api/department/:name", DeleteDepartment)
+	router.POST("/api/department/:name/restore", RestoreDepartment)
+
+	departments := map[string]*Department{}
+	for _, pair := range [][2]string{{"Tree Division", ""}, {"Tree Team A", "Tree Division"}, {"Tree Squad", "Tree Team A"}, {"Tree Team B", "Tree Division"}} {
+		department := &Department{Department_Name: pair[0]}
+		if parent, ok := departments[pair[1]]; ok {
+			department.ParentID = &parent.ID
+		}
+		db.Create(department)
+		departments[pair[0]] = department
+	}
+	t.Cleanup(func() {
+		for _, department := range departments {
+			db.Model(department).Association("Department_Employees").Clear()
+			db.Unscoped().Delete(department)
+		}
+	})
+
+	return router, token, departments
+}
+
+func departmentRequest(router *gin.Engine, token string, method string, url string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	return w
+}
+
+func TestDepartmentHierarchy(t *testing.T) {
+	router, token, departments := departmentTreeData(t)
+
+	w := departmentRequest(router, token, "GET", "/api/department/Tree Division/children", "")
+	var children []DepartmentResponse
+	err := json.Unmarshal(w.Body.Bytes(), &children)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(children))
+
+	w = departmentRequest(router, token, "GET", "/api/department/Tree Division/descendants", "")
+	var descendants []DepartmentResponse
+	err = json.Unmarshal(w.Body.Bytes(), &descendants)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 3, len(descendants)) {
+		assert.Equal(t, "Tree Squad", descendants[2].Name)
+	}
+
+	// 상위 부서를 지정해서 생성
+	w = departmentRequest(router, token, "POST", "/api/department/", `{"dname": ["Tree Squad 2"], "parent": "Tree Team B"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var created Department
+	db.Where("Department_Name = ?", "Tree Squad 2").Find(&created)
+	departments["Tree Squad 2"] = &created
+	if assert.NotNil(t, created.ParentID) {
+		assert.Equal(t, departments["Tree Team B"].ID, *created.ParentID)
+	}
+
+	w = departmentRequest(router, token, "POST", "/api/department/", `{"dname": ["Tree Orphan"], "parent": "No Department"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	// 자기 하위 부서 아래로는 이동할 수 없음
+	w = departmentRequest(router, token, "PUT", "/api/department/Tree Division/parent", `{"parent": "Tree Squad"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = departmentRequest(router, token, "PUT", "/api/department/Tree Division/parent", `{"parent": "Tree Division"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = departmentRequest(router, token, "PUT", "/api/department/Tree Squad/parent", `{"parent": "Tree Team B"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var moved Department
+	db.Where("id = ?", departments["Tree Squad"].ID).Find(&moved)
+	assert.Equal(t, departments["Tree Team B"].ID, *moved.ParentID)
+
+	// 최상위로 이동
+	w = departmentRequest(router, token, "PUT", "/api/department/Tree Squad/parent", `{"parent": ""}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var top Department
+	db.Where("id = ?", departments["Tree Squad"].ID).Find(&top)
+	assert.Nil(t, top.ParentID)
+}
+
+func TestDeleteDepartmentWithChildren(t *testing.T) {
+	router, token, departments := departmentTreeData(t)
+
+	w := departmentRequest(router, token, "DELETE", "/api/department/Tree Team A", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	// 하위 부서는 응답 형식으로
+	var conflict struct {
+		Details struct {
+			Descendants []map[string]interface{} `json:"descendants"`
+		} `json:"details"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &conflict))
+	if assert.Equal(t, 1, len(conflict.Details.Descendants)) {
+		assert.Equal(t, "Tree Squad", conflict.Details.Descendants[0]["name"])
+		assert.NotContains(t, conflict.Details.Descendants[0], "Department_Name")
+	}
+	w = departmentRequest(router, token, "DELETE", "/api/department/Tree Team A?strategy=move", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 하위 부서는 Division 아래로
+	w = departmentRequest(router, token, "DELETE", "/api/department/Tree Team A?strategy=reparent", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var squad Department
+	db.Where("id = ?", departments["Tree Squad"].ID).Find(&squad)
+	assert.Equal(t, departments["Tree Division"].ID, *squad.ParentID)
+	// 옮겨진 하위 부서도 감사 기록
+	var moveLogs []AuditLog
+	db.Where("action = ? AND entity_type = ? AND entity_id = ?", AuditUpdate, EntityDepartment, squad.ID).Order("id desc").Limit(1).Find(&moveLogs)
+	if assert.Equal(t, 1, len(moveLogs)) {
+		assert.Equal(t, map[string]interface{}{"from": float64(departments["Tree Team A"].ID), "to": float64(departments["Tree Division"].ID)},
+			moveLogs[0].Changes["parentId"])
+	}
+
+	// 하위 부서까지 삭제
+	w = departmentRequest(router, token, "DELETE", "/api/department/Tree Division?strategy=cascade", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&Department{}).Where("Department_Name LIKE ?", "Tree %").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestReadEmployeeInDepartmentRecursive(t *testing.T) {
+	router, token, departments := departmentTreeData(t)
+
+	employee1 := Employee{Employee_Name: "Tree Employee 1"}
+	employee2 := Employee{Employee_Name: "Tree Employee 2"}
+	db.Create(&employee1)
+	db.Create(&employee2)
+	db.Model(&employee1).Association("Employee_Departments").Append(departments["Tree Division"], departments["Tree Squad"])
+	db.Model(&employee2).Association("Employee_Departments").Append(departments["Tree Team B"])
+
+	var employees []EmployeeResponse
+	results := PageResponse{Data: &employees}
+	w := departmentRequest(router, token, "GET", "/api/department/Tree Division/employee", "")
+	err := json.Unmarshal(w.Body.Bytes(), &results)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, len(employees))
+
+	// 여러 하위 부서에 속해도 한 번만
+	employees = nil
+	w = departmentRequest(router, token, "GET", "/api/department/Tree Division/employee?recursive=true", "")
+	err = json.Unmarshal(w.Body.Bytes(), &results)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(employees))
+	assert.Equal(t, int64(2), *results.Total)
+
+	db.Model(&employee1).Association("Employee_Departments").Clear()
+	db.Model(&employee2).Association("Employee_Departments").Clear()
+	db.Unscoped().Delete(&employee1)
+	db.Unscoped().Delete(&employee2)
+}
+
+func TestSoftDeleteAndRestoreDepartment(t *testing.T) {
+	router, token, departments := departmentTreeData(t)
+
+	w := departmentRequest(router, token, "DELETE", "/api/department/Tree Team A?strategy=cascade", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var squad Department
+	db.Unscoped().Where("id = ?", departments["Tree Squad"].ID).Find(&squad)
+	assert.True(t, squad.DeletedAt.Valid)
+
+	// 삭제된 부서의 이름은 사용할 수 없음
+	w = departmentRequest(router, token, "POST", "/api/department/", `{"dname": ["Tree Squad"]}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "is deleted")
+
+	w = departmentRequest(router, token, "GET", "/api/department/Tree Team A/children", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 상위 부서부터 되돌려야 함
+	w = departmentRequest(router, token, "POST", "/api/department/Tree Squad/restore", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = departmentRequest(router, token, "POST", "/api/department/Tree Team A/restore", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = departmentRequest(router, token, "POST", "/api/department/Tree Squad/restore", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = departmentRequest(router, token, "POST", "/api/department/Tree Squad/restore", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var children []DepartmentResponse
+	w = departmentRequest(router, token, "GET", "/api/department/Tree Team A/children", "")
+	err := json.Unmarshal(w.Body.Bytes(), &children)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(children))
+}
+
+func TestAddAndRenameDepartmentV2(t *testing.T) {
+	router, token, departments := departmentTreeData(t)
+	router.POST("/api/v2/department/", AddDepartmentV2)
+	router.PUT("/api/v2/department/:name", RenameDepartmentV2)
+	t.Cleanup(func() {
+		db.Unscoped().Where("Department_Name LIKE ?", "Tree V2 %").Delete(&Department{})
+	})
+
+	w := departmentRequest(router, token, "POST", "/api/v2/department/", `{"names": ["Tree V2 A", "Tree V2 B"], "parent": "Tree Team B"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data []DepartmentResponse `json:"data"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(created.Data)) {
+		assert.Equal(t, "Tree V2 A", created.Data[0].Name)
+		assert.Equal(t, departments["Tree Team B"].ID, *created.Data[1].ParentID)
+	}
+
+	w = departmentRequest(router, token, "POST", "/api/v2/department/", `{"names": []}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = departmentRequest(router, token, "POST", "/api/v2/department/", `{"names": ["Tree V2 A"]}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = departmentRequest(router, token, "PUT", "/api/v2/department/Tree V2 A", `{"name": "Tree V2 C"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var renamed DepartmentResponse
+	err = json.Unmarshal(w.Body.Bytes(), &renamed)
+	assert.NoError(t, err)
+	assert.Equal(t, "Tree V2 C", renamed.Name)
+	assert.Equal(t, created.Data[0].ID, renamed.ID)
+
+	w = departmentRequest(router, token, "PUT", "/api/v2/department/Tree V2 C", `{"name": "Tree V2 B"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = departmentRequest(router, token, "PUT", "/api/v2/department/Tree V2 Missing", `{"name": "Tree V2 D"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
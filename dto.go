@@ -0,0 +1,190 @@
+package main
+
+import "time"
+
+// 응답에 사용하는 타입. gorm model을 그대로 보내지 않아서 Table 구조가 바뀌어도 응답 형식은 유지됨
+
+// 사원 응답. departments, manager는 include한 경우에만. departments는 소속이 없어도 빈 배열
+type EmployeeResponse struct {
+	ID             uint                `json:"id"`
+	Name           string              `json:"name"`
+	Email          string              `json:"email"`
+	Phone          string              `json:"phone"`
+	JobTitle       string              `json:"jobTitle"`
+	EmploymentType string              `json:"employmentType"`
+	Status         string              `json:"status"`
+	EntryTime      time.Time           `json:"entryTime"`
+	ManagerID      *uint               `json:"managerId"`
+	Manager        *EmployeeSummary    `json:"manager,omitempty"`
+	Attributes     JSONMap             `json:"attributes,omitempty"`
+	DeletedAt      *time.Time          `json:"deletedAt,omitempty"`
+	Departments    []DepartmentSummary `json:"departments"`
+}
+
+// 다른 응답 안에 들어가는 사원 (상사 등)
+type EmployeeSummary struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	JobTitle string `json:"jobTitle,omitempty"`
+}
+
+// 부서 응답. employees는 include한 경우에만. 소속 사원이 없어도 빈 배열
+type DepartmentResponse struct {
+	ID        uint               `json:"id"`
+	Name      string             `json:"name"`
+	ParentID  *uint              `json:"parentId"`
+	DeletedAt *time.Time         `json:"deletedAt,omitempty"`
+	Employees []EmployeeResponse `json:"employees"`
+}
+
+// 다른 응답 안에 들어가는 부서. 소속 사원은 포함하지 않음
+type DepartmentSummary struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	ParentID *uint  `json:"parentId"`
+}
+
+// 부서 소속 응답. endDate가 null이면 현재 소속
+type AssignmentResponse struct {
+	ID           uint               `json:"id"`
+	EmployeeID   uint               `json:"employeeId"`
+	DepartmentID uint               `json:"departmentId"`
+	Department   *DepartmentSummary `json:"department,omitempty"`
+	Role         string             `json:"role"`
+	Allocation   int                `json:"allocation"`
+	IsPrimary    bool               `json:"isPrimary"`
+	StartDate    time.Time          `json:"startDate"`
+	EndDate      *time.Time         `json:"endDate"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	UpdatedAt    time.Time          `json:"updatedAt"`
+}
+
+// 계정 응답. 비밀번호 hash 등 내부 항목은 제외
+type AccountResponse struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	CA        string    `json:"ca"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newEmployeeResponse(employee Employee) EmployeeResponse {
+	response := EmployeeResponse{
+		ID:             employee.ID,
+		Name:           employee.Employee_Name,
+		Email:          employee.Email,
+		Phone:          employee.Phone,
+		JobTitle:       employee.JobTitle,
+		EmploymentType: employee.EmploymentType,
+		Status:         employee.Status,
+		EntryTime:      employee.EntryTime,
+		ManagerID:      employee.ManagerID,
+		Attributes:     employee.Attributes,
+		DeletedAt:      deletedTime(employee.DeletedAt),
+		Departments:    make([]DepartmentSummary, 0, len(employee.Employee_Departments)),
+	}
+	if employee.Manager != nil {
+		response.Manager = &EmployeeSummary{ID: employee.Manager.ID, Name: employee.Manager.Employee_Name, JobTitle: employee.Manager.JobTitle}
+	}
+	for _, department := range employee.Employee_Departments {
+		response.Departments = append(response.Departments, newDepartmentSummary(*department))
+	}
+	return response
+}
+
+func newEmployeeResponses(employees []Employee) []EmployeeResponse {
+	responses := make([]EmployeeResponse, len(employees))
+	for i, employee := range employees {
+		responses[i] = newEmployeeResponse(employee)
+	}
+	return responses
+}
+
+func newDepartmentSummary(department Department) DepartmentSummary {
+	return DepartmentSummary{ID: department.ID, Name: department.Department_Name, ParentID: department.ParentID}
+}
+
+func newDepartmentResponse(department Department) DepartmentResponse {
+	response := DepartmentResponse{
+		ID:        department.ID,
+		Name:      department.Department_Name,
+		ParentID:  department.ParentID,
+		DeletedAt: deletedTime(department.DeletedAt),
+		Employees: make([]EmployeeResponse, 0, len(department.Department_Employees)),
+	}
+	for _, employee := range department.Department_Employees {
+		response.Employees = append(response.Employees, newEmployeeResponse(*employee))
+	}
+	return response
+}
+
+func newDepartmentResponses(departments []Department) []DepartmentResponse {
+	responses := make([]DepartmentResponse, len(departments))
+	for i, department := range departments {
+		responses[i] = newDepartmentResponse(department)
+	}
+	return responses
+}
+
+func newAssignmentResponse(assignment Assignment) AssignmentResponse {
+	response := AssignmentResponse{
+		ID:           assignment.ID,
+		EmployeeID:   assignment.EmployeeID,
+		DepartmentID: assignment.DepartmentID,
+		Role:         assignment.Role,
+		Allocation:   assignment.Allocation,
+		IsPrimary:    assignment.IsPrimary,
+		StartDate:    assignment.StartDate,
+		EndDate:      deletedTime(assignment.EndDate),
+		CreatedAt:    assignment.CreatedAt,
+		UpdatedAt:    assignment.UpdatedAt,
+	}
+	if assignment.Department != nil {
+		summary := newDepartmentSummary(*assignment.Department)
+		response.Department = &summary
+	}
+	return response
+}
+
+func newAssignmentResponses(assignments []Assignment) []AssignmentResponse {
+	responses := make([]AssignmentResponse, len(assignments))
+	for i, assignment := range assignments {
+		responses[i] = newAssignmentResponse(assignment)
+	}
+	return responses
+}
+
+func newAccountResponse(account Account) AccountResponse {
+	return AccountResponse{ID: account.ID, Email: account.Email, CA: account.CA, Role: account.Role, CreatedAt: account.CreatedAt}
+}
+
+func newAccountResponses(accounts []Account) []AccountResponse {
+	responses := make([]AccountResponse, len(accounts))
+	for i, account := range accounts {
+		responses[i] = newAccountResponse(account)
+	}
+	return responses
+}
+
+/* model이면 응답 타입으로 바꿈. 감사 기록의 before, after도 응답과 같은 형식으로 남기기 위해 사용 */
+func toResponse(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Employee:
+		return newEmployeeResponse(v)
+	case *Employee:
+		return newEmployeeResponse(*v)
+	case Department:
+		return newDepartmentResponse(v)
+	case *Department:
+		return newDepartmentResponse(*v)
+	case Assignment:
+		return newAssignmentResponse(v)
+	case *Assignment:
+		return newAssignmentResponse(*v)
+	case Account:
+		return newAccountResponse(v)
+	case *Account:
+		return newAccountResponse(*v)
+	}
+	return value
+}
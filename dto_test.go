@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestEmployeeResponse(t *testing.T) {
+	managerID := uint(1)
+	parentID := uint(3)
+	manager := Employee{ID: managerID, Employee_Name: "DTO Manager", JobTitle: "Lead"}
+	department := &Department{ID: 4, Department_Name: "DTO Team", ParentID: &parentID,
+		Department_Employees: []*Employee{{ID: 9}}}
+	employee := Employee{ID: 2, Employee_Name: "DTO Kim", JobTitle: "Engineer", ManagerID: &managerID, Manager: &manager,
+		Employee_Departments: []*Department{department}}
+
+	b, err := json.Marshal(newEmployeeResponse(employee))
+	assert.NoError(t, err)
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "DTO Kim", m["name"])
+	assert.Equal(t, "Engineer", m["jobTitle"])
+	assert.Equal(t, float64(1), m["managerId"])
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "DTO Manager", "jobTitle": "Lead"}, m["manager"])
+	// 부서 안의 사원은 다시 포함하지 않음
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": float64(4), "name": "DTO Team", "parentId": float64(3)}}, m["departments"])
+	assert.NotContains(t, m, "deletedAt")
+	assert.NotContains(t, m, "Employee_Name")
+
+	employee.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	employee.Manager = nil
+	employee.Employee_Departments = nil
+	response := newEmployeeResponse(employee)
+	assert.NotNil(t, response.DeletedAt)
+	assert.Nil(t, response.Manager)
+	assert.Equal(t, []DepartmentSummary{}, response.Departments)
+
+	// 소속이 없어도 null이 아닌 빈 배열
+	b, err = json.Marshal(response)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"departments":[]`)
+	b, err = json.Marshal(newDepartmentResponse(Department{ID: 4, Department_Name: "DTO Team"}))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"employees":[]`)
+}
+
+func TestAccountResponse(t *testing.T) {
+	account := Account{Email: "dto@test.com", CA: "LOCAL", Role: RoleEditor, PasswordHash: "hash"}
+	account.ID = 5
+	account.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+	b, err := json.Marshal(toResponse(&account))
+	assert.NoError(t, err)
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	assert.ElementsMatch(t, []string{"id", "email", "ca", "role", "createdAt"}, keys)
+	assert.Equal(t, "LOCAL", m["ca"])
+
+	// model이 아니면 그대로
+	assert.Equal(t, "text", toResponse("text"))
+}
@@ -1,138 +1,518 @@
 package main
 
 import (
-	"log"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 var letterRunes = []rune("ABCDEFGHIJKLMNOPQRSPUGWSYZ")
 
+// 사원 고용 형태와 재직 상태
+const (
+	EmploymentFullTime = "full-time"
+	EmploymentPartTime = "part-time"
+	EmploymentContract = "contract"
+	EmploymentIntern   = "intern"
+
+	StatusActive     = "active"
+	StatusOnLeave    = "on-leave"
+	StatusTerminated = "terminated"
+)
+
 // Employee Table
 type Employee struct {
 	ID                   uint      `gorm:"primaryKey"`
 	EntryTime            time.Time `gorm:"autoCreateTime"`
 	Employee_Name        string
-	Employee_Departments []*Department `gorm:"many2many:employee_departments"`
+	Email                string         `gorm:"size:255;index"`
+	Phone                string         `gorm:"size:32"`
+	JobTitle             string         `gorm:"size:128"`
+	EmploymentType       string         `gorm:"size:32;default:full-time"`
+	Status               string         `gorm:"size:32;default:active;index"`
+	ManagerID            *uint          `gorm:"index"` // 상사(Employee.ID). 없으면 null
+	Manager              *Employee      `gorm:"foreignKey:ManagerID" json:",omitempty"`
+	Attributes           JSONMap        // 회사별로 필요한 추가 항목
+	DeletedAt            gorm.DeletedAt `gorm:"index"` // 삭제된 사원은 기본 조회에서 제외. restore로 되돌릴 수 있음
+	Employee_Departments []*Department  `gorm:"many2many:employee_departments"`
+}
+
+// JSON column에 저장되는 key-value 값
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONMap", value)
+	}
+	return json.Unmarshal(data, m)
+}
+
+func (JSONMap) GormDataType() string {
+	return "json"
+}
+
+/* MySQL은 JSON, SQLite는 TEXT column */
+func (JSONMap) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "mysql" {
+		return "JSON"
+	}
+	return "TEXT"
 }
 
+// 사원 목록에서 sort에 사용할 수 있는 column
+var employeeSortFields = []string{"id", "entry_time", "employee_name"}
+
 type eData struct {
-	EName string `json:"ename" binding:"required"`
-	DName string `json:"dname"`
+	EName          string   `json:"ename" binding:"required,max=255"`
+	DName          string   `json:"dname,omitempty"`  // 부서 하나만 지정할 때 (이전 형식)
+	DNames         []string `json:"dnames,omitempty"` // 여러 부서에 동시에 소속
+	Email          string   `json:"email,omitempty" binding:"omitempty,email,max=255"`
+	Phone          string   `json:"phone,omitempty" binding:"omitempty,phone"`
+	JobTitle       string   `json:"job_title,omitempty" binding:"max=128"`
+	EmploymentType string   `json:"employment_type,omitempty" binding:"omitempty,oneof=full-time part-time contract intern"`
+	Status         string   `json:"status,omitempty" binding:"omitempty,oneof=active on-leave terminated"`
+	ManagerID      uint     `json:"manager_id,omitempty"`
+	Attributes     JSONMap  `json:"attributes,omitempty"`
+}
+
+// 사원 정보 수정. 보낸 항목만 수정
+type eUpdateData struct {
+	EName          *string  `json:"ename" binding:"omitempty,min=1,max=255"`
+	Email          *string  `json:"email" binding:"omitempty,max=255"`
+	Phone          *string  `json:"phone" binding:"omitempty,max=32"`
+	JobTitle       *string  `json:"job_title" binding:"omitempty,max=128"`
+	EmploymentType *string  `json:"employment_type" binding:"omitempty,oneof=full-time part-time contract intern"`
+	Status         *string  `json:"status" binding:"omitempty,oneof=active on-leave terminated"`
+	ManagerID      *uint    `json:"manager_id"` // 0이면 상사 없음
+	Attributes     *JSONMap `json:"attributes"`
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{3,31}$`)
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+			return phonePattern.MatchString(fl.Field().String())
+		})
+	}
+}
+
+/* dname과 dnames를 합친 부서 이름 목록 (중복 제거) */
+func (data *eData) departmentNames() []string {
+	names := make([]string, 0, len(data.DNames)+1)
+	for _, name := range data.DNames {
+		if name != "" && !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	if data.DName != "" && !containsString(names, data.DName) {
+		names = append(names, data.DName)
+	}
+	return names
+}
+
+/* 상사로 지정할 사원이 있는지 확인 */
+func checkManager(tx *gorm.DB, managerID uint) *ApiError {
+	var count int64
+	tx.Model(&Employee{}).Where("id = ?", managerID).Count(&count)
+	if count == 0 {
+		apiErr := NewApiError(http.StatusUnprocessableEntity, CodeEmployeeNotFound, "No such manager")
+		apiErr.Errors = []FieldError{{Field: "manager_id", Rule: "exists", Message: "manager_id should be an existing employee"}}
+		return apiErr
+	}
+	return nil
+}
+
+// 일괄 생성에서 항목 하나의 처리 결과
+type bulkResult struct {
+	Index  int       `json:"index"`
+	Status int       `json:"status"`
+	ID     uint      `json:"id,omitempty"`
+	Name   string    `json:"ename"`
+	Error  *ApiError `json:"error,omitempty"`
 }
 
-/* 새로운 Employee 추가(C) */
+/* 새로운 Employee 추가(C). atomic=true(기본)면 전부 성공하거나 전부 취소, false면 항목별로 처리 */
 func AddEmployee(c *gin.Context) {
 	var data []eData
-	var department Department
-	var employee Employee
-	var temp string
-	msg := make([]string, 0, 3)
-	err := c.ShouldBindJSON(&data)
+	// binding은 배열 항목의 위치를 알려주지 않으므로 decode 후 항목별로 직접 검증
+	err := json.NewDecoder(c.Request.Body).Decode(&data)
 	if err != nil {
-		log.Println(err)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "invalid json",
-		})
-		c.Abort()
+		AbortWithBindError(c, err)
 		return
 	}
 
-	for i := 0; i < len(data); i++ {
-		employee = Employee{}
-		employee.Employee_Name = data[i].EName
-		if data[i].DName == "" {
-			db.Create(&employee)
-			temp = employee.Employee_Name + ": Create Success without department"
-			msg = append(msg, temp)
+	results := make([]bulkResult, len(data))
+	items := make([]interface{}, len(data))
+	for i := range data {
+		results[i] = bulkResult{Index: i, Name: data[i].EName}
+		items[i] = &data[i]
+	}
+	if !addEmployees(c, items, data, results) {
+		return
+	}
 
+	status := http.StatusOK
+	msg := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Error != nil {
+			status = http.StatusMultiStatus
+			msg = append(msg, result.Name+": Create Fail. "+result.Error.Detail)
+		} else if names := data[result.Index].departmentNames(); len(names) == 0 {
+			msg = append(msg, result.Name+": Create Success without department")
 		} else {
-			department = Department{}
-			db.Where("Department_Name = ?", data[i].DName).Find(&department)
-			if department.ID == 0 { // department name incorrect. Abort API with msg
-				temp = employee.Employee_Name + ": Create Fail. Department " + data[i].DName + " is not exist"
-				msg = append(msg, temp)
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-					"msg":           msg,
-					"not processed": data[i:],
-				})
-				return
+			msg = append(msg, result.Name+": Create Success in department "+strings.Join(names, ", "))
+		}
+	}
+
+	c.JSON(status, gin.H{
+		"msg":     msg,
+		"results": results,
+	})
+}
+
+// v2 사원 추가 body. v1(eData)과 같은 항목을 camelCase로 받고 부서는 departments 하나로
+type employeeRequest struct {
+	Name           string   `json:"name" binding:"required,max=255"`
+	Email          string   `json:"email" binding:"omitempty,email,max=255"`
+	Phone          string   `json:"phone" binding:"omitempty,phone"`
+	JobTitle       string   `json:"jobTitle" binding:"max=128"`
+	EmploymentType string   `json:"employmentType" binding:"omitempty,oneof=full-time part-time contract intern"`
+	Status         string   `json:"status" binding:"omitempty,oneof=active on-leave terminated"`
+	ManagerID      uint     `json:"managerId"`
+	Attributes     JSONMap  `json:"attributes"`
+	Departments    []string `json:"departments"` // 처음 부서가 주 소속
+}
+
+func (data *employeeRequest) eData() eData {
+	return eData{
+		EName:          data.Name,
+		DNames:         data.Departments,
+		Email:          data.Email,
+		Phone:          data.Phone,
+		JobTitle:       data.JobTitle,
+		EmploymentType: data.EmploymentType,
+		Status:         data.Status,
+		ManagerID:      data.ManagerID,
+		Attributes:     data.Attributes,
+	}
+}
+
+// v2 사원 추가에서 항목 하나의 결과. 성공하면 만들어진 사원
+type employeeResult struct {
+	Index    int               `json:"index"`
+	Status   int               `json:"status"`
+	Employee *EmployeeResponse `json:"employee,omitempty"`
+	Error    *ApiError         `json:"error,omitempty"`
+}
+
+/* v2 사원 추가. 모두 성공하면 201, 일부만 성공하면 207이고 결과에 만들어진 사원을 포함 */
+func AddEmployeeV2(c *gin.Context) {
+	var requests []employeeRequest
+	err := json.NewDecoder(c.Request.Body).Decode(&requests)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+
+	data := make([]eData, len(requests))
+	results := make([]bulkResult, len(requests))
+	items := make([]interface{}, len(requests))
+	for i := range requests {
+		data[i] = requests[i].eData()
+		results[i] = bulkResult{Index: i, Name: requests[i].Name}
+		items[i] = &requests[i]
+	}
+	if !addEmployees(c, items, data, results) {
+		return
+	}
+
+	ids := make([]uint, 0, len(results))
+	for _, result := range results {
+		if result.Error == nil {
+			ids = append(ids, result.ID)
+		}
+	}
+	var employees []Employee
+	if len(ids) > 0 {
+		err = db.Where("id IN ?", ids).Preload("Employee_Departments").Find(&employees).Error
+		if err != nil {
+			AbortWithInternalError(c, err, "Error on Create Employees")
+			return
+		}
+	}
+
+	status := http.StatusCreated
+	response := make([]employeeResult, len(results))
+	for i, result := range results {
+		response[i] = employeeResult{Index: result.Index, Status: result.Status, Error: result.Error}
+		if result.Error != nil {
+			status = http.StatusMultiStatus
+		}
+		for _, employee := range employees {
+			if employee.ID == result.ID {
+				created := newEmployeeResponse(employee)
+				response[i].Employee = &created
 			}
+		}
+	}
+	c.JSON(status, gin.H{
+		"results": response,
+	})
+}
+
+/* items(binding 검증 대상)를 검증하고 data를 생성해서 results에 기록. 전체가 실패해서 응답했으면 false */
+func addEmployees(c *gin.Context, items []interface{}, data []eData, results []bulkResult) bool {
+	atomic := true
+	if value := c.Query("atomic"); value != "" {
+		var err error
+		atomic, err = strconv.ParseBool(value)
+		if err != nil {
+			AbortWithError(c, InvalidParameter("atomic", "atomic should be true or false"))
+			return false
+		}
+	}
 
-			db.Create(&employee)
-			db.Model(&employee).Association("Employee_Departments").Append(&department)
+	for i := range items {
+		err := binding.Validator.ValidateStruct(items[i])
+		if err != nil {
+			if atomic {
+				AbortWithError(c, BindError(err, fmt.Sprintf("[%d].", i)))
+				return false
+			}
+			results[i].Error = BindError(err, fmt.Sprintf("[%d].", i))
+			results[i].Status = results[i].Error.Status
+		}
+	}
 
-			temp = employee.Employee_Name + ": Create Success in department " + data[i].DName
-			msg = append(msg, temp)
+	if !atomic {
+		for i := range data {
+			if results[i].Error != nil {
+				continue
+			}
+			// 항목마다 transaction을 나눠서 실패한 항목만 취소
+			err := db.Transaction(func(tx *gorm.DB) error {
+				createEmployee(c, tx, data[i], &results[i])
+				if results[i].Error != nil {
+					return results[i].Error
+				}
+				return nil
+			})
+			if err != nil && results[i].Error == nil { // commit 실패 등. 만든 사원도 취소됨
+				results[i].Error = internalError(c, err, "Error on Create Employee")
+				results[i].Status = results[i].Error.Status
+				results[i].ID = 0
+			}
 		}
+		return true
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"msg": msg,
+	var failed *bulkResult
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := range data {
+			createEmployee(c, tx, data[i], &results[i])
+			if results[i].Error != nil {
+				failed = &results[i]
+				return results[i].Error
+			}
+		}
+		return nil
 	})
+	if failed != nil {
+		// 나머지 항목은 함께 취소됨
+		for i := range results {
+			if &results[i] != failed {
+				results[i].Status = http.StatusFailedDependency
+				results[i].ID = 0
+			}
+		}
+		AbortWithError(c, NewApiError(failed.Error.Status, failed.Error.Code,
+			fmt.Sprintf("[%d] %s. Nothing is created", failed.Index, failed.Error.Detail)).WithDetails(gin.H{
+			"results": results,
+		}))
+		return false
+	} else if err != nil {
+		AbortWithInternalError(c, err, "Error on Create Employees")
+		return false
+	}
+	return true
 }
 
-/* Employee Table 불러오기(R)_By Paging */
-func ReadEmployee(c *gin.Context) {
-	var employees []Employee
-	limit, page, sort := Paging(c)
-	offset := (page - 1) * limit
+/* 사원 한 명을 생성하고 부서에 배정. 결과는 result에 기록 */
+func createEmployee(c *gin.Context, tx *gorm.DB, data eData, result *bulkResult) {
+	names := data.departmentNames()
+	var departments []*Department
+	if len(names) > 0 {
+		err := tx.Where("Department_Name IN ?", names).Find(&departments).Error
+		if err != nil {
+			result.Error = internalError(c, err, "Error on Create Employee")
+			result.Status = result.Error.Status
+			return
+		}
+	}
 
-	//result := db.Find(&employees)
-	result := db.Limit(limit).Offset(offset).Order(sort).
-		Preload("Employee_Departments").Find(&employees)
+	for _, name := range names {
+		found := false
+		for _, department := range departments {
+			found = found || department.Department_Name == name
+		}
+		if !found { // department name incorrect
+			result.Error = NewApiError(http.StatusUnprocessableEntity, CodeDepartmentNotFound, "Department "+name+" is not exist")
+			result.Status = result.Error.Status
+			return
+		}
+	}
 
-	if result.Error != nil {
-		log.Println(result.Error)
+	employee := Employee{
+		Employee_Name:        data.EName,
+		Email:                data.Email,
+		Phone:                data.Phone,
+		JobTitle:             data.JobTitle,
+		EmploymentType:       data.EmploymentType,
+		Status:               data.Status,
+		Attributes:           data.Attributes,
+		Employee_Departments: departments,
+	}
+	if data.ManagerID != 0 {
+		result.Error = checkManager(tx, data.ManagerID)
+		if result.Error != nil {
+			result.Status = result.Error.Status
+			return
+		}
+		employee.ManagerID = &data.ManagerID
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "Read Error",
-		})
-		c.Abort()
+	err := tx.Omit("Employee_Departments.*").Create(&employee).Error
+	if err != nil {
+		result.Error = internalError(c, err, "Error on Create Employee")
+		result.Status = result.Error.Status
 		return
 	}
-	/*
-		var resultEmployees []readEmployee
-		for i := 0; i < len(employees); i++ {
-			tmp := readEmployee{employees[i].ID, employees[i].Employee_Name, nil}
-			for j := 0; j < len(employees[i].Employee_Departments); j++ {
-				tmp.Department = append(tmp.Department, employees[i].Employee_Departments[j].Department_Name)
+
+	// 처음 적은 부서가 주 소속. departments는 DB 순서이므로 이름으로 찾음
+	if len(departments) > 0 {
+		primary := departments[0]
+		for _, department := range departments {
+			if department.Department_Name == names[0] {
+				primary = department
 			}
-			resultEmployees = append(resultEmployees, tmp)
 		}
-	*/
+		err = tx.Model(&Assignment{}).Where("employee_id = ? AND department_id = ?", employee.ID, primary.ID).Update("is_primary", true).Error
+		if err != nil {
+			result.Error = internalError(c, err, "Error on Create Employee")
+			result.Status = result.Error.Status
+			return
+		}
+	}
 
-	c.JSON(http.StatusOK, employees)
+	err = recordEmployeeHistory(tx, HistoryCreated, employee.ID)
+	if err == nil {
+		err = recordAudit(c, tx, AuditCreate, EntityEmployee, employee.ID, nil, employee)
+	}
+	if err != nil {
+		result.Error = internalError(c, err, "Error on Create Employee")
+		result.Status = result.Error.Status
+		return
+	}
+
+	result.ID = employee.ID
+	result.Status = http.StatusCreated
+}
+
+/* Employee Table 불러오기(R)_By Paging. ?as_of=면 그 시점의 사원과 소속, ?include_deleted=true면 삭제된 사원 포함, ?filter=로 조건 지정. ?include=, ?fields=로 응답 항목 선택 */
+func ReadEmployee(c *gin.Context) {
+	var employees []Employee
+	pagination, pagingErr := Paging(c, &Employee{}, employeeSortFields...)
+	if pagingErr != nil {
+		AbortWithError(c, pagingErr)
+		return
+	}
+	asOf, apiErr := parseAsOf(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	query, apiErr := includeDeleted(c, db.Model(&Employee{}))
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	if asOf != nil {
+		query = employeesAsOf(db, *asOf)
+	}
+	query, apiErr = applyFilter(c, query, employeeFilterFields(asOf))
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	options, apiErr := parseReadOptions(c, &EmployeeResponse{}, employeeIncludes, "departments")
+	if apiErr == nil && asOf != nil {
+		apiErr = options.checkAsOf("departments")
+	}
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	var response *PageResponse
+	var err error
+	if asOf != nil {
+		response, err = pagination.Find(c, query, &employees)
+		if err == nil && options.Has("departments") {
+			err = fillDepartmentsAsOf(db, employees, *asOf)
+		}
+	} else {
+		//result := db.Find(&employees)
+		response, err = pagination.Find(c, query, &employees, options.Preloads...)
+	}
+	if err != nil {
+		AbortWithInternalError(c, err, "Read Error")
+		return
+	}
+	response.Data = newEmployeeResponses(employees)
+
+	options.JSON(c, http.StatusOK, response)
 }
 
 /* 기존의 Employee 내용 수정(U) */
 func UpdateEmployee(c *gin.Context) {
-	var employee Employee
-	dataId := c.Param("id")
-
-	var data eData
+	var data eUpdateData
 	err := c.ShouldBindJSON(&data)
 	if err != nil {
-		log.Println(err)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "invalid json",
-		})
-		c.Abort()
+		AbortWithBindError(c, err)
 		return
 	}
-
-	result := db.Model(&employee).Where("id = ?", dataId).Update("Employee_Name", data.EName)
-	if result.Error != nil {
-		log.Println(result.Error)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "Update error",
-		})
-		c.Abort()
+	if _, ok := updateEmployee(c, data); !ok {
 		return
 	}
 
@@ -141,31 +521,162 @@ func UpdateEmployee(c *gin.Context) {
 	})
 }
 
+// v2 사원 정보 수정 body. 보낸 항목만 수정
+type employeeUpdateRequest struct {
+	Name           *string  `json:"name" binding:"omitempty,min=1,max=255"`
+	Email          *string  `json:"email" binding:"omitempty,max=255"`
+	Phone          *string  `json:"phone" binding:"omitempty,max=32"`
+	JobTitle       *string  `json:"jobTitle" binding:"omitempty,max=128"`
+	EmploymentType *string  `json:"employmentType" binding:"omitempty,oneof=full-time part-time contract intern"`
+	Status         *string  `json:"status" binding:"omitempty,oneof=active on-leave terminated"`
+	ManagerID      *uint    `json:"managerId"` // 0이면 상사 없음
+	Attributes     *JSONMap `json:"attributes"`
+}
+
+/* v2 사원 정보 수정. 수정된 사원을 소속 부서와 함께 응답 */
+func UpdateEmployeeV2(c *gin.Context) {
+	var data employeeUpdateRequest
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	employee, ok := updateEmployee(c, eUpdateData{
+		EName:          data.Name,
+		Email:          data.Email,
+		Phone:          data.Phone,
+		JobTitle:       data.JobTitle,
+		EmploymentType: data.EmploymentType,
+		Status:         data.Status,
+		ManagerID:      data.ManagerID,
+		Attributes:     data.Attributes,
+	})
+	if !ok {
+		return
+	}
+
+	db.Where("id = ?", employee.ID).Preload("Employee_Departments").Find(&employee)
+	c.JSON(http.StatusOK, newEmployeeResponse(employee))
+}
+
+/* :id 사원의 보낸 항목만 수정하고 수정된 사원을 돌려줌. 실패하면 에러 응답 후 false */
+func updateEmployee(c *gin.Context, data eUpdateData) (Employee, bool) {
+	var employee Employee
+	if apiErr := data.validate(); apiErr != nil {
+		AbortWithError(c, apiErr)
+		return employee, false
+	}
+
+	db.Where("id = ?", c.Param("id")).Find(&employee)
+	if employee.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
+		return employee, false
+	}
+
+	updates := data.updates()
+	if len(updates) == 0 {
+		AbortWithError(c, NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed, "Nothing to update"))
+		return employee, false
+	}
+
+	var after Employee
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if data.ManagerID != nil && *data.ManagerID != 0 {
+			if apiErr := validateManager(tx, employee.ID, *data.ManagerID); apiErr != nil {
+				return apiErr
+			}
+		}
+		err := tx.Model(&Employee{}).Where("id = ?", employee.ID).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		err = recordEmployeeHistory(tx, HistoryUpdated, employee.ID)
+		if err != nil {
+			return err
+		}
+
+		tx.Where("id = ?", employee.ID).Find(&after)
+		return recordAudit(c, tx, AuditUpdate, EntityEmployee, employee.ID, employee, after)
+	})
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		AbortWithError(c, apiErr)
+		return employee, false
+	} else if err != nil {
+		AbortWithInternalError(c, err, "Update error")
+		return employee, false
+	}
+	return after, true
+}
+
+/* email, phone은 빈 문자열로 지울 수 있으므로 binding 대신 직접 검증 */
+func (data *eUpdateData) validate() *ApiError {
+	v, _ := binding.Validator.Engine().(*validator.Validate)
+	if data.Email != nil && *data.Email != "" && v.Var(*data.Email, "email") != nil {
+		return fieldError("email", "email", "email should be an email")
+	}
+	if data.Phone != nil && *data.Phone != "" && !phonePattern.MatchString(*data.Phone) {
+		return fieldError("phone", "phone", "phone is invalid")
+	}
+	return nil
+}
+
+/* 보낸 항목만 column 이름으로 모음 */
+func (data *eUpdateData) updates() map[string]interface{} {
+	updates := map[string]interface{}{}
+	if data.EName != nil {
+		updates["employee_name"] = *data.EName
+	}
+	if data.Email != nil {
+		updates["email"] = *data.Email
+	}
+	if data.Phone != nil {
+		updates["phone"] = *data.Phone
+	}
+	if data.JobTitle != nil {
+		updates["job_title"] = *data.JobTitle
+	}
+	if data.EmploymentType != nil {
+		updates["employment_type"] = *data.EmploymentType
+	}
+	if data.Status != nil {
+		updates["status"] = *data.Status
+	}
+	if data.ManagerID != nil {
+		if *data.ManagerID == 0 {
+			updates["manager_id"] = nil
+		} else {
+			updates["manager_id"] = *data.ManagerID
+		}
+	}
+	if data.Attributes != nil {
+		updates["attributes"] = *data.Attributes
+	}
+	return updates
+}
+
 /* 기존의 Emplpyee 삭제(D) */
 func DeleteEmployee(c *gin.Context) {
 	eName := c.Param("name")
 
 	var employees []Employee
-	db.Where("Employee_Name = ?", employees).Find(&employees)
 	db.Where("Employee_Name = ?", eName).Find(&employees)
 	if len(employees) > 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"employee info": employees,
-			"msg":           "There're employees with same name",
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeAmbiguousEmployee, "There're employees with same name").WithDetails(gin.H{
+			"employee info": newEmployeeResponses(employees),
 			"can use":       "/api/employee/id/:id",
-		})
-		c.Abort()
+		}))
 		return
 	} else if len(employees) == 0 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "No such employee",
-		})
-		c.Abort()
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
 		return
 	}
 
-	db.Model(&employees[0]).Association("Employee_Departments").Clear()
-	db.Delete(&employees[0])
+	err := deleteEmployee(c, employees[0])
+	if err != nil {
+		AbortWithInternalError(c, err, "Delete error")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"msg": "Delete Complete",
 	})
@@ -179,43 +690,117 @@ func DeleteEmployeById(c *gin.Context) {
 	// Find employee
 	db.Where("id=?", employee_id).Find(&employee)
 	if employee.ID == 0 {
-		log.Println("Employee id incorrect")
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": "error deleting employee",
-		})
-		c.Abort()
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
 		return
 	}
 
-	db.Model(&employee).Association("Employee_Departments").Clear()
-	db.Delete(&employee)
+	err := deleteEmployee(c, employee)
+	if err != nil {
+		AbortWithInternalError(c, err, "Delete error")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"msg": "Delete Complete",
 	})
 }
 
+/* 사원 삭제(soft delete). 소속은 그대로 두고 부하는 삭제된 사원의 상사에게 */
+func deleteEmployee(c *gin.Context, employee Employee) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := recordEmployeeHistory(tx, HistoryDeleted, employee.ID)
+		if err != nil {
+			return err
+		}
+		err = recordAudit(c, tx, AuditDelete, EntityEmployee, employee.ID, employee, nil)
+		if err != nil {
+			return err
+		}
+		err = tx.Delete(&employee).Error
+		if err != nil {
+			return err
+		}
+		return reparentReports(tx, employee)
+	})
+}
+
+/* 삭제된 사원 되돌리기. 소속은 삭제 전 그대로이고 다른 상사에게 옮겨진 부하는 그대로 둠 */
+func RestoreEmployee(c *gin.Context) {
+	var employee Employee
+	db.Unscoped().Where("id = ?", c.Param("id")).Find(&employee)
+	if employee.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
+		return
+	} else if !employee.DeletedAt.Valid {
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeConflict, "Employee is not deleted"))
+		return
+	}
+
+	restored := employee
+	restored.DeletedAt = gorm.DeletedAt{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&Employee{}).Where("id = ?", employee.ID).Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+		err = recordEmployeeHistory(tx, HistoryRestored, employee.ID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(c, tx, AuditRestore, EntityEmployee, employee.ID, employee, restored)
+	})
+	if err != nil {
+		AbortWithInternalError(c, err, "Restore error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":      "Employee Restore Complete",
+		"employee": newEmployeeResponse(restored),
+	})
+}
+
 /* n일 이내 입사한 사원 조회_Paging 추가 */
 func SearchEmployeeByDay(c *gin.Context) {
-	n := c.Param("days")
+	n, err := strconv.Atoi(c.Param("days"))
+	if err != nil {
+		AbortWithError(c, InvalidParameter("days", "days should be a number"))
+		return
+	}
+
 	var employees []Employee
-	limit, page, sort := Paging(c)
-	offset := (page - 1) * limit
+	pagination, pagingErr := Paging(c, &Employee{}, employeeSortFields...)
+	if pagingErr != nil {
+		AbortWithError(c, pagingErr)
+		return
+	}
+	query, apiErr := includeDeleted(c, db.Model(&Employee{}))
+	if apiErr == nil {
+		query, apiErr = applyFilter(c, query, employeeFilterFields(nil))
+	}
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	options, apiErr := parseReadOptions(c, &EmployeeResponse{}, employeeIncludes, "departments")
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
 
-	//result := db.Where("TO_DAYS(SYSDATE()) - TO_DAYS(created_at) <= ?", n).Find(&employees)
-	result := db.Limit(limit).Offset(offset).Order(sort).Where(
-		"TO_DAYS(SYSDATE()) - TO_DAYS(entry_time) <= ?", n).Preload("Employee_Departments").Find(&employees)
-	if result.Error != nil {
-		log.Println(result.Error)
+	// DB 종류에 상관없이 동작하도록 기준 날짜(n일 전 0시)를 계산해서 비교
+	now := time.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -n)
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": result.Error.Error(),
-		})
-		c.Abort()
+	//result := db.Where("TO_DAYS(SYSDATE()) - TO_DAYS(created_at) <= ?", n).Find(&employees)
+	response, err := pagination.Find(c, query.Where(
+		"entry_time >= ?", since), &employees, options.Preloads...)
+	if err != nil {
+		AbortWithInternalError(c, err, "Database error")
 		return
 	}
+	response.Data = newEmployeeResponses(employees)
 
-	c.JSON(http.StatusOK, employees)
+	options.JSON(c, http.StatusOK, response)
 }
 
 /* 해당 이름의 모든 사원 조회 */
@@ -224,18 +809,23 @@ func SearchEmployeeByName(c *gin.Context) {
 
 	var employees []Employee
 
-	result := db.Where("Employee_Name = ?", name).Preload("Employee_Departments").Find(&employees)
+	query, apiErr := includeDeleted(c, db)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	options, apiErr := parseReadOptions(c, &EmployeeResponse{}, employeeIncludes, "departments")
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	result := options.Preload(query.Where("Employee_Name = ?", name)).Find(&employees)
 	if result.Error != nil {
-		log.Println(result.Error)
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"msg": result.Error.Error(),
-		})
-		c.Abort()
+		AbortWithInternalError(c, result.Error, "Database error")
 		return
 	}
 
-	c.JSON(http.StatusOK, employees)
+	options.JSON(c, http.StatusOK, newEmployeeResponses(employees))
 }
 
 /* Random String으로 이름 이니셜 생성 */
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +14,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 /* Employee Table CRUD Test */
@@ -19,15 +24,18 @@ func TestAddEmployee(t *testing.T) {
 	assert.NoError(t, err)
 
 	var result map[string]interface{}
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.POST("/api/employee/:name/:department", AddEmployee)
+	router.POST("/api/employee/", AddEmployee)
 
+	db.Create(&Department{Department_Name: "Test Department"})
+
+	payload := []byte(`[{"ename": "Test Employee", "dname": "Test Department"}]`)
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("POST", "/api/employee/Test Employee/Test Department", nil)
+	request, _ := http.NewRequest("POST", "/api/employee/", bytes.NewBuffer(payload))
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
@@ -37,17 +45,217 @@ func TestAddEmployee(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	db.Where("Employee_Name = ?", "Test Employee").Association("Employee_Departments").Clear()
+	var employee Employee
+	db.Where("Employee_Name = ?", "Test Employee").Find(&employee)
+	db.Model(&employee).Association("Employee_Departments").Clear()
 	db.Unscoped().Where("Employee_Name=?", "Test Employee").Delete(&Employee{})
 	db.Unscoped().Where("Department_Name=?", "Test Department").Delete(&Department{})
 }
 
+func TestAddEmployeeAtomic(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/employee/", AddEmployee)
+
+	department1 := Department{Department_Name: "Bulk Department 1"}
+	department2 := Department{Department_Name: "Bulk Department 2"}
+	db.Create(&department1)
+	db.Create(&department2)
+
+	// 두 번째 항목의 부서가 없으므로 전부 취소
+	payload := []byte(`[{"ename": "Bulk Atomic"}, {"ename": "Bulk Atomic", "dname": "No Department"}]`)
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/api/employee/", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	var apiErr ApiError
+	err = json.Unmarshal(w.Body.Bytes(), &apiErr)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, CodeDepartmentNotFound, apiErr.Code)
+
+	var count int64
+	db.Model(&Employee{}).Where("Employee_Name = ?", "Bulk Atomic").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 여러 부서에 동시에 소속
+	payload = []byte(`[{"ename": "Bulk Atomic", "dnames": ["Bulk Department 1", "Bulk Department 2"]}]`)
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/api/employee/", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var employee Employee
+	db.Where("Employee_Name = ?", "Bulk Atomic").Preload("Employee_Departments").Find(&employee)
+	assert.Equal(t, 2, len(employee.Employee_Departments))
+
+	db.Model(&employee).Association("Employee_Departments").Clear()
+	db.Unscoped().Delete(&employee)
+	db.Unscoped().Delete(&department1)
+	db.Unscoped().Delete(&department2)
+}
+
+func TestAddEmployeeNotAtomic(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/employee/", AddEmployee)
+
+	payload := []byte(`[{"ename": "Bulk Partial"}, {"ename": "Bulk Partial", "dname": "No Department"}, {"dname": "No Name"}]`)
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/api/employee/?atomic=false", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	var result struct {
+		Results []bulkResult `json:"results"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	if assert.Equal(t, 3, len(result.Results)) {
+		assert.Equal(t, http.StatusCreated, result.Results[0].Status)
+		assert.NotZero(t, result.Results[0].ID)
+		assert.Equal(t, http.StatusUnprocessableEntity, result.Results[1].Status)
+		assert.Equal(t, CodeDepartmentNotFound, result.Results[1].Error.Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, result.Results[2].Status)
+		assert.Equal(t, CodeValidationFailed, result.Results[2].Error.Code)
+	}
+
+	var count int64
+	db.Model(&Employee{}).Where("Employee_Name = ?", "Bulk Partial").Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	db.Unscoped().Where("Employee_Name = ?", "Bulk Partial").Delete(&Employee{})
+}
+
+// commit이 항상 실패하는 DB 연결
+type failCommitPool struct{ *sql.DB }
+
+type failCommitTx struct{ *sql.Tx }
+
+func (pool failCommitPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := pool.DB.BeginTx(ctx, opts)
+	return &failCommitTx{tx}, err
+}
+
+func (tx *failCommitTx) Commit() error {
+	tx.Tx.Rollback()
+	return errors.New("commit failed")
+}
+
+func TestAddEmployeeNotAtomicCommitError(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/employee/", AddEmployee)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	original := db
+	db = original.Session(&gorm.Session{Context: context.Background()}) // Statement를 복사해서 원래 db는 그대로
+	db.Statement.ConnPool = failCommitPool{sqlDB}
+	defer func() { db = original }()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/api/employee/?atomic=false", bytes.NewBufferString(`[{"ename": "Commit Fail"}]`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	var result struct {
+		Results []bulkResult `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	// commit에 실패한 항목은 성공으로 응답하지 않음
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	if assert.Equal(t, 1, len(result.Results)) {
+		assert.Equal(t, http.StatusInternalServerError, result.Results[0].Status)
+		assert.Zero(t, result.Results[0].ID)
+		assert.Equal(t, CodeInternalError, result.Results[0].Error.Code)
+	}
+
+	var count int64
+	original.Model(&Employee{}).Where("Employee_Name = ?", "Commit Fail").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestAddEmployeeProfile(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/employee/", AddEmployee)
+
+	manager := Employee{Employee_Name: "Profile Manager"}
+	db.Create(&manager)
+
+	payload := []byte(fmt.Sprintf(`[{"ename": "Profile Employee", "email": "profile@test.com", "phone": "+82 10-1234-5678",
+		"job_title": "Engineer", "employment_type": "contract", "status": "on-leave", "manager_id": %d,
+		"attributes": {"badge": "A-17", "remote": true}}]`, manager.ID))
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/api/employee/", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var employee Employee
+	db.Where("Employee_Name = ?", "Profile Employee").Find(&employee)
+	assert.Equal(t, "profile@test.com", employee.Email)
+	assert.Equal(t, "Engineer", employee.JobTitle)
+	assert.Equal(t, EmploymentContract, employee.EmploymentType)
+	assert.Equal(t, StatusOnLeave, employee.Status)
+	if assert.NotNil(t, employee.ManagerID) {
+		assert.Equal(t, manager.ID, *employee.ManagerID)
+	}
+	assert.Equal(t, JSONMap{"badge": "A-17", "remote": true}, employee.Attributes)
+
+	// 기본값
+	payload = []byte(`[{"ename": "Profile Default"}]`)
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/api/employee/", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var defaults Employee
+	db.Where("Employee_Name = ?", "Profile Default").Find(&defaults)
+	assert.Equal(t, StatusActive, defaults.Status)
+	assert.Equal(t, EmploymentFullTime, defaults.EmploymentType)
+
+	for _, invalid := range []string{
+		`[{"ename": "Profile Invalid", "status": "retired"}]`,
+		`[{"ename": "Profile Invalid", "email": "not-an-email"}]`,
+		`[{"ename": "Profile Invalid", "phone": "call me"}]`,
+		`[{"ename": "Profile Invalid", "manager_id": 999999}]`,
+	} {
+		w = httptest.NewRecorder()
+		request, _ = http.NewRequest("POST", "/api/employee/", bytes.NewBufferString(invalid))
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		router.ServeHTTP(w, request)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, invalid)
+	}
+
+	db.Unscoped().Where("Employee_Name IN ?", []string{"Profile Employee", "Profile Default", "Profile Manager"}).Delete(&Employee{})
+}
+
 func TestReadEmployee(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	var results []Employee
-	token, err := GenerateToken("gotest", "myCA")
+	var employees []EmployeeResponse
+	results := PageResponse{Data: &employees}
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -71,8 +279,9 @@ func TestReadEmployeePaging(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	var results []Employee
-	token, err := GenerateToken("gotest", "myCA")
+	var employees []EmployeeResponse
+	results := PageResponse{Data: &employees}
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -95,7 +304,7 @@ func TestReadEmployeeInvalidPaging(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -108,7 +317,7 @@ func TestReadEmployeeInvalidPaging(t *testing.T) {
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestUpdateEmployee(t *testing.T) {
@@ -116,12 +325,12 @@ func TestUpdateEmployee(t *testing.T) {
 	assert.NoError(t, err)
 	var result Employee
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.PUT("/api/employee/:id/:new", UpdateEmployee)
+	router.PUT("/api/employee/:id", UpdateEmployee)
 
 	// Create Data for Test
 	newName := "New Name"
@@ -131,8 +340,9 @@ func TestUpdateEmployee(t *testing.T) {
 	db.Create(&newEmployee)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/employee/" + strconv.FormatUint(uint64(newEmployee.ID), 10) + "/" + newName
-	request, _ := http.NewRequest("PUT", requrl, nil) // id=1인 employee의 name을 UJS로 UPDATE
+	payload, _ := json.Marshal(eData{EName: newName})
+	requrl := "/api/employee/" + strconv.FormatUint(uint64(newEmployee.ID), 10)
+	request, _ := http.NewRequest("PUT", requrl, bytes.NewBuffer(payload))
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
@@ -145,17 +355,62 @@ func TestUpdateEmployee(t *testing.T) {
 	db.Unscoped().Where("id = ?", newEmployee.ID).Delete(&Employee{})
 }
 
+func TestUpdateEmployeeProfile(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.PUT("/api/employee/:id", UpdateEmployee)
+
+	manager := Employee{Employee_Name: "Update Manager"}
+	db.Create(&manager)
+	employee := Employee{Employee_Name: "Update Profile", JobTitle: "Engineer", ManagerID: &manager.ID}
+	db.Create(&employee)
+	requrl := "/api/employee/" + strconv.FormatUint(uint64(employee.ID), 10)
+
+	// 보낸 항목만 수정
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("PUT", requrl, bytes.NewBufferString(`{"status": "terminated", "manager_id": 0, "email": ""}`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result Employee
+	db.Where("id = ?", employee.ID).Find(&result)
+	assert.Equal(t, "Update Profile", result.Employee_Name)
+	assert.Equal(t, "Engineer", result.JobTitle)
+	assert.Equal(t, StatusTerminated, result.Status)
+	assert.Nil(t, result.ManagerID)
+
+	// 자기 자신은 상사가 될 수 없음
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("PUT", requrl, bytes.NewBufferString(fmt.Sprintf(`{"manager_id": %d}`, employee.ID)))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("PUT", requrl, bytes.NewBufferString(`{"employment_type": "volunteer"}`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	db.Unscoped().Delete(&employee)
+	db.Unscoped().Delete(&manager)
+}
+
 func TestDeleteEmployee(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 	var result Employee
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.DELETE("/api/employee/:id", DeleteEmployee)
+	router.DELETE("/api/employee/id/:id", DeleteEmployeById)
 
 	test := Employee{ // 지울 data 정보
 		Employee_Name: "deleteTest",
@@ -164,7 +419,7 @@ func TestDeleteEmployee(t *testing.T) {
 	assert.NoError(t, createResult.Error)
 
 	w := httptest.NewRecorder()
-	requrl := "/api/employee/" + strconv.FormatUint(uint64(test.ID), 10)
+	requrl := "/api/employee/id/" + strconv.FormatUint(uint64(test.ID), 10)
 	request, _ := http.NewRequest("DELETE", requrl, nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -179,28 +434,28 @@ func TestDeleteEmployeeInvalidEid(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
-	router.DELETE("/api/employee/:id", DeleteEmployee)
+	router.DELETE("/api/employee/id/:id", DeleteEmployeById)
 
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("DELETE", "/api/employee/-1", nil) // Use invalid department id
+	request, _ := http.NewRequest("DELETE", "/api/employee/id/-1", nil) // Use invalid department id
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestSearchEmployeeByName(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
-	var results []Employee
+	var results []EmployeeResponse
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -223,9 +478,10 @@ func TestSearchEmployeeByName(t *testing.T) {
 func TestSearchEmployeeByDay(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
-	var results []Employee
+	var employees []EmployeeResponse
+	results := PageResponse{Data: &employees}
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -251,7 +507,7 @@ func TestSearchEmployeeByDayInvalidPaging(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
@@ -266,24 +522,30 @@ func TestSearchEmployeeByDayInvalidPaging(t *testing.T) {
 
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestSearchEmployeeByDayPaging(t *testing.T) {
 	err = InitDB()
 	assert.NoError(t, err)
-	var results []Employee
+	var employees []EmployeeResponse
+	results := PageResponse{Data: &employees}
 
-	token, err := GenerateToken("gotest", "myCA")
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
 	assert.NoError(t, err)
 
 	router := gin.Default()
 	router.Use(AuthorizeAccount())
 	router.GET("/api/employee/day/:days", SearchEmployeeByDay)
 
+	newEmployee := Employee{ // 오늘 입사한 사원
+		Employee_Name: "Day Test",
+	}
+	db.Create(&newEmployee)
+
 	days := 4
 	w := httptest.NewRecorder()
-	requrl := "/api/employee/day/" + strconv.Itoa(days) + "?page=1&limit=1" //하나는 data가 있을 것이라고 가정
+	requrl := "/api/employee/day/" + strconv.Itoa(days) + "?page=1&limit=1"
 	request, _ := http.NewRequest("GET", requrl, nil)
 	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 
@@ -293,5 +555,127 @@ func TestSearchEmployeeByDayPaging(t *testing.T) {
 	assert.NoError(t, err)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Equal(t, 1, len(results))
+	assert.Equal(t, 1, len(employees))
+
+	db.Unscoped().Where("id = ?", newEmployee.ID).Delete(&Employee{})
+}
+
+func TestSoftDeleteAndRestoreEmployee(t *testing.T) {
+	router, token, id := historyTestData(t)
+	router.POST("/api/employee/:id/restore", RestoreEmployee)
+	router.GET("/api/employee/name/:name", SearchEmployeeByName)
+
+	// 새 소속을 하나 추가. 삭제 후에도 유지되어야 함
+	var department Department
+	db.Where("Department_Name = ?", "HistorySales").Find(&department)
+	db.Create(&Assignment{EmployeeID: id, DepartmentID: department.ID})
+
+	w := orgRequest(router, token, "DELETE", fmt.Sprintf("/api/employee/id/%d", id), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var deleted Employee
+	db.Unscoped().Where("id = ?", id).Find(&deleted)
+	assert.True(t, deleted.DeletedAt.Valid)
+	var active int64
+	db.Model(&Assignment{}).Where("employee_id = ?", id).Count(&active)
+	assert.Equal(t, int64(1), active)
+
+	var employees []EmployeeResponse
+	w = orgRequest(router, token, "GET", "/api/employee/name/History Employee", "")
+	json.Unmarshal(w.Body.Bytes(), &employees)
+	assert.Empty(t, employees)
+	w = orgRequest(router, token, "GET", "/api/employee/name/History Employee?include_deleted=true", "")
+	json.Unmarshal(w.Body.Bytes(), &employees)
+	assert.Equal(t, 1, len(employees))
+	w = orgRequest(router, token, "GET", "/api/employee/?include_deleted=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = orgRequest(router, token, "POST", fmt.Sprintf("/api/employee/%d/restore", id), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	employees = nil
+	w = orgRequest(router, token, "GET", "/api/employee/name/History Employee", "")
+	json.Unmarshal(w.Body.Bytes(), &employees)
+	if assert.Equal(t, 1, len(employees)) {
+		assert.Equal(t, "HistorySales", employees[0].Departments[0].Name)
+	}
+
+	w = orgRequest(router, token, "POST", fmt.Sprintf("/api/employee/%d/restore", id), "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = orgRequest(router, token, "POST", "/api/employee/-1/restore", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var histories []EmployeeHistory
+	db.Where("employee_id = ?", id).Order("id desc").Limit(1).Find(&histories)
+	assert.Equal(t, HistoryRestored, histories[0].Action)
+}
+
+func TestAddAndUpdateEmployeeV2(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/v2/employee/", AddEmployeeV2)
+	router.PUT("/api/v2/employee/:id", UpdateEmployeeV2)
+
+	department := Department{Department_Name: "V2 Department"}
+	db.Create(&department)
+	t.Cleanup(func() {
+		var employees []Employee
+		db.Unscoped().Where("Employee_Name LIKE ?", "V2 %").Find(&employees)
+		for _, employee := range employees {
+			db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+			db.Unscoped().Delete(&employee)
+		}
+		db.Unscoped().Delete(&department)
+	})
+
+	// camelCase body. 만들어진 사원을 소속 부서와 함께 응답
+	w := orgRequest(router, token, "POST", "/api/v2/employee/?atomic=false",
+		`[{"name": "V2 Kim", "jobTitle": "Engineer", "departments": ["V2 Department"]}, {"name": "V2 Lee", "departments": ["V2 Missing"]}]`)
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	var result struct {
+		Results []employeeResult `json:"results"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	if !assert.Equal(t, 2, len(result.Results)) || !assert.NotNil(t, result.Results[0].Employee) {
+		return
+	}
+	created := result.Results[0].Employee
+	assert.Equal(t, http.StatusCreated, result.Results[0].Status)
+	assert.Equal(t, "V2 Kim", created.Name)
+	assert.Equal(t, "Engineer", created.JobTitle)
+	if assert.Equal(t, 1, len(created.Departments)) {
+		assert.Equal(t, "V2 Department", created.Departments[0].Name)
+	}
+	assert.Nil(t, result.Results[1].Employee)
+	assert.Equal(t, CodeDepartmentNotFound, result.Results[1].Error.Code)
+
+	// 검증 에러의 항목 이름도 camelCase
+	w = orgRequest(router, token, "POST", "/api/v2/employee/", `[{"jobTitle": "Engineer"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var apiErr ApiError
+	err = json.Unmarshal(w.Body.Bytes(), &apiErr)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(apiErr.Errors)) {
+		assert.Equal(t, "[0].name", apiErr.Errors[0].Field)
+	}
+
+	// v1 body는 v2에서 사용하지 않음
+	w = orgRequest(router, token, "POST", "/api/v2/employee/", `[{"ename": "V2 Old"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/v2/employee/%d", created.ID), `{"jobTitle": "Lead", "employmentType": "contract"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated EmployeeResponse
+	err = json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lead", updated.JobTitle)
+	assert.Equal(t, EmploymentContract, updated.EmploymentType)
+	assert.Equal(t, "V2 Kim", updated.Name)
+	assert.Equal(t, 1, len(updated.Departments))
+
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/v2/employee/%d", created.ID), `{"job_title": "Lead"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 }
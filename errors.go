@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// 클라이언트가 분기할 때 사용하는 에러 코드
+const (
+	CodeInvalidJSON           = "invalid_json"
+	CodeValidationFailed      = "validation_failed"
+	CodeInvalidParameter      = "invalid_parameter"
+	CodeUnauthorized          = "unauthorized"
+	CodeForbidden             = "forbidden"
+	CodeNotFound              = "not_found"
+	CodeEmployeeNotFound      = "employee_not_found"
+	CodeDepartmentNotFound    = "department_not_found"
+	CodeAccountNotFound       = "account_not_found"
+	CodeApiKeyNotFound        = "api_key_not_found"
+	CodeAssignmentNotFound    = "assignment_not_found"
+	CodeAssignmentExists      = "assignment_exists"
+	CodeAmbiguousEmployee     = "ambiguous_employee_name"
+	CodeDepartmentExists      = "department_exists"
+	CodeAccountExists         = "account_exists"
+	CodeConflict              = "conflict"
+	CodeManagerCycle          = "manager_cycle"
+	CodeDepartmentCycle       = "department_cycle"
+	CodeDepartmentHasChildren = "department_has_children"
+	CodeInvalidOAuthState     = "invalid_oauth_state"
+	CodeEmailNotVerified      = "email_not_verified"
+	CodeInvalidRefreshToken   = "invalid_refresh_token"
+	CodeUpstreamError         = "upstream_error"
+	CodeInternalError         = "internal_error"
+)
+
+const problemContentType = "application/problem+json"
+
+// RFC 7807 (application/problem+json) 형식의 에러 응답
+type ApiError struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`  // 검증에 실패한 필드 목록
+	Details   interface{}  `json:"details,omitempty"` // 코드별 추가 정보
+}
+
+// 검증에 실패한 필드 하나
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Detail)
+}
+
+func NewApiError(status int, code string, detail string) *ApiError {
+	return &ApiError{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+/* 에러 응답에 추가 정보를 붙임 (중복된 사원 목록 등) */
+func (e *ApiError) WithDetails(details interface{}) *ApiError {
+	e.Details = details
+	return e
+}
+
+/* 에러를 problem+json으로 응답하고 다음 Handler 실행을 중단 */
+func AbortWithError(c *gin.Context, apiErr *ApiError) {
+	apiErr.Instance = c.Request.URL.Path
+	apiErr.RequestID = c.GetString("request_id")
+
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(apiErr.Status, apiErr)
+}
+
+/* DB 오류 등 내부 에러. 원인은 log에만 남김 */
+func AbortWithInternalError(c *gin.Context, err error, detail string) {
+	AbortWithError(c, internalError(c, err, detail))
+}
+
+/* 응답하지 않고 내부 에러만 만들 때 (일괄 처리의 항목별 결과 등) */
+func internalError(c *gin.Context, err error, detail string) *ApiError {
+	log.Println(c.GetString("request_id"), err)
+	return NewApiError(http.StatusInternalServerError, CodeInternalError, detail)
+}
+
+/* ShouldBindJSON 실패 처리. 형식 오류는 400, 값 검증 실패는 422 */
+func AbortWithBindError(c *gin.Context, err error) {
+	AbortWithError(c, BindError(err, ""))
+}
+
+/* binding 에러를 ApiError로 변환. prefix는 배열 요청에서 "[0]." 처럼 위치 표시 */
+func BindError(err error, prefix string) *ApiError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		apiErr := NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed, "Request has invalid fields")
+		for _, fe := range validationErrors {
+			apiErr.Errors = append(apiErr.Errors, newFieldError(prefix, fe))
+		}
+		return apiErr
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" { // 최상위 타입이 다르면 형식 오류
+		apiErr := NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed, "Request has invalid fields")
+		apiErr.Errors = []FieldError{{
+			Field:   prefix + typeError.Field,
+			Rule:    "type",
+			Message: typeError.Field + " should be " + typeError.Type.String(),
+		}}
+		return apiErr
+	}
+
+	return NewApiError(http.StatusBadRequest, CodeInvalidJSON, "invalid json")
+}
+
+func newFieldError(prefix string, fe validator.FieldError) FieldError {
+	field := prefix + fieldPath(fe)
+	message := field + " is invalid"
+	switch fe.Tag() {
+	case "required":
+		message = field + " is required"
+	case "min":
+		message = field + " should be at least " + fe.Param()
+	case "max":
+		message = field + " should be at most " + fe.Param()
+	case "email":
+		message = field + " should be an email"
+	case "oneof":
+		message = field + " should be one of " + fe.Param()
+	}
+	return FieldError{Field: field, Rule: fe.Tag(), Message: message}
+}
+
+/* "eData.ename" -> "ename", "dData.dname[0]" -> "dname[0]" */
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+/* 필드 하나의 값 검증 실패 (binding 밖에서 검증한 경우) */
+func fieldError(field string, rule string, message string) *ApiError {
+	apiErr := NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed, "Request has invalid fields")
+	apiErr.Errors = []FieldError{{Field: field, Rule: rule, Message: message}}
+	return apiErr
+}
+
+/* Paging 등 query, path 값이 잘못된 경우 */
+func InvalidParameter(name string, detail string) *ApiError {
+	apiErr := NewApiError(http.StatusBadRequest, CodeInvalidParameter, detail)
+	apiErr.Errors = []FieldError{{Field: name, Rule: "invalid", Message: detail}}
+	return apiErr
+}
+
+/* 인증 실패. 클라이언트가 다시 인증하도록 WWW-Authenticate를 함께 보냄 */
+func abortUnauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="myapi"`)
+	AbortWithError(c, NewApiError(http.StatusUnauthorized, CodeUnauthorized, detail))
+}
+
+/* 존재하지 않거나 허용되지 않는 role */
+func invalidRole(detail string) *ApiError {
+	apiErr := NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed, detail)
+	apiErr.Errors = []FieldError{{Field: "role", Rule: "oneof", Message: detail}}
+	return apiErr
+}
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+/* 요청마다 X-Request-ID를 부여. 클라이언트가 보낸 값이 있으면 그대로 사용 */
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.Request.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID, _ = newRandomToken(12)
+		}
+
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
+
+/* 등록되지 않은 경로 */
+func NoRoute(c *gin.Context) {
+	AbortWithError(c, NewApiError(http.StatusNotFound, CodeNotFound, "No such route"))
+}
+
+func init() {
+	// 검증 에러의 필드 이름을 json tag 이름으로 표시
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorProblemJSON(t *testing.T) {
+	router := SetupRouter()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/api/employee/", nil)
+	request.Header.Add("X-Request-ID", "test-request-1")
+	router.ServeHTTP(w, request)
+
+	var result ApiError
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, "test-request-1", w.Header().Get("X-Request-ID"))
+	assert.Equal(t, CodeUnauthorized, result.Code)
+	assert.Equal(t, http.StatusUnauthorized, result.Status)
+	assert.Equal(t, "/api/employee/", result.Instance)
+	assert.Equal(t, "test-request-1", result.RequestID)
+}
+
+func TestErrorNoRoute(t *testing.T) {
+	router := SetupRouter()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/api/nothing", nil)
+	router.ServeHTTP(w, request)
+
+	var result ApiError
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, CodeNotFound, result.Code)
+	assert.NotEmpty(t, w.Header().Get("X-Request-ID"))
+}
+
+func TestErrorValidationFields(t *testing.T) {
+	router := SetupRouter()
+
+	// 형식이 잘못된 JSON은 400
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/register", bytes.NewBufferString(`{"email": `))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 값 검증 실패는 422와 실패한 필드 목록
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/auth/register", bytes.NewBufferString(`{"email": "not-an-email", "password": "short"}`))
+	router.ServeHTTP(w, request)
+
+	var result ApiError
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, CodeValidationFailed, result.Code)
+	fields := map[string]string{}
+	for _, fe := range result.Errors {
+		fields[fe.Field] = fe.Rule
+	}
+	assert.Equal(t, "email", fields["email"])
+	assert.Equal(t, "min", fields["password"])
+}
+
+func TestErrorValidationArrayFields(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+	router := SetupRouter()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/api/employee/", bytes.NewBufferString(`[{"ename": "ok", "dname": "x"}, {"dname": "x"}]`))
+	request.Header.Add("Authorization", "Bearer "+token)
+	router.ServeHTTP(w, request)
+
+	var result ApiError
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, CodeValidationFailed, result.Code)
+	if assert.Len(t, result.Errors, 1) {
+		assert.Equal(t, "[1].ename", result.Errors[0].Field)
+		assert.Equal(t, "required", result.Errors[0].Rule)
+	}
+}
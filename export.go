@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// export에서 한 번에 조회하는 row 수
+const exportBatchSize = 500
+
+// export 파일의 column
+var (
+	employeeExportColumns   = []string{"id", "employee_name", "email", "phone", "job_title", "employment_type", "status", "manager_id", "entry_time", "deleted_at", "departments", "primary_department", "attributes"}
+	departmentExportColumns = []string{"id", "department_name", "parent_id", "parent_name", "deleted_at"}
+	assignmentExportColumns = []string{"id", "employee_id", "employee_name", "department_id", "department_name", "role", "allocation", "is_primary", "start_date", "end_date"}
+)
+
+// csv 또는 jsonl로 row를 바로 응답에 씀
+type exportWriter struct {
+	c       *gin.Context
+	format  string
+	columns []string
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+/* ?format=csv(기본)|jsonl 확인 후 다운로드 header를 보냄. 이후에는 status를 바꿀 수 없음 */
+func newExportWriter(c *gin.Context, name string, columns []string) (*exportWriter, *ApiError) {
+	w := &exportWriter{c: c, format: c.DefaultQuery("format", "csv"), columns: columns}
+	switch w.format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w.csv = csv.NewWriter(c.Writer)
+	case "jsonl":
+		c.Header("Content-Type", "application/x-ndjson")
+		w.json = json.NewEncoder(c.Writer)
+	default:
+		return nil, InvalidParameter("format", "format should be csv or jsonl")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, w.format))
+	c.Status(http.StatusOK)
+
+	if w.csv != nil {
+		w.csv.Write(columns)
+	}
+	return w, nil
+}
+
+/* columns 순서의 값 한 줄. csv는 문자열로, jsonl은 column 이름을 key로 씀 */
+func (w *exportWriter) write(values ...interface{}) error {
+	if w.json != nil {
+		row := make(map[string]interface{}, len(values))
+		for i, value := range values {
+			row[w.columns[i]] = value
+		}
+		return w.json.Encode(row)
+	}
+
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = exportString(value)
+	}
+	return w.csv.Write(record)
+}
+
+/* batch마다 보내서 client가 바로 받을 수 있도록 */
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+/* 응답을 보내기 시작한 뒤의 에러는 status로 알릴 수 없으므로 기록만 하고 중단 */
+func (w *exportWriter) fail(err error) {
+	log.Println(w.c.GetString("request_id"), "export stopped:", err)
+	w.flush()
+}
+
+func exportString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case *uint:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*v), 10)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case JSONMap:
+		if v == nil {
+			return ""
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func deletedTime(deletedAt gorm.DeletedAt) *time.Time {
+	if !deletedAt.Valid {
+		return nil
+	}
+	return &deletedAt.Time
+}
+
+/* 사원 목록 export. 목록과 같은 sort, filter, include_deleted, as_of를 사용하고 소속 부서는 ; 로 이어서 씀 */
+func ExportEmployees(c *gin.Context) {
+	pagination, apiErr := Paging(c, &Employee{}, employeeSortFields...)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	asOf, apiErr := parseAsOf(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	query, apiErr := includeDeleted(c, db.Model(&Employee{}))
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	if asOf != nil {
+		query = employeesAsOf(db, *asOf)
+	}
+	query, apiErr = applyFilter(c, query, employeeFilterFields(asOf))
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	w, apiErr := newExportWriter(c, "employees", employeeExportColumns)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	var employees []Employee
+	err := pagination.Each(c, query, &employees, exportBatchSize, func() error {
+		ids := make([]uint, len(employees))
+		for i, employee := range employees {
+			ids[i] = employee.ID
+		}
+		memberships := db.Model(&Assignment{})
+		if asOf != nil {
+			memberships = assignmentsAsOf(db, *asOf)
+		}
+		var assignments []Assignment
+		err := memberships.Where("employee_id IN ?", ids).Preload("Department").Order("is_primary desc, start_date asc, id asc").Find(&assignments).Error
+		if err != nil {
+			return err
+		}
+		departments := make(map[uint][]string, len(employees))
+		primary := make(map[uint]string, len(employees))
+		for _, assignment := range assignments {
+			if assignment.Department == nil { // 삭제된 부서
+				continue
+			}
+			departments[assignment.EmployeeID] = append(departments[assignment.EmployeeID], assignment.Department.Department_Name)
+			if assignment.IsPrimary {
+				primary[assignment.EmployeeID] = assignment.Department.Department_Name
+			}
+		}
+
+		for _, e := range employees {
+			err = w.write(e.ID, e.Employee_Name, e.Email, e.Phone, e.JobTitle, e.EmploymentType, e.Status, e.ManagerID,
+				e.EntryTime, deletedTime(e.DeletedAt), strings.Join(departments[e.ID], ";"), primary[e.ID], e.Attributes)
+			if err != nil {
+				return err
+			}
+		}
+		return w.flush()
+	})
+	if err != nil {
+		w.fail(err)
+	}
+}
+
+/* 부서 목록 export. 목록과 같은 sort, filter, include_deleted를 사용 */
+func ExportDepartments(c *gin.Context) {
+	pagination, apiErr := Paging(c, &Department{}, departmentSortFields...)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	query, apiErr := includeDeleted(c, db.Model(&Department{}))
+	if apiErr == nil {
+		query, apiErr = applyFilter(c, query, departmentFilterFields)
+	}
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	w, apiErr := newExportWriter(c, "departments", departmentExportColumns)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	var departments []Department
+	err := pagination.Each(c, query, &departments, exportBatchSize, func() error {
+		var parentIDs []uint
+		for _, department := range departments {
+			if department.ParentID != nil {
+				parentIDs = append(parentIDs, *department.ParentID)
+			}
+		}
+		var parents []Department
+		if len(parentIDs) > 0 {
+			err := db.Unscoped().Where("id IN ?", parentIDs).Find(&parents).Error
+			if err != nil {
+				return err
+			}
+		}
+		names := make(map[uint]string, len(parents))
+		for _, parent := range parents {
+			names[parent.ID] = parent.Department_Name
+		}
+
+		for _, d := range departments {
+			parentName := ""
+			if d.ParentID != nil {
+				parentName = names[*d.ParentID]
+			}
+			err := w.write(d.ID, d.Department_Name, d.ParentID, parentName, deletedTime(d.DeletedAt))
+			if err != nil {
+				return err
+			}
+		}
+		return w.flush()
+	})
+	if err != nil {
+		w.fail(err)
+	}
+}
+
+// 소속 목록에서 sort에 사용할 수 있는 column
+var assignmentSortFields = []string{"id", "start_date"}
+
+/* 소속 목록 export. ?include_ended=true면 끝난 소속, ?as_of=면 그 시점의 소속 */
+func ExportAssignments(c *gin.Context) {
+	pagination, apiErr := Paging(c, &Assignment{}, assignmentSortFields...)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	asOf, apiErr := parseAsOf(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	includeEnded, err := strconv.ParseBool(c.DefaultQuery("include_ended", "false"))
+	if err != nil {
+		AbortWithError(c, InvalidParameter("include_ended", "include_ended should be true or false"))
+		return
+	}
+
+	query := db.Model(&Assignment{})
+	if asOf != nil {
+		query = assignmentsAsOf(db, *asOf)
+	} else if includeEnded {
+		query = query.Unscoped()
+	}
+	// 삭제된 사원, 부서의 소속도 이름을 보여줌
+	query = query.Preload("Department", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() })
+
+	w, apiErr := newExportWriter(c, "assignments", assignmentExportColumns)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	var assignments []Assignment
+	err = pagination.Each(c, query, &assignments, exportBatchSize, func() error {
+		ids := make([]uint, len(assignments))
+		for i, assignment := range assignments {
+			ids[i] = assignment.EmployeeID
+		}
+		var employees []Employee
+		err := db.Unscoped().Select("id", "employee_name").Where("id IN ?", ids).Find(&employees).Error
+		if err != nil {
+			return err
+		}
+		names := make(map[uint]string, len(employees))
+		for _, employee := range employees {
+			names[employee.ID] = employee.Employee_Name
+		}
+
+		for _, a := range assignments {
+			departmentName := ""
+			if a.Department != nil {
+				departmentName = a.Department.Department_Name
+			}
+			err = w.write(a.ID, a.EmployeeID, names[a.EmployeeID], a.DepartmentID, departmentName,
+				a.Role, a.Allocation, a.IsPrimary, a.StartDate, deletedTime(a.EndDate))
+			if err != nil {
+				return err
+			}
+		}
+		return w.flush()
+	})
+	if err != nil {
+		w.fail(err)
+	}
+}
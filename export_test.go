@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportTestData(t *testing.T) (*gin.Engine, string, Employee) {
+	token, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/export/employees", ExportEmployees)
+	router.GET("/api/export/departments", ExportDepartments)
+	router.GET("/api/export/assignments", ExportAssignments)
+
+	parent := Department{Department_Name: "Export Main"}
+	db.Create(&parent)
+	sub := Department{Department_Name: "Export Sub", ParentID: &parent.ID}
+	db.Create(&sub)
+	employee := Employee{Employee_Name: "Export Kim", Email: "kim@example.com", Attributes: JSONMap{"seat": "3F"}}
+	db.Create(&employee)
+	db.Create(&Assignment{EmployeeID: employee.ID, DepartmentID: sub.ID})
+	db.Create(&Assignment{EmployeeID: employee.ID, DepartmentID: parent.ID, IsPrimary: true})
+	t.Cleanup(func() {
+		db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+		db.Unscoped().Delete(&employee)
+		db.Unscoped().Delete(&sub)
+		db.Unscoped().Delete(&parent)
+	})
+
+	return router, token, employee
+}
+
+/* csv를 column 이름 -> 값 map 목록으로 */
+func readExportCSV(t *testing.T, body []byte) []map[string]string {
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	assert.NoError(t, err)
+
+	rows := make([]map[string]string, 0, len(records))
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, value := range record {
+			row[records[0][i]] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestExportEmployees(t *testing.T) {
+	router, token, employee := exportTestData(t)
+
+	w := orgRequest(router, token, "GET", "/api/export/employees", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "employees.csv")
+
+	var found map[string]string
+	for _, row := range readExportCSV(t, w.Body.Bytes()) {
+		if row["employee_name"] == employee.Employee_Name {
+			found = row
+		}
+	}
+	if assert.NotNil(t, found) {
+		assert.Equal(t, "Export Main;Export Sub", found["departments"])
+		assert.Equal(t, "Export Main", found["primary_department"])
+		assert.Equal(t, `{"seat":"3F"}`, found["attributes"])
+		assert.Equal(t, "", found["manager_id"])
+	}
+
+	w = orgRequest(router, token, "GET", "/api/export/employees?format=jsonl&sort=-id", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	scanner := bufio.NewScanner(w.Body)
+	var lines []map[string]interface{}
+	for scanner.Scan() {
+		var line map[string]interface{}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		assert.NoError(t, err)
+		lines = append(lines, line)
+	}
+	if assert.NotEmpty(t, lines) {
+		assert.Equal(t, float64(employee.ID), lines[0]["id"]) // 가장 최근 사원
+		assert.Equal(t, "3F", lines[0]["attributes"].(map[string]interface{})["seat"])
+		assert.Nil(t, lines[0]["deleted_at"])
+	}
+
+	w = orgRequest(router, token, "GET", "/api/export/employees?format=xml", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = orgRequest(router, token, "GET", "/api/export/employees?sort=email", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportDepartmentsAndAssignments(t *testing.T) {
+	router, token, employee := exportTestData(t)
+
+	w := orgRequest(router, token, "GET", "/api/export/departments", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	parents := map[string]string{}
+	for _, row := range readExportCSV(t, w.Body.Bytes()) {
+		parents[row["department_name"]] = row["parent_name"]
+	}
+	assert.Equal(t, "Export Main", parents["Export Sub"])
+	assert.Equal(t, "", parents["Export Main"])
+
+	// 끝난 소속은 include_ended=true일 때만
+	db.Where("employee_id = ? AND is_primary = ?", employee.ID, false).Delete(&Assignment{})
+	count := func(url string) int {
+		w := orgRequest(router, token, "GET", url, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		n := 0
+		for _, row := range readExportCSV(t, w.Body.Bytes()) {
+			if row["employee_name"] == employee.Employee_Name {
+				n++
+			}
+		}
+		return n
+	}
+	assert.Equal(t, 1, count("/api/export/assignments"))
+	assert.Equal(t, 2, count("/api/export/assignments?include_ended=true"))
+
+	w = orgRequest(router, token, "GET", "/api/export/assignments?include_ended=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
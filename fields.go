@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ?include=에 사용할 수 있는 이름(응답의 json 이름) -> Preload 경로(Go field 이름)
+var (
+	employeeIncludes   = map[string]string{"departments": "Employee_Departments", "manager": "Manager"}
+	departmentIncludes = map[string]string{"employees": "Department_Employees", "employees.departments": "Department_Employees.Employee_Departments"}
+	assignmentIncludes = map[string]string{"department": "Department"}
+)
+
+// ?fields=를 나눈 tree. nil이면 모든 항목
+type fieldTree map[string]fieldTree
+
+// 조회 응답에 포함할 연관 데이터와 항목
+type ReadOptions struct {
+	Includes []string  // ?include= 이름
+	Preloads []string  // Preload 경로
+	Fields   fieldTree // ?fields=. nil이면 모든 항목
+
+	includeKeys map[string]bool // 응답에 항상 남기는 연관 데이터의 json 이름
+	omitPaths   map[string]bool // include하지 않아서 응답에서 빼는 연관 데이터 ("employees.departments")
+}
+
+/* ?include=, ?fields= 확인. fields는 응답 타입(model)의 json 이름. include가 없으면 defaults, include=(빈 값)이면 연관 데이터 없음 */
+func parseReadOptions(c *gin.Context, model interface{}, includes map[string]string, defaults ...string) (*ReadOptions, *ApiError) {
+	options := &ReadOptions{includeKeys: map[string]bool{}, omitPaths: map[string]bool{}}
+	modelType := reflect.Indirect(reflect.ValueOf(model)).Type()
+
+	names := defaults
+	if value, ok := c.GetQuery("include"); ok {
+		names = nil
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !containsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		path, ok := includes[name]
+		if !ok {
+			return nil, InvalidParameter("include", "Cannot include "+name+". Use one of "+strings.Join(sortedKeys(includes), ", "))
+		}
+		options.Includes = append(options.Includes, name)
+		options.Preloads = append(options.Preloads, path)
+		options.includeKeys[strings.Split(name, ".")[0]] = true
+	}
+	for name := range includes {
+		if !options.included(name) {
+			options.omitPaths[name] = true
+		}
+	}
+
+	if value := c.Query("fields"); value != "" {
+		options.Fields = fieldTree{}
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			if !hasJSONPath(modelType, strings.Split(field, ".")) {
+				return nil, InvalidParameter("fields", "Unknown field "+field+". Use one of "+strings.Join(jsonFieldNames(modelType), ", "))
+			}
+			tree := options.Fields
+			for _, part := range strings.Split(field, ".") {
+				if tree[part] == nil {
+					tree[part] = fieldTree{}
+				}
+				tree = tree[part]
+			}
+		}
+	}
+	return options, nil
+}
+
+/* include에 name이 있는지 */
+func (options *ReadOptions) Has(name string) bool {
+	return containsString(options.Includes, name)
+}
+
+/* name 또는 그 아래 연관 데이터를 include했는지 */
+func (options *ReadOptions) included(name string) bool {
+	for _, include := range options.Includes {
+		if include == name || strings.HasPrefix(include, name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+/* Preload 적용 */
+func (options *ReadOptions) Preload(query *gorm.DB) *gorm.DB {
+	for _, preload := range options.Preloads {
+		query = query.Preload(preload)
+	}
+	return query
+}
+
+/* include하지 않은 연관 데이터와 fields에 없는 항목을 빼고 응답. PageResponse면 data만 거름 */
+func (options *ReadOptions) JSON(c *gin.Context, status int, data interface{}) {
+	if options.Fields == nil && len(options.omitPaths) == 0 {
+		c.JSON(status, data)
+		return
+	}
+
+	target := data
+	if response, ok := data.(*PageResponse); ok {
+		target = response.Data
+	}
+	b, err := json.Marshal(target)
+	if err != nil {
+		AbortWithInternalError(c, err, "Response error")
+		return
+	}
+	var value interface{}
+	err = json.Unmarshal(b, &value)
+	if err != nil {
+		AbortWithInternalError(c, err, "Response error")
+		return
+	}
+	value = options.omit(value, "")
+	if options.Fields != nil {
+		value = options.filter(value, options.Fields, true)
+	}
+
+	if response, ok := data.(*PageResponse); ok {
+		copied := *response
+		copied.Data = value
+		c.JSON(status, copied)
+		return
+	}
+	c.JSON(status, value)
+}
+
+/* include하지 않은 연관 데이터를 뺌. prefix는 상위 연관 데이터의 경로 */
+func (options *ReadOptions) omit(value interface{}, prefix string) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = options.omit(v[i], prefix)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			path := prefix + key
+			if options.omitPaths[path] {
+				delete(v, key)
+			} else if options.hasOmitUnder(path) {
+				v[key] = options.omit(v[key], path+".")
+			}
+		}
+	}
+	return value
+}
+
+func (options *ReadOptions) hasOmitUnder(path string) bool {
+	for omitted := range options.omitPaths {
+		if strings.HasPrefix(omitted, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+/* tree에 있는 항목만 남김. 빈 tree는 그 아래 모든 항목. include한 연관 데이터는 최상위에서 항상 남김 */
+func (options *ReadOptions) filter(value interface{}, tree fieldTree, top bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = options.filter(v[i], tree, top)
+		}
+		return v
+	case map[string]interface{}:
+		if len(tree) == 0 {
+			return v
+		}
+		for key := range v {
+			sub, ok := tree[key]
+			if !ok && !(top && options.includeKeys[key]) {
+				delete(v, key)
+			} else if ok {
+				v[key] = options.filter(v[key], sub, false)
+			}
+		}
+		return v
+	}
+	return value
+}
+
+func jsonTagName(field reflect.StructField) string {
+	if field.PkgPath != "" { // unexported
+		return ""
+	}
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tag == "-" {
+		return ""
+	} else if tag != "" {
+		return tag
+	}
+	return field.Name
+}
+
+/* 응답 json의 항목 이름 (embedded struct 포함) */
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			names = append(names, jsonFieldNames(field.Type)...)
+		} else if name := jsonTagName(field); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+/* "departments.name" 처럼 연관 데이터 아래 항목까지 확인 */
+func hasJSONPath(t reflect.Type, path []string) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || len(path) == 0 {
+		return len(path) == 0
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			if hasJSONPath(field.Type, path) {
+				return true
+			}
+		} else if jsonTagName(field) == path[0] {
+			if len(path) == 1 {
+				return true
+			}
+			return hasJSONPath(field.Type, path[1:])
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/* include에 as_of로 조회할 수 없는 연관 데이터가 있으면 400 */
+func (options *ReadOptions) checkAsOf(supported ...string) *ApiError {
+	for _, name := range options.Includes {
+		if !containsString(supported, name) {
+			return InvalidParameter("include", name+" cannot be included with as_of")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldsTestData(t *testing.T) (*gin.Engine, string, Employee) {
+	token, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/employee/", ReadEmployee)
+	router.GET("/api/employee/:id/assignments", ReadAssignment)
+	router.GET("/api/department/", ReadDepartment)
+	router.GET("/api/department/:name", SearchDepartmentByName)
+
+	department := Department{Department_Name: "Fields Team"}
+	db.Create(&department)
+	manager := Employee{Employee_Name: "Fields Manager"}
+	db.Create(&manager)
+	employee := Employee{Employee_Name: "Fields Kim", Email: "kim@example.com", ManagerID: &manager.ID}
+	db.Create(&employee)
+	db.Create(&Assignment{EmployeeID: employee.ID, DepartmentID: department.ID, IsPrimary: true})
+	t.Cleanup(func() {
+		db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+		db.Unscoped().Delete(&employee)
+		db.Unscoped().Delete(&manager)
+		db.Unscoped().Delete(&department)
+	})
+
+	return router, token, employee
+}
+
+func TestFieldsAndIncludeEmployee(t *testing.T) {
+	router, token, employee := fieldsTestData(t)
+	filter := "&filter=name=Fields Kim"
+
+	read := func(query string) []map[string]interface{} {
+		var rows []map[string]interface{}
+		response := PageResponse{Data: &rows}
+		w := orgRequest(router, token, "GET", "/api/employee/?"+query+filter, "")
+		assert.Equal(t, http.StatusOK, w.Code, query)
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(rows), query)
+		return rows
+	}
+
+	// 기본은 모든 항목과 소속 부서
+	rows := read("")
+	assert.Equal(t, 1, len(rows[0]["departments"].([]interface{})))
+	assert.Contains(t, rows[0], "email")
+	assert.Contains(t, rows[0], "jobTitle")
+	assert.NotContains(t, rows[0], "deletedAt")
+
+	rows = read("fields=id,name")
+	assert.Equal(t, map[string]interface{}{"id": float64(employee.ID), "name": "Fields Kim", "departments": rows[0]["departments"]}, rows[0])
+
+	rows = read("include=&fields=id")
+	assert.Equal(t, map[string]interface{}{"id": float64(employee.ID)}, rows[0])
+
+	rows = read("include=manager&fields=id,manager.name")
+	assert.Equal(t, map[string]interface{}{"name": "Fields Manager"}, rows[0]["manager"])
+	assert.NotContains(t, rows[0], "departments")
+
+	rows = read("include=departments&fields=id,departments.name")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "Fields Team"}}, rows[0]["departments"])
+
+	// include한 연관 데이터는 없어도 빈 배열, include하지 않으면 항목이 없음
+	rows = read("include=")
+	assert.NotContains(t, rows[0], "departments")
+	var managers []map[string]interface{}
+	response := PageResponse{Data: &managers}
+	w := orgRequest(router, token, "GET", "/api/employee/?include=departments&filter=name=Fields Manager", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.Equal(t, 1, len(managers)) {
+		assert.Equal(t, []interface{}{}, managers[0]["departments"])
+	}
+
+	// model의 Go 이름은 사용할 수 없음
+	for _, query := range []string{"include=salary", "fields=salary", "fields=manager.salary", "fields=Employee_Name", "include=manager&as_of=2020-01-01"} {
+		w := orgRequest(router, token, "GET", "/api/employee/?"+query, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	w = orgRequest(router, token, "GET", fmt.Sprintf("/api/employee/%d/assignments?include=&fields=departmentId,isPrimary", employee.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var assignments []map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &assignments)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(assignments)) {
+		assert.Equal(t, 2, len(assignments[0]))
+		assert.Equal(t, true, assignments[0]["isPrimary"])
+	}
+}
+
+func TestFieldsAndIncludeDepartment(t *testing.T) {
+	router, token, _ := fieldsTestData(t)
+
+	var departments []map[string]interface{}
+	w := orgRequest(router, token, "GET", "/api/department/Fields Team?include=employees.departments&fields=name,employees.name,employees.departments.id", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err := json.Unmarshal(w.Body.Bytes(), &departments)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(departments)) {
+		employees := departments[0]["employees"].([]interface{})
+		employee := employees[0].(map[string]interface{})
+		assert.Equal(t, "Fields Kim", employee["name"])
+		assert.Equal(t, 1, len(employee["departments"].([]interface{})))
+		assert.Equal(t, 2, len(employee))
+	}
+
+	// employees만 include하면 사원의 departments는 없음
+	w = orgRequest(router, token, "GET", "/api/department/Fields Team?include=employees", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &departments))
+	if assert.Equal(t, 1, len(departments)) {
+		employee := departments[0]["employees"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "Fields Kim", employee["name"])
+		assert.NotContains(t, employee, "departments")
+	}
+
+	// include= 는 /api/department/only 와 같음
+	var rows []DepartmentResponse
+	response := PageResponse{Data: &rows}
+	w = orgRequest(router, token, "GET", "/api/department/?include=&filter=name=Fields Team", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(rows)) {
+		assert.Nil(t, rows[0].Employees)
+	}
+}
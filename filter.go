@@ -0,0 +1,311 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 필터 값의 종류. 종류별로 사용할 수 있는 연산자가 다름
+const (
+	FilterString = "string"
+	FilterNumber = "number"
+	FilterTime   = "time"
+)
+
+// 필터 연산자. 긴 것부터 찾아야 >=가 >로 잘리지 않음
+var filterOperators = []string{">=", "<=", "!=", "!~", "=", ">", "<", "~"}
+
+var filterOperatorsByType = map[string][]string{
+	FilterString: {"=", "!=", "~", "!~"},
+	FilterNumber: {"=", "!=", ">", ">=", "<", "<="},
+	FilterTime:   {"=", "!=", ">", ">=", "<", "<="},
+}
+
+// 필터에 사용할 수 있는 항목. Apply가 있으면 Column 대신 직접 조건을 만듦 (다른 Table 조회 등)
+type FilterField struct {
+	Column   string
+	Type     string
+	Nullable bool // null 값 비교 가능
+	Apply    func(query *gorm.DB, condition FilterCondition) *gorm.DB
+}
+
+// ?filter=의 조건 하나. field op value[,value...]
+type FilterCondition struct {
+	Field  string
+	Op     string
+	Values []string // 여러 값은 = (IN), != (NOT IN)에서만 사용
+}
+
+/* ?filter= 를 읽어 query에 조건을 추가. 없으면 query 그대로 */
+func applyFilter(c *gin.Context, query *gorm.DB, fields map[string]FilterField) (*gorm.DB, *ApiError) {
+	conditions, apiErr := ParseFilter(c.Query("filter"), fields)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	for _, condition := range conditions {
+		field := fields[condition.Field]
+		if field.Apply != nil {
+			query = field.Apply(query, condition)
+			continue
+		}
+		query = filterClause(query, field, condition)
+	}
+	return query, nil
+}
+
+/* "entry_time>=2025-01-01;department=Sales,HR;name~kim" -> 조건 목록. ; 와 , 는 \ 로 escape */
+func ParseFilter(value string, fields map[string]FilterField) ([]FilterCondition, *ApiError) {
+	var conditions []FilterCondition
+	for _, part := range splitEscaped(value, ';') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		end := 0
+		for end < len(part) && (part[end] == '_' || part[end] >= 'a' && part[end] <= 'z') {
+			end++
+		}
+		name := part[:end]
+		rest := strings.TrimSpace(part[end:])
+		if name == "" {
+			return nil, InvalidParameter("filter", "Invalid filter: "+part)
+		}
+		field, ok := fields[name]
+		if !ok {
+			return nil, InvalidParameter("filter", "Cannot filter by "+name+". Use one of "+strings.Join(filterFieldNames(fields), ", "))
+		}
+
+		op := ""
+		for _, candidate := range filterOperators {
+			if strings.HasPrefix(rest, candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return nil, InvalidParameter("filter", "Invalid operator in "+part+". Use one of "+strings.Join(filterOperators, " "))
+		}
+		if !containsString(filterOperatorsByType[field.Type], op) {
+			return nil, InvalidParameter("filter", fmt.Sprintf("%s cannot be used with %s. Use one of %s", op, name, strings.Join(filterOperatorsByType[field.Type], " ")))
+		}
+
+		values := splitEscaped(rest[len(op):], ',')
+		for i := range values {
+			values[i] = unescapeFilter(strings.TrimSpace(values[i]))
+		}
+		if len(values) > 1 && op != "=" && op != "!=" {
+			return nil, InvalidParameter("filter", "Only = and != can have several values: "+part)
+		}
+		for _, v := range values {
+			if v == "" {
+				return nil, InvalidParameter("filter", "Empty value in "+part)
+			}
+			if apiErr := field.check(name, op, v); apiErr != nil {
+				return nil, apiErr
+			}
+		}
+		conditions = append(conditions, FilterCondition{Field: name, Op: op, Values: values})
+	}
+	return conditions, nil
+}
+
+/* 값이 항목의 종류에 맞는지 확인 */
+func (field FilterField) check(name string, op string, value string) *ApiError {
+	if value == "null" {
+		if !field.Nullable || (op != "=" && op != "!=") {
+			return InvalidParameter("filter", name+" cannot be compared with null")
+		}
+		return nil
+	}
+	switch field.Type {
+	case FilterNumber:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return InvalidParameter("filter", name+" should be a number")
+		}
+	case FilterTime:
+		if _, err := parseDate(value); err != nil {
+			return InvalidParameter("filter", name+" should be 2006-01-02 or RFC3339")
+		}
+	}
+	return nil
+}
+
+/* 조건 하나를 WHERE 절로. 날짜만 적은 시간 값은 그 날 전체로 비교 */
+func filterClause(query *gorm.DB, field FilterField, condition FilterCondition) *gorm.DB {
+	column := field.Column
+	values := condition.Values
+	if len(values) == 1 && values[0] == "null" {
+		if condition.Op == "=" {
+			return query.Where(column + " IS NULL")
+		}
+		return query.Where(column + " IS NOT NULL")
+	}
+
+	switch condition.Op {
+	case "~", "!~":
+		like := "LOWER(" + column + ") LIKE ? ESCAPE '!'"
+		if condition.Op == "!~" {
+			like = "LOWER(" + column + ") NOT LIKE ? ESCAPE '!'"
+		}
+		return query.Where(like, "%"+escapeLike(strings.ToLower(values[0]))+"%")
+	}
+
+	if field.Type == FilterTime {
+		return timeFilterClause(query, column, condition)
+	}
+
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+		if field.Type == FilterNumber {
+			args[i], _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	switch {
+	case condition.Op == "=" && len(args) > 1:
+		return query.Where(column+" IN ?", args)
+	case condition.Op == "!=" && len(args) > 1:
+		return query.Where(column+" NOT IN ?", args)
+	case condition.Op == "!=" && field.Nullable: // SQL의 != 는 null을 제외하므로 포함시킴
+		return query.Where("("+column+" <> ? OR "+column+" IS NULL)", args[0])
+	case condition.Op == "!=":
+		return query.Where(column+" <> ?", args[0])
+	default:
+		return query.Where(column+" "+condition.Op+" ?", args[0])
+	}
+}
+
+func timeFilterClause(query *gorm.DB, column string, condition FilterCondition) *gorm.DB {
+	ranges := make([]string, 0, len(condition.Values))
+	var args []interface{}
+	for _, value := range condition.Values {
+		from, _ := parseDate(value)
+		to := from
+		if len(value) == len("2006-01-02") {
+			to = from.AddDate(0, 0, 1)
+		}
+
+		switch condition.Op {
+		case ">":
+			if to.After(from) {
+				return query.Where(column+" >= ?", to)
+			}
+			return query.Where(column+" > ?", from)
+		case ">=":
+			return query.Where(column+" >= ?", from)
+		case "<":
+			return query.Where(column+" < ?", from)
+		case "<=":
+			if to.After(from) {
+				return query.Where(column+" < ?", to)
+			}
+			return query.Where(column+" <= ?", from)
+		}
+
+		if to.After(from) {
+			ranges = append(ranges, "("+column+" >= ? AND "+column+" < ?)")
+			args = append(args, from, to)
+		} else {
+			ranges = append(ranges, column+" = ?")
+			args = append(args, from)
+		}
+	}
+	where := "(" + strings.Join(ranges, " OR ") + ")"
+	if condition.Op == "!=" {
+		where = "NOT " + where
+	}
+	return query.Where(where, args...)
+}
+
+/* 구분자로 나눔. \ 뒤의 구분자는 나누지 않음 */
+func splitEscaped(value string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' {
+			i++
+		} else if value[i] == sep {
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func unescapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+func filterFieldNames(fields map[string]FilterField) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/* 사원 목록의 필터 항목. department는 부서 이름이고 asOf가 있으면 그 시점의 소속으로 비교 */
+func employeeFilterFields(asOf *time.Time) map[string]FilterField {
+	return map[string]FilterField{
+		"id":              {Column: "employees.id", Type: FilterNumber},
+		"name":            {Column: "employees.employee_name", Type: FilterString},
+		"email":           {Column: "employees.email", Type: FilterString},
+		"phone":           {Column: "employees.phone", Type: FilterString},
+		"job_title":       {Column: "employees.job_title", Type: FilterString},
+		"employment_type": {Column: "employees.employment_type", Type: FilterString},
+		"status":          {Column: "employees.status", Type: FilterString},
+		"manager_id":      {Column: "employees.manager_id", Type: FilterNumber, Nullable: true},
+		"entry_time":      {Column: "employees.entry_time", Type: FilterTime},
+		"department": {Type: FilterString, Apply: func(query *gorm.DB, condition FilterCondition) *gorm.DB {
+			members := db.Model(&Assignment{})
+			if asOf != nil {
+				members = assignmentsAsOf(db, *asOf)
+			}
+			members = members.Select("employee_departments.employee_id").
+				Joins("JOIN departments ON departments.id = employee_departments.department_id")
+			if condition.Op == "~" || condition.Op == "!~" {
+				members = members.Where("LOWER(departments.department_name) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(condition.Values[0]))+"%")
+			} else {
+				members = members.Where("departments.department_name IN ?", condition.Values)
+			}
+			if condition.Op == "!=" || condition.Op == "!~" {
+				return query.Where("employees.id NOT IN (?)", members)
+			}
+			return query.Where("employees.id IN (?)", members)
+		}},
+	}
+}
+
+// 부서 목록의 필터 항목. parent는 상위 부서 이름
+var departmentFilterFields = map[string]FilterField{
+	"id":        {Column: "departments.id", Type: FilterNumber},
+	"name":      {Column: "departments.department_name", Type: FilterString},
+	"parent_id": {Column: "departments.parent_id", Type: FilterNumber, Nullable: true},
+	"parent": {Type: FilterString, Apply: func(query *gorm.DB, condition FilterCondition) *gorm.DB {
+		parents := db.Model(&Department{}).Select("id")
+		if condition.Op == "~" || condition.Op == "!~" {
+			parents = parents.Where("LOWER(department_name) LIKE ? ESCAPE '!'", "%"+escapeLike(strings.ToLower(condition.Values[0]))+"%")
+		} else {
+			parents = parents.Where("department_name IN ?", condition.Values)
+		}
+		if condition.Op == "!=" || condition.Op == "!~" {
+			return query.Where("(departments.parent_id NOT IN (?) OR departments.parent_id IS NULL)", parents)
+		}
+		return query.Where("departments.parent_id IN (?)", parents)
+	}},
+}
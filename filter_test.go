@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	fields := employeeFilterFields(nil)
+
+	conditions, apiErr := ParseFilter(`entry_time>=2025-01-01; department=Sales,HR ;name~kim\,lee`, fields)
+	assert.Nil(t, apiErr)
+	assert.Equal(t, []FilterCondition{
+		{Field: "entry_time", Op: ">=", Values: []string{"2025-01-01"}},
+		{Field: "department", Op: "=", Values: []string{"Sales", "HR"}},
+		{Field: "name", Op: "~", Values: []string{"kim,lee"}},
+	}, conditions)
+
+	conditions, apiErr = ParseFilter("", fields)
+	assert.Nil(t, apiErr)
+	assert.Empty(t, conditions)
+
+	for _, filter := range []string{
+		"salary>100",            // 없는 항목
+		"name^kim",              // 없는 연산자
+		"name>kim",              // 문자열에 크기 비교
+		"entry_time~2025",       // 시간에 포함 검색
+		"id=abc",                // 숫자가 아님
+		"entry_time>=yesterday", // 날짜가 아님
+		"entry_time>=2025-01-01,2025-02-01",
+		"name=",
+		"name=null", // null이 될 수 없는 항목
+		"=kim",
+	} {
+		_, apiErr = ParseFilter(filter, fields)
+		if assert.NotNil(t, apiErr, filter) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.Status)
+			assert.Equal(t, "filter", apiErr.Errors[0].Field)
+		}
+	}
+}
+
+func TestFilterEmployees(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/employee/", ReadEmployee)
+	router.GET("/api/department/only", ReadDepartmentOnly)
+
+	sales := Department{Department_Name: "Filter Sales"}
+	db.Create(&sales)
+	hr := Department{Department_Name: "Filter HR", ParentID: &sales.ID}
+	db.Create(&hr)
+	kim := Employee{Employee_Name: "Filter Kim", Status: StatusOnLeave}
+	lee := Employee{Employee_Name: "Filter Lee", ManagerID: nil}
+	park := Employee{Employee_Name: "Filter Park"}
+	for _, employee := range []*Employee{&kim, &lee, &park} {
+		db.Create(employee)
+	}
+	lee.ManagerID = &kim.ID
+	db.Model(&lee).Update("manager_id", kim.ID)
+	db.Model(&kim).Update("entry_time", time.Date(2020, 3, 1, 9, 0, 0, 0, time.Local))
+	db.Create(&Assignment{EmployeeID: kim.ID, DepartmentID: sales.ID})
+	db.Create(&Assignment{EmployeeID: lee.ID, DepartmentID: hr.ID})
+	t.Cleanup(func() {
+		for _, employee := range []*Employee{&kim, &lee, &park} {
+			db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+			db.Unscoped().Delete(employee)
+		}
+		db.Unscoped().Delete(&hr)
+		db.Unscoped().Delete(&sales)
+	})
+
+	names := func(filter string) []string {
+		var employees []EmployeeResponse
+		response := PageResponse{Data: &employees}
+		w := orgRequest(router, token, "GET", "/api/employee/?limit=100&filter="+url.QueryEscape(filter), "")
+		assert.Equal(t, http.StatusOK, w.Code, filter)
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		result := []string{}
+		for _, employee := range employees {
+			result = append(result, employee.Name)
+		}
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{"Filter Kim", "Filter Lee"}, names("name~filter;department=Filter Sales,Filter HR"))
+	assert.ElementsMatch(t, []string{"Filter Park"}, names("name~FILTER;department!=Filter Sales,Filter HR"))
+	assert.ElementsMatch(t, []string{"Filter Kim"}, names("name~filter;status=on-leave"))
+	assert.ElementsMatch(t, []string{"Filter Lee"}, names("name~filter;manager_id="+fmt.Sprint(kim.ID)))
+	assert.ElementsMatch(t, []string{"Filter Kim", "Filter Park"}, names("name~filter;manager_id=null"))
+	assert.ElementsMatch(t, []string{"Filter Kim", "Filter Park"}, names("name~filter;manager_id!="+fmt.Sprint(kim.ID)))
+	assert.ElementsMatch(t, []string{"Filter Kim"}, names("name~filter;entry_time=2020-03-01"))
+	assert.ElementsMatch(t, []string{"Filter Kim"}, names("name~filter;entry_time<=2020-03-01"))
+	assert.ElementsMatch(t, []string{"Filter Lee", "Filter Park"}, names("name~filter;entry_time>2020-03-01"))
+	assert.ElementsMatch(t, []string{"Filter Lee", "Filter Park"}, names("name!~kim;name~filter"))
+
+	w := orgRequest(router, token, "GET", "/api/employee/?filter=salary>1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var departments []DepartmentResponse
+	response := PageResponse{Data: &departments}
+	w = orgRequest(router, token, "GET", "/api/department/only?filter="+url.QueryEscape("parent=Filter Sales"), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(departments)) {
+		assert.Equal(t, "Filter HR", departments[0].Name)
+	}
+	w = orgRequest(router, token, "GET", "/api/department/only?filter=parent_id>x", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 사원 기록의 종류
+const (
+	HistoryCreated  = "created"
+	HistoryUpdated  = "updated"
+	HistoryDeleted  = "deleted"
+	HistoryRestored = "restored"
+)
+
+// 사원 정보가 바뀔 때마다 남기는 기록. ChangedAt부터 다음 기록 전까지의 사원 상태
+type EmployeeHistory struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	EmployeeID     uint      `gorm:"index" json:"employeeId"`
+	Action         string    `gorm:"size:16" json:"action"`
+	ChangedAt      time.Time `gorm:"index" json:"changedAt"`
+	Changes        JSONMap   `json:"changes,omitempty"` // 항목별 {"from", "to"}
+	EntryTime      time.Time `json:"entryTime"`
+	Employee_Name  string    `json:"employeeName"`
+	Email          string    `gorm:"size:255" json:"email"`
+	Phone          string    `gorm:"size:32" json:"phone"`
+	JobTitle       string    `gorm:"size:128" json:"jobTitle"`
+	EmploymentType string    `gorm:"size:32" json:"employmentType"`
+	Status         string    `gorm:"size:32" json:"status"`
+	ManagerID      *uint     `json:"managerId"`
+	Attributes     JSONMap   `json:"attributes"`
+}
+
+// 사원 이력 화면의 한 줄. 사원 정보 변경과 부서 소속 시작, 종료
+type HistoryEvent struct {
+	Time       time.Time           `json:"time"`
+	Event      string              `json:"event"` // created, updated, deleted, assigned, unassigned
+	Changes    JSONMap             `json:"changes,omitempty"`
+	Assignment *AssignmentResponse `json:"assignment,omitempty"`
+}
+
+// as_of 조회에서 사원 Table 대신 사용할 기록의 column
+var employeeHistoryColumns = "employee_id AS id, entry_time, employee_name, email, phone, job_title, employment_type, status, manager_id, attributes"
+
+func newEmployeeHistory(employee Employee, action string) EmployeeHistory {
+	return EmployeeHistory{
+		EmployeeID:     employee.ID,
+		Action:         action,
+		ChangedAt:      time.Now(),
+		EntryTime:      employee.EntryTime,
+		Employee_Name:  employee.Employee_Name,
+		Email:          employee.Email,
+		Phone:          employee.Phone,
+		JobTitle:       employee.JobTitle,
+		EmploymentType: employee.EmploymentType,
+		Status:         employee.Status,
+		ManagerID:      employee.ManagerID,
+		Attributes:     employee.Attributes,
+	}
+}
+
+/* 이력 화면에 보여줄 항목 */
+func (history EmployeeHistory) fields() map[string]interface{} {
+	var managerID interface{}
+	if history.ManagerID != nil {
+		managerID = *history.ManagerID
+	}
+	return map[string]interface{}{
+		"employeeName":   history.Employee_Name,
+		"email":          history.Email,
+		"phone":          history.Phone,
+		"jobTitle":       history.JobTitle,
+		"employmentType": history.EmploymentType,
+		"status":         history.Status,
+		"managerId":      managerID,
+		"attributes":     history.Attributes,
+	}
+}
+
+/* 이전 기록과 달라진 항목 */
+func diffHistory(prev EmployeeHistory, next EmployeeHistory) JSONMap {
+	return diffMaps(prev.fields(), next.fields())
+}
+
+/* 두 map에서 값이 다른 항목의 {"from", "to"} */
+func diffMaps(before map[string]interface{}, after map[string]interface{}) JSONMap {
+	changes := JSONMap{}
+	for name, value := range after {
+		if !reflect.DeepEqual(before[name], value) {
+			changes[name] = map[string]interface{}{"from": before[name], "to": value}
+		}
+	}
+	for name, value := range before {
+		if _, ok := after[name]; !ok {
+			changes[name] = map[string]interface{}{"from": value, "to": nil}
+		}
+	}
+	return changes
+}
+
+/* 사원들의 현재 상태를 기록하고 검색 색인을 갱신. 삭제는 지우기 전에 기록 */
+func recordEmployeeHistory(tx *gorm.DB, action string, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var employees []Employee
+	err := tx.Where("id IN ?", ids).Find(&employees).Error
+	if err != nil {
+		return err
+	}
+
+	for _, employee := range employees {
+		history := newEmployeeHistory(employee, action)
+		if action != HistoryDeleted {
+			var prev EmployeeHistory
+			tx.Where("employee_id = ?", employee.ID).Order("id desc").Limit(1).Find(&prev)
+			history.Changes = diffHistory(prev, history)
+			if action == HistoryUpdated && len(history.Changes) == 0 {
+				continue
+			}
+		}
+
+		err = tx.Create(&history).Error
+		if err != nil {
+			return err
+		}
+	}
+	return indexEmployees(tx, employees)
+}
+
+/* ?as_of= 시점. 날짜만 보내면 그 날의 끝. 없으면 nil */
+func parseAsOf(c *gin.Context) (*time.Time, *ApiError) {
+	value := c.Query("as_of")
+	if value == "" {
+		return nil, nil
+	}
+
+	asOf, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	dateOnly := err == nil
+	if !dateOnly {
+		asOf, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, InvalidParameter("as_of", "as_of should be 2006-01-02 or RFC3339")
+		}
+	}
+	if asOf.After(time.Now()) {
+		return nil, InvalidParameter("as_of", "as_of should not be in the future")
+	}
+	if dateOnly {
+		asOf = asOf.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return &asOf, nil
+}
+
+/* asOf 시점에 유효했던 소속 */
+func assignmentsAsOf(tx *gorm.DB, asOf time.Time) *gorm.DB {
+	return tx.Unscoped().Model(&Assignment{}).Where("start_date <= ? AND (end_date IS NULL OR end_date > ?)", asOf, asOf)
+}
+
+/* asOf 시점의 사원 목록. employees Table 대신 사원마다 그 시점의 마지막 기록을 사용 */
+func employeesAsOf(tx *gorm.DB, asOf time.Time) *gorm.DB {
+	latest := tx.Model(&EmployeeHistory{}).Select("MAX(id)").Where("changed_at <= ?", asOf).Group("employee_id")
+	snapshot := tx.Model(&EmployeeHistory{}).Select(employeeHistoryColumns).Where("id IN (?) AND action <> ?", latest, HistoryDeleted)
+	return tx.Unscoped().Table("(?) AS employees", snapshot) // 삭제 여부는 기록의 action으로 판단
+}
+
+/* asOf 시점에 소속된 부서를 채움 */
+func fillDepartmentsAsOf(tx *gorm.DB, employees []Employee, asOf time.Time) error {
+	if len(employees) == 0 {
+		return nil
+	}
+
+	index := make(map[uint]*Employee, len(employees))
+	ids := make([]uint, 0, len(employees))
+	for i := range employees {
+		employees[i].Employee_Departments = []*Department{}
+		index[employees[i].ID] = &employees[i]
+		ids = append(ids, employees[i].ID)
+	}
+
+	var assignments []Assignment
+	err := assignmentsAsOf(tx, asOf).Where("employee_id IN ?", ids).Preload("Department").Order("start_date asc, id asc").Find(&assignments).Error
+	if err != nil {
+		return err
+	}
+	for _, assignment := range assignments {
+		if assignment.Department != nil { // 삭제된 부서
+			index[assignment.EmployeeID].Employee_Departments = append(index[assignment.EmployeeID].Employee_Departments, assignment.Department)
+		}
+	}
+	return nil
+}
+
+/* 사원 정보 변경과 부서 소속 이력. 삭제된 사원도 조회 가능 */
+func ReadEmployeeHistory(c *gin.Context) {
+	var histories []EmployeeHistory
+	result := db.Where("employee_id = ?", c.Param("id")).Order("id asc").Find(&histories)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
+
+	var assignments []Assignment
+	result = db.Unscoped().Where("employee_id = ?", c.Param("id")).Preload("Department").Find(&assignments)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
+
+	if len(histories) == 0 && len(assignments) == 0 {
+		var employee Employee
+		db.Where("id = ?", c.Param("id")).Find(&employee)
+		if employee.ID == 0 {
+			AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
+			return
+		}
+	}
+
+	events := make([]HistoryEvent, 0, len(histories)+len(assignments)*2)
+	for _, history := range histories {
+		events = append(events, HistoryEvent{Time: history.ChangedAt, Event: history.Action, Changes: history.Changes})
+	}
+	for i := range assignments {
+		assignment := newAssignmentResponse(assignments[i])
+		events = append(events, HistoryEvent{Time: assignment.StartDate, Event: "assigned", Assignment: &assignment})
+		if assignment.EndDate != nil {
+			events = append(events, HistoryEvent{Time: *assignment.EndDate, Event: "unassigned", Assignment: &assignment})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+/* 2020-01-01 Engineer로 입사, 2022-01-01 Lead로 변경, 2023-01-01 부서에서 나감 */
+func historyTestData(t *testing.T) (*gin.Engine, string, uint) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/employee/", ReadEmployee)
+	router.POST("/api/employee/", AddEmployee)
+	router.PUT("/api/employee/:id", UpdateEmployee)
+	router.DELETE("/api/employee/id/:id", DeleteEmployeById)
+	router.GET("/api/employee/:id/history", ReadEmployeeHistory)
+	router.GET("/api/department/:name", SearchDepartmentByName)
+	router.GET("/api/department/:name/employee", ReadEmployeeInDepartment)
+	router.DELETE("/api/assign/id/:eid/:department", DeleteEmployeeDepartmentById)
+
+	department := Department{Department_Name: "HistorySales"}
+	db.Create(&department)
+
+	w := orgRequest(router, token, "POST", "/api/employee/", `[{"ename": "History Employee", "dname": "HistorySales", "job_title": "Engineer"}]`)
+	var result struct {
+		Results []bulkResult `json:"results"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	id := result.Results[0].ID
+	t.Cleanup(func() {
+		db.Where("employee_id = ?", id).Delete(&EmployeeHistory{})
+		db.Unscoped().Where("employee_id = ?", id).Delete(&Assignment{})
+		db.Unscoped().Where("id = ?", id).Delete(&Employee{})
+		db.Unscoped().Delete(&department)
+	})
+
+	db.Model(&EmployeeHistory{}).Where("employee_id = ?", id).Update("changed_at", time.Date(2020, 1, 1, 9, 0, 0, 0, time.Local))
+	db.Model(&Assignment{}).Where("employee_id = ?", id).Update("start_date", time.Date(2020, 1, 1, 9, 0, 0, 0, time.Local))
+
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/employee/%d", id), `{"job_title": "Lead"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&EmployeeHistory{}).Where("employee_id = ? AND action = ?", id, HistoryUpdated).Update("changed_at", time.Date(2022, 1, 1, 9, 0, 0, 0, time.Local))
+
+	w = orgRequest(router, token, "DELETE", fmt.Sprintf("/api/assign/id/%d/HistorySales?end_date=2023-01-01", id), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return router, token, id
+}
+
+func TestReadEmployeeInDepartmentAsOf(t *testing.T) {
+	router, token, id := historyTestData(t)
+
+	for asOf, jobTitle := range map[string]string{"2021-06-01": "Engineer", "2022-06-01": "Lead", "2024-01-01": "", "": ""} {
+		var employees []EmployeeResponse
+		w := orgRequest(router, token, "GET", "/api/department/HistorySales/employee?as_of="+asOf, "")
+		err := json.Unmarshal(w.Body.Bytes(), &PageResponse{Data: &employees})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Code)
+		if jobTitle == "" {
+			assert.Empty(t, employees, asOf)
+		} else if assert.Equal(t, 1, len(employees), asOf) {
+			assert.Equal(t, id, employees[0].ID)
+			assert.Equal(t, jobTitle, employees[0].JobTitle)
+		}
+	}
+
+	var departments []DepartmentResponse
+	w := orgRequest(router, token, "GET", "/api/department/HistorySales?as_of=2021-06-01", "")
+	err := json.Unmarshal(w.Body.Bytes(), &departments)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 1, len(departments)) && assert.Equal(t, 1, len(departments[0].Employees)) {
+		assert.Equal(t, "Engineer", departments[0].Employees[0].JobTitle)
+	}
+
+	for _, asOf := range []string{"yesterday", "2999-01-01"} {
+		w = orgRequest(router, token, "GET", "/api/department/HistorySales/employee?as_of="+asOf, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, asOf)
+	}
+}
+
+func TestReadEmployeeAsOf(t *testing.T) {
+	router, token, id := historyTestData(t)
+
+	// 삭제된 사원도 삭제 전 시점에서는 조회됨
+	w := orgRequest(router, token, "DELETE", fmt.Sprintf("/api/employee/id/%d", id), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&EmployeeHistory{}).Where("employee_id = ? AND action = ?", id, HistoryDeleted).Update("changed_at", time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local))
+
+	find := func(asOf string) *EmployeeResponse {
+		var employees []EmployeeResponse
+		w := orgRequest(router, token, "GET", "/api/employee/?limit=100&as_of="+asOf, "")
+		err := json.Unmarshal(w.Body.Bytes(), &PageResponse{Data: &employees})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, w.Code)
+		for i := range employees {
+			if employees[i].ID == id {
+				return &employees[i]
+			}
+		}
+		return nil
+	}
+
+	assert.Nil(t, find("2019-06-01"))
+	if employee := find("2021-06-01"); assert.NotNil(t, employee) && assert.Equal(t, 1, len(employee.Departments)) {
+		assert.Equal(t, "Engineer", employee.JobTitle)
+		assert.Equal(t, "HistorySales", employee.Departments[0].Name)
+	}
+	if employee := find("2023-06-01"); assert.NotNil(t, employee) {
+		assert.Equal(t, "Lead", employee.JobTitle)
+		assert.Empty(t, employee.Departments)
+	}
+	assert.Nil(t, find("2024-06-01"))
+}
+
+func TestReadEmployeeHistory(t *testing.T) {
+	router, token, id := historyTestData(t)
+
+	w := orgRequest(router, token, "DELETE", fmt.Sprintf("/api/employee/id/%d", id), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var events []HistoryEvent
+	w = orgRequest(router, token, "GET", fmt.Sprintf("/api/employee/%d/history", id), "")
+	err := json.Unmarshal(w.Body.Bytes(), &events)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.Event)
+	}
+	assert.Equal(t, []string{HistoryCreated, "assigned", HistoryUpdated, "unassigned", HistoryDeleted}, names)
+	if len(events) == 5 {
+		assert.Equal(t, map[string]interface{}{"from": "Engineer", "to": "Lead"}, events[2].Changes["jobTitle"])
+		assert.Equal(t, "HistorySales", events[1].Assignment.Department.Name)
+	}
+
+	w = orgRequest(router, token, "GET", "/api/employee/-1/history", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
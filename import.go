@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// 가져오기 파일의 최대 크기
+const maxImportSize = 10 << 20
+
+// 가져오기 파일에서 사용할 수 있는 column. 사원은 attributes.<key> 도 가능
+var (
+	employeeImportFields   = []string{"ename", "dname", "dnames", "email", "phone", "job_title", "employment_type", "status", "manager_id"}
+	departmentImportFields = []string{"dname", "parent"}
+)
+
+const attributeColumnPrefix = "attributes."
+
+// dry run이거나 실패한 행이 있으면 transaction을 취소할 때 사용
+var errImportRollback = errors.New("import rollback")
+
+// 가져오기에서 행 하나의 처리 결과
+type importResult struct {
+	Row    int       `json:"row"` // 파일의 행 번호. header가 1
+	Status int       `json:"status"`
+	ID     uint      `json:"id,omitempty"` // dry run이면 비어있음
+	Name   string    `json:"name"`
+	Error  *ApiError `json:"error,omitempty"`
+}
+
+// 파일의 한 행. column 이름 -> 값
+type importRow struct {
+	Line   int
+	Values map[string]string
+}
+
+// 행 하나를 만드는 함수. 실패하면 result.Error에 기록
+type importFunc func(c *gin.Context, tx *gorm.DB, row importRow, result *importResult)
+
+/* csv(기본) 또는 xlsx 파일로 사원 추가. ?dry_run=true면 검증 결과만 반환 */
+func ImportEmployees(c *gin.Context) {
+	runImport(c, employeeImportFields, "ename", importEmployee)
+}
+
+/* csv(기본) 또는 xlsx 파일로 부서 추가. parent는 파일의 앞쪽 행에 있는 부서여도 됨 */
+func ImportDepartments(c *gin.Context) {
+	runImport(c, departmentImportFields, "dname", importDepartment)
+}
+
+/* 파일을 읽어 모든 행을 하나의 transaction에서 처리. 한 행이라도 실패하거나 dry run이면 전부 취소 */
+func runImport(c *gin.Context, fields []string, required string, create importFunc) {
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(value)
+		if err != nil {
+			AbortWithError(c, InvalidParameter("dry_run", "dry_run should be true or false"))
+			return
+		}
+	}
+
+	records, apiErr := readImportFile(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	rows, ignored, apiErr := mapImportColumns(c, records, fields, required)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	results := make([]importResult, len(rows))
+	failed := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, row := range rows {
+			results[i] = importResult{Row: row.Line, Name: row.Values[required]}
+			// 실패한 행이 있어도 나머지 행을 계속 검증해서 한 번에 알려줌
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				create(c, tx, row, &results[i])
+				if results[i].Error != nil {
+					return results[i].Error
+				}
+				return nil
+			})
+			if results[i].Error != nil {
+				results[i].Status = results[i].Error.Status
+				failed++
+			} else if err != nil {
+				return err
+			}
+		}
+		if dryRun || failed > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		AbortWithInternalError(c, err, "Import error")
+		return
+	}
+
+	if dryRun {
+		for i := range results {
+			results[i].ID = 0
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun":  true,
+			"valid":   failed == 0,
+			"rows":    len(results),
+			"failed":  failed,
+			"ignored": ignored,
+			"results": results,
+		})
+		return
+	}
+	if failed > 0 {
+		for i := range results {
+			results[i].ID = 0
+			if results[i].Error == nil {
+				results[i].Status = http.StatusFailedDependency
+			}
+		}
+		AbortWithError(c, NewApiError(http.StatusUnprocessableEntity, CodeValidationFailed,
+			fmt.Sprintf("%d of %d rows failed. Nothing is imported", failed, len(results))).WithDetails(gin.H{
+			"ignored": ignored,
+			"results": results,
+		}))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun":  false,
+		"rows":    len(results),
+		"ignored": ignored,
+		"results": results,
+	})
+}
+
+/* multipart의 file 또는 요청 body를 읽음. 형식은 ?format=, 파일 확장자, Content-Type 순서로 판단 */
+func readImportFile(c *gin.Context) ([][]string, *ApiError) {
+	var reader io.Reader = c.Request.Body
+	filename := ""
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, fieldError("file", "required", "file is required")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, InvalidParameter("file", "file cannot be opened")
+		}
+		defer file.Close()
+		reader = file
+		filename = header.Filename
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxImportSize+1))
+	if err != nil {
+		return nil, InvalidParameter("file", "file cannot be read")
+	}
+	if len(data) > maxImportSize {
+		return nil, NewApiError(http.StatusRequestEntityTooLarge, CodeInvalidParameter, fmt.Sprintf("file should be at most %d bytes", maxImportSize))
+	}
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		switch {
+		case strings.EqualFold(filepath.Ext(filename), ".xlsx"),
+			strings.Contains(c.ContentType(), "spreadsheetml"):
+			format = "xlsx"
+		default:
+			format = "csv"
+		}
+	}
+
+	var records [][]string
+	switch format {
+	case "csv":
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // 엑셀에서 저장한 csv의 BOM
+		records, err = readCSV(data)
+	case "xlsx":
+		records, err = readXLSX(data)
+	default:
+		return nil, InvalidParameter("format", "format should be csv or xlsx")
+	}
+	if err != nil {
+		return nil, InvalidParameter("file", err.Error())
+	}
+	if len(records) == 0 {
+		return nil, fieldError("file", "required", "file has no header")
+	}
+	return records, nil
+}
+
+/* csv를 읽음. 빈 줄은 빈 record로 남겨서 index+1이 파일의 행 번호가 되도록 함 */
+func readCSV(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		for len(records) < line-1 {
+			records = append(records, nil)
+		}
+		records = append(records, record)
+	}
+}
+
+/* header를 column 이름으로 바꾸고 빈 행을 건너뜀. ?mapping={"파일의 header": "column"} 으로 이름을 지정 */
+func mapImportColumns(c *gin.Context, records [][]string, fields []string, required string) ([]importRow, []string, *ApiError) {
+	mapping := map[string]string{}
+	value := c.Query("mapping")
+	if value == "" {
+		value = c.PostForm("mapping")
+	}
+	if value != "" {
+		err := json.Unmarshal([]byte(value), &mapping)
+		if err != nil {
+			return nil, nil, InvalidParameter("mapping", `mapping should be a json object like {"Name": "ename"}`)
+		}
+		for header, column := range mapping {
+			if !isImportColumn(column, fields) {
+				return nil, nil, InvalidParameter("mapping", fmt.Sprintf("%s: unknown column %s. Use one of %s", header, column, strings.Join(fields, ", ")))
+			}
+		}
+	}
+
+	columns := make([]string, len(records[0]))
+	ignored := []string{}
+	for i, header := range records[0] {
+		header = strings.TrimSpace(header)
+		column, ok := mapping[header]
+		if !ok {
+			column = strings.ToLower(header)
+		}
+		if isImportColumn(column, fields) {
+			columns[i] = column
+		} else if header != "" {
+			ignored = append(ignored, header)
+		}
+	}
+	if !containsString(columns, required) {
+		return nil, nil, fieldError(required, "required", "file should have a "+required+" column")
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		row := importRow{Line: i + 2, Values: map[string]string{}}
+		for j, value := range record {
+			value = strings.TrimSpace(value)
+			if j < len(columns) && columns[j] != "" && value != "" {
+				row.Values[columns[j]] = value
+			}
+		}
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, ignored, nil
+}
+
+func isImportColumn(column string, fields []string) bool {
+	if containsString(fields, column) {
+		return true
+	}
+	// attributes는 사원에서만 사용
+	return containsString(fields, "ename") && strings.HasPrefix(column, attributeColumnPrefix) && len(column) > len(attributeColumnPrefix)
+}
+
+/* 행을 eData로 바꿔 AddEmployee와 같은 검증, 생성 과정을 거침. dnames는 ; 로 구분 */
+func importEmployee(c *gin.Context, tx *gorm.DB, row importRow, result *importResult) {
+	values := row.Values
+	data := eData{
+		EName:          values["ename"],
+		DName:          values["dname"],
+		Email:          values["email"],
+		Phone:          values["phone"],
+		JobTitle:       values["job_title"],
+		EmploymentType: values["employment_type"],
+		Status:         values["status"],
+	}
+	for _, name := range strings.Split(values["dnames"], ";") {
+		if name = strings.TrimSpace(name); name != "" {
+			data.DNames = append(data.DNames, name)
+		}
+	}
+	if value := values["manager_id"]; value != "" {
+		managerID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			result.Error = fieldError("manager_id", "type", "manager_id should be a number")
+			return
+		}
+		data.ManagerID = uint(managerID)
+	}
+	for column, value := range values {
+		if strings.HasPrefix(column, attributeColumnPrefix) {
+			if data.Attributes == nil {
+				data.Attributes = JSONMap{}
+			}
+			data.Attributes[strings.TrimPrefix(column, attributeColumnPrefix)] = value
+		}
+	}
+
+	err := binding.Validator.ValidateStruct(&data)
+	if err != nil {
+		result.Error = BindError(err, "")
+		return
+	}
+
+	created := bulkResult{}
+	createEmployee(c, tx, data, &created)
+	result.ID = created.ID
+	result.Status = created.Status
+	result.Error = created.Error
+}
+
+/* 부서 하나를 추가. 이름이 겹치면 실패(삭제된 부서 포함) */
+func importDepartment(c *gin.Context, tx *gorm.DB, row importRow, result *importResult) {
+	name := row.Values["dname"]
+	if name == "" {
+		result.Error = fieldError("dname", "required", "dname is required")
+		return
+	}
+
+	var existing Department
+	tx.Unscoped().Where("Department_Name = ?", name).Find(&existing)
+	if existing.ID != 0 {
+		detail := name + ": Department already exists"
+		if existing.DeletedAt.Valid {
+			detail = name + ": Department is deleted. Restore or purge it"
+		}
+		result.Error = NewApiError(http.StatusConflict, CodeDepartmentExists, detail)
+		return
+	}
+
+	department := Department{Department_Name: name}
+	if parentName := row.Values["parent"]; parentName != "" {
+		parent, apiErr := findParentDepartment(tx, parentName)
+		if apiErr != nil {
+			result.Error = apiErr
+			return
+		}
+		department.ParentID = &parent.ID
+	}
+
+	err := tx.Create(&department).Error
+	if err == nil {
+		err = recordAudit(c, tx, AuditCreate, EntityDepartment, department.ID, nil, department)
+	}
+	if err != nil {
+		result.Error = internalError(c, err, name+": Create Fail!")
+		return
+	}
+
+	result.ID = department.ID
+	result.Status = http.StatusCreated
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func importTestRouter(t *testing.T) (*gin.Engine, string) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/import/employees", ImportEmployees)
+	router.POST("/api/import/departments", ImportDepartments)
+
+	t.Cleanup(func() {
+		var employees []Employee
+		db.Unscoped().Where("Employee_Name LIKE ?", "Import %").Find(&employees)
+		for _, employee := range employees {
+			db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+			db.Unscoped().Delete(&employee)
+		}
+		db.Unscoped().Where("Department_Name LIKE ?", "Import %").Order("id desc").Delete(&Department{})
+	})
+	return router, token
+}
+
+/* 파일을 multipart의 file로 보냄 */
+func importRequest(router *gin.Engine, token string, url string, filename string, data []byte) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write(data)
+	writer.Close()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", url, body)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, request)
+	return w
+}
+
+type importResponse struct {
+	DryRun  bool           `json:"dryRun"`
+	Valid   bool           `json:"valid"`
+	Rows    int            `json:"rows"`
+	Failed  int            `json:"failed"`
+	Ignored []string       `json:"ignored"`
+	Results []importResult `json:"results"`
+	Details struct {
+		Results []importResult `json:"results"`
+	} `json:"details"`
+}
+
+func decodeImport(t *testing.T, w *httptest.ResponseRecorder) importResponse {
+	var response importResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	return response
+}
+
+func TestImportDepartments(t *testing.T) {
+	router, token := importTestRouter(t)
+	mapping := `&mapping={"Name":"dname","Parent Dept":"parent"}`
+	csv := "Name,Parent Dept,Memo\nImport HQ,,head office\n\nImport Team,Import HQ,\n"
+
+	w := orgRequest(router, token, "POST", "/api/import/departments?dry_run=true"+mapping, csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeImport(t, w)
+	assert.True(t, response.DryRun)
+	assert.True(t, response.Valid)
+	assert.Equal(t, 2, response.Rows)
+	assert.Equal(t, []string{"Memo"}, response.Ignored)
+	assert.Equal(t, 4, response.Results[1].Row)
+
+	var count int64
+	db.Model(&Department{}).Where("Department_Name LIKE ?", "Import %").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = orgRequest(router, token, "POST", "/api/import/departments?"+mapping, csv)
+	assert.Equal(t, http.StatusOK, w.Code)
+	response = decodeImport(t, w)
+	assert.NotZero(t, response.Results[0].ID)
+
+	var team Department
+	db.Where("Department_Name = ?", "Import Team").Find(&team)
+	assert.Equal(t, response.Results[0].ID, *team.ParentID)
+
+	// 이미 있는 부서가 있으면 아무것도 만들지 않음
+	w = orgRequest(router, token, "POST", "/api/import/departments?"+mapping, "Name\nImport Other\nImport HQ\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	response = decodeImport(t, w)
+	assert.Equal(t, http.StatusFailedDependency, response.Details.Results[0].Status)
+	assert.Equal(t, http.StatusConflict, response.Details.Results[1].Status)
+	db.Model(&Department{}).Where("Department_Name = ?", "Import Other").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = orgRequest(router, token, "POST", "/api/import/departments?mapping={\"Name\":\"title\"}", "Name\nImport Other\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = orgRequest(router, token, "POST", "/api/import/departments", "Title\nImport Other\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = orgRequest(router, token, "POST", "/api/import/departments?format=json", "dname\nImport Other\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportEmployees(t *testing.T) {
+	router, token := importTestRouter(t)
+	db.Create(&Department{Department_Name: "Import Dept A"})
+	db.Create(&Department{Department_Name: "Import Dept B"})
+
+	csv := "\xef\xbb\xbfename,dnames,email,attributes.seat\n" +
+		"Import Kim,Import Dept B;Import Dept A,kim@example.com,3F\n" +
+		"Import Lee,Import Dept C,not-an-email,\n"
+
+	w := importRequest(router, token, "/api/import/employees?dry_run=true", "employees.csv", []byte(csv))
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeImport(t, w)
+	assert.False(t, response.Valid)
+	assert.Equal(t, 1, response.Failed)
+	assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+	assert.Zero(t, response.Results[0].ID)
+	assert.Equal(t, 3, response.Results[1].Row)
+	assert.Equal(t, "email", response.Results[1].Error.Errors[0].Field)
+
+	w = importRequest(router, token, "/api/import/employees", "employees.csv", []byte(csv))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var count int64
+	db.Model(&Employee{}).Where("Employee_Name LIKE ?", "Import %").Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// 부서 이름이 틀린 행
+	csv = "ename,dnames\nImport Lee,Import Dept C\n"
+	w = importRequest(router, token, "/api/import/employees?dry_run=true", "employees.csv", []byte(csv))
+	response = decodeImport(t, w)
+	assert.Equal(t, CodeDepartmentNotFound, response.Results[0].Error.Code)
+
+	csv = "ename,dnames,email,attributes.seat\nImport Kim,Import Dept B;Import Dept A,kim@example.com,3F\n"
+	w = importRequest(router, token, "/api/import/employees", "employees.csv", []byte(csv))
+	assert.Equal(t, http.StatusOK, w.Code)
+	response = decodeImport(t, w)
+
+	var employee Employee
+	db.Where("id = ?", response.Results[0].ID).Find(&employee)
+	assert.Equal(t, "kim@example.com", employee.Email)
+	assert.Equal(t, "3F", employee.Attributes["seat"])
+
+	var primary Assignment
+	db.Where("employee_id = ? AND is_primary = ?", employee.ID, true).Preload("Department").Find(&primary)
+	assert.Equal(t, "Import Dept B", primary.Department.Department_Name)
+	db.Model(&Assignment{}).Where("employee_id = ?", employee.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+/* 공유 문자열과 inline 문자열을 사용하는 최소한의 xlsx */
+func testXLSX() []byte {
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="People" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/people.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Name</t></si><si><t>Title</t></si><si><r><t>Import </t></r><r><t>Park</t></r></si></sst>`,
+		"xl/worksheets/people.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2" t="inlineStr"><is><t>Designer</t></is></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+
+	buf := &bytes.Buffer{}
+	writer := zip.NewWriter(buf)
+	for name, content := range files {
+		file, _ := writer.Create(name)
+		file.Write([]byte(content))
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestImportEmployeesXLSX(t *testing.T) {
+	router, token := importTestRouter(t)
+
+	records, err := readXLSX(testXLSX())
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Name", "", "Title"}, {"Import Park", "", "Designer"}}, records)
+
+	_, err = readXLSX([]byte("not a zip"))
+	assert.Error(t, err)
+
+	w := importRequest(router, token, `/api/import/employees?mapping={"Name":"ename","Title":"job_title"}`, "people.xlsx", testXLSX())
+	assert.Equal(t, http.StatusOK, w.Code)
+	response := decodeImport(t, w)
+
+	var employee Employee
+	db.Where("id = ?", response.Results[0].ID).Find(&employee)
+	assert.Equal(t, "Import Park", employee.Employee_Name)
+	assert.Equal(t, "Designer", employee.JobTitle)
+}
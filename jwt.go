@@ -7,24 +7,43 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+// 토큰 종류(typ claim). OAuth state도 같은 KeyRing으로 서명하므로 종류가 다른 토큰은 받지 않음
+const (
+	tokenTypeAccess     = "access"
+	tokenTypeOAuthState = "oauth_state"
+)
+
 type JwtClaim struct {
 	Email string
 	CA    string
+	Role  string
+	Type  string `json:"typ"`
 	jwt.StandardClaims
 }
 
 /* 로그인 후 사용할 JWT 토큰을 생성함 */
-func GenerateToken(Email string, CA string) (signedToken string, err error) {
-	claims := &JwtClaim{ // Account ID와 만료에 대한 정보를 담고 있음
+func GenerateToken(Email string, CA string, Role string) (signedToken string, err error) {
+	jti, err := newRandomToken(16) // 로그아웃 시 토큰을 무효화하기 위한 ID
+	if err != nil {
+		return
+	}
+
+	claims := &JwtClaim{ // Account ID, 권한과 만료에 대한 정보를 담고 있음
 		Email: Email,
 		CA:    CA,
+		Role:  Role,
+		Type:  tokenTypeAccess,
 		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
 			ExpiresAt: time.Now().Local().Add(60 * time.Minute).Unix(), // 60분 뒤 만료
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)   // token 생성
-	signedToken, err = token.SignedString([]byte("SecreteCode")) // Secretecode는 토큰 자체 인증키(자체 비밀번호 느낌)
+	ring, err := currentKeyRing()
+	if err != nil {
+		return
+	}
+	signedToken, err = ring.sign(claims) // KeyRing의 현재 키로 서명하고 header에 kid를 남김
 
 	if err != nil {
 		return
@@ -34,12 +53,15 @@ func GenerateToken(Email string, CA string) (signedToken string, err error) {
 
 /* JWT 토큰 검증 */
 func ValidateToken(signedToken string) (claims *JwtClaim, err error) {
+	ring, err := currentKeyRing()
+	if err != nil {
+		return
+	}
+
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&JwtClaim{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte("SecreteCode"), nil
-		},
+		ring.verifyKeyFunc, // header의 kid로 키를 찾음. 교체 전 키로 만든 토큰도 검증 가능
 	)
 
 	if err != nil {
@@ -52,6 +74,11 @@ func ValidateToken(signedToken string) (claims *JwtClaim, err error) {
 		return
 	}
 
+	if claims.Type != tokenTypeAccess { // OAuth state 등 다른 용도의 토큰
+		err = errors.New("jwt is not an access token")
+		return
+	}
+
 	if claims.ExpiresAt < time.Now().Local().Unix() {
 		err = errors.New("jwt is expired")
 		return
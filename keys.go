@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// JWT 서명 키. signKey가 nil이면 검증에만 사용 (교체 후 남겨둔 예전 공개키)
+type JwtKey struct {
+	Kid       string
+	Alg       string
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// 서명에 사용하는 현재 키와 검증에 사용하는 모든 키
+type KeyRing struct {
+	current *JwtKey
+	keys    map[string]*JwtKey
+}
+
+var jwtKeys *KeyRing
+
+// 서버 시작 전에 InitKeyRing을 하지 않았을 때 한 번만 생성하도록
+var (
+	keyRingOnce sync.Once
+	keyRingErr  error
+)
+
+// 환경변수로 KeyRing을 생성
+//   - JWT_KEY_DIR: <kid>.pem (RSA/EC 개인키 또는 공개키), <kid>.secret (HS256) 파일이 있는 폴더
+//   - JWT_SECRET: HS256 키 (kid = default)
+//   - JWT_CURRENT_KID: 새 토큰 서명에 사용할 kid
+func InitKeyRing() (err error) {
+	ring, err := LoadKeyRing(os.Getenv("JWT_KEY_DIR"), os.Getenv("JWT_SECRET"), os.Getenv("JWT_CURRENT_KID"))
+	if err != nil {
+		return
+	}
+
+	jwtKeys = ring
+	return
+}
+
+/* 키 파일과 secret으로 KeyRing 생성. 아무 키도 없으면 실행 중에만 쓰는 임의의 HS256 키 사용 */
+func LoadKeyRing(dir string, secret string, currentKid string) (ring *KeyRing, err error) {
+	ring = &KeyRing{keys: make(map[string]*JwtKey)}
+
+	if secret != "" {
+		ring.keys["default"] = &JwtKey{Kid: "default", Alg: "HS256", signKey: []byte(secret), verifyKey: []byte(secret)}
+	}
+
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			key, err := loadKeyFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if key != nil {
+				ring.keys[key.Kid] = key
+			}
+		}
+	}
+
+	if len(ring.keys) == 0 {
+		log.Println("No JWT key configured. Using a random key (tokens are invalid after restart)")
+		random := make([]byte, 32)
+		if _, err = rand.Read(random); err != nil {
+			return
+		}
+		ring.keys["random"] = &JwtKey{Kid: "random", Alg: "HS256", signKey: random, verifyKey: random}
+	}
+
+	if currentKid == "" {
+		// 서명 가능한 키가 하나뿐이면 그 키를 사용
+		for _, key := range ring.keys {
+			if key.signKey == nil {
+				continue
+			}
+			if currentKid != "" {
+				return nil, errors.New("JWT_CURRENT_KID is required when there are several signing keys")
+			}
+			currentKid = key.Kid
+		}
+	}
+
+	ring.current = ring.keys[currentKid]
+	if ring.current == nil || ring.current.signKey == nil {
+		return nil, fmt.Errorf("no signing key for kid: %s", currentKid)
+	}
+	return
+}
+
+/* 파일 이름(확장자 제외)이 kid. 알고리즘은 키 종류로 결정 */
+func loadKeyFile(path string) (*JwtKey, error) {
+	ext := filepath.Ext(path)
+	kid := strings.TrimSuffix(filepath.Base(path), ext)
+	if ext != ".pem" && ext != ".secret" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext == ".secret" {
+		secret := []byte(strings.TrimSpace(string(data)))
+		return &JwtKey{Kid: kid, Alg: "HS256", signKey: secret, verifyKey: secret}, nil
+	}
+
+	parsed, err := parsePEMKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &JwtKey{Kid: kid, Alg: "RS256", signKey: key, verifyKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &JwtKey{Kid: kid, Alg: "RS256", verifyKey: key}, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s: only P-256 EC keys are supported", path)
+		}
+		return &JwtKey{Kid: kid, Alg: "ES256", signKey: key, verifyKey: &key.PublicKey}, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s: only P-256 EC keys are supported", path)
+		}
+		return &JwtKey{Kid: kid, Alg: "ES256", verifyKey: key}, nil
+	}
+
+	return nil, fmt.Errorf("%s: unsupported key type", path)
+}
+
+/* PKCS#1, PKCS#8, SEC1 개인키와 PKIX, PKCS#1 공개키 PEM 지원 */
+func parsePEMKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("unsupported key format")
+}
+
+/* kid로 검증 키를 찾음. 토큰의 alg가 키의 alg와 다르면 거부 */
+func (ring *KeyRing) verifyKeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ring.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+	if token.Method.Alg() != key.Alg {
+		return nil, fmt.Errorf("unexpected signing method: %s", token.Method.Alg())
+	}
+	return key.verifyKey, nil
+}
+
+/* 현재 키로 서명 */
+func (ring *KeyRing) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(ring.current.Alg), claims)
+	token.Header["kid"] = ring.current.Kid
+	return token.SignedString(ring.current.signKey)
+}
+
+/* 공개키(RS256, ES256)만 JWK 형식으로 반환. HS256 키는 공개하지 않음 */
+func (ring *KeyRing) JWKS() []gin.H {
+	kids := make([]string, 0, len(ring.keys))
+	for kid := range ring.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := make([]gin.H, 0, len(kids))
+	for _, kid := range kids {
+		key := ring.keys[kid]
+		switch pub := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, gin.H{
+				"kty": "RSA",
+				"kid": key.Kid,
+				"use": "sig",
+				"alg": key.Alg,
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwks = append(jwks, gin.H{
+				"kty": "EC",
+				"kid": key.Kid,
+				"use": "sig",
+				"alg": key.Alg,
+				"crv": pub.Curve.Params().Name,
+				"x":   base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size))),
+				"y":   base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size))),
+			})
+		}
+	}
+	return jwks
+}
+
+/* KeyRing이 아직 없으면 환경변수로 생성. 동시에 요청이 와도 임의의 키는 하나만 만들어짐 */
+func currentKeyRing() (*KeyRing, error) {
+	keyRingOnce.Do(func() {
+		if jwtKeys == nil {
+			keyRingErr = InitKeyRing()
+		}
+	})
+	if jwtKeys == nil {
+		return nil, keyRingErr
+	}
+	return jwtKeys, nil
+}
+
+/* 다른 서비스에서 myapi 토큰을 검증할 수 있도록 공개키 제공 */
+func ReadJWKS(c *gin.Context) {
+	ring, err := currentKeyRing()
+	if err != nil {
+		AbortWithInternalError(c, err, "Cannot load JWT keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keys": ring.JWKS(),
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+/* 테스트용 RSA, EC 키 파일 생성 */
+func writeTestKeys(t *testing.T) string {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "rsa-1.pem"), rsaPEM, 0600))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.NoError(t, err)
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ec-2.pem"), ecPEM, 0600))
+
+	return dir
+}
+
+/* 테스트 동안만 jwtKeys를 바꿔서 사용 */
+func useKeyRing(t *testing.T, ring *KeyRing) {
+	prev := jwtKeys
+	jwtKeys = ring
+	t.Cleanup(func() { jwtKeys = prev })
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	dir := writeTestKeys(t)
+
+	ring, err := LoadKeyRing(dir, "", "rsa-1")
+	assert.NoError(t, err)
+	useKeyRing(t, ring)
+
+	oldToken, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	// 새 키로 교체해도 예전 토큰은 검증 가능
+	ring, err = LoadKeyRing(dir, "", "ec-2")
+	assert.NoError(t, err)
+	useKeyRing(t, ring)
+
+	newToken, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	claims, err := ValidateToken(oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "gotest", claims.Email)
+
+	token, _, err := new(jwt.Parser).ParseUnverified(newToken, &JwtClaim{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ES256", token.Method.Alg())
+	assert.Equal(t, "ec-2", token.Header["kid"])
+
+	_, err = ValidateToken(newToken)
+	assert.NoError(t, err)
+}
+
+func TestKeyRingRequiresCurrentKid(t *testing.T) {
+	dir := writeTestKeys(t)
+
+	_, err := LoadKeyRing(dir, "", "")
+	assert.Error(t, err)
+
+	_, err = LoadKeyRing(dir, "", "no-such-kid")
+	assert.Error(t, err)
+}
+
+func TestCurrentKeyRingOnce(t *testing.T) {
+	useKeyRing(t, nil)
+	keyRingOnce = sync.Once{}
+	t.Setenv("JWT_KEY_DIR", "")
+	t.Setenv("JWT_SECRET", "")
+
+	// 동시에 처음 요청이 와도 같은 임의의 키를 사용
+	rings := make([]*KeyRing, 8)
+	var wg sync.WaitGroup
+	for i := range rings {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ring, err := currentKeyRing()
+			assert.NoError(t, err)
+			rings[i] = ring
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NotNil(t, rings[0])
+	for _, ring := range rings {
+		assert.Same(t, rings[0], ring)
+	}
+}
+
+func TestValidateTokenUnknownKid(t *testing.T) {
+	ring, err := LoadKeyRing("", "first secret", "")
+	assert.NoError(t, err)
+	useKeyRing(t, ring)
+
+	token, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	// 같은 kid라도 다른 secret이면 실패, 모르는 kid도 실패
+	ring, err = LoadKeyRing("", "second secret", "")
+	assert.NoError(t, err)
+	useKeyRing(t, ring)
+	_, err = ValidateToken(token)
+	assert.Error(t, err)
+
+	ring, err = LoadKeyRing(writeTestKeys(t), "", "rsa-1")
+	assert.NoError(t, err)
+	useKeyRing(t, ring)
+	_, err = ValidateToken(token)
+	assert.Error(t, err)
+}
+
+func TestReadJWKS(t *testing.T) {
+	ring, err := LoadKeyRing(writeTestKeys(t), "hs secret", "rsa-1")
+	assert.NoError(t, err)
+	useKeyRing(t, ring)
+
+	router := gin.Default()
+	router.GET("/.well-known/jwks.json", ReadJWKS)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+	router.ServeHTTP(w, request)
+
+	var result struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, len(result.Keys)) // HS256 키는 공개하지 않음
+	assert.Equal(t, "ec-2", result.Keys[0]["kid"])
+	assert.Equal(t, "P-256", result.Keys[0]["crv"])
+	assert.Equal(t, "rsa-1", result.Keys[1]["kid"])
+	assert.Equal(t, "AQAB", result.Keys[1]["e"])
+}
@@ -13,14 +13,47 @@ var db *gorm.DB
 var err error
 
 func main() {
-	err := InitDB()
+	// .env가 없으면 환경변수만 사용 (DB_DRIVER, DB_DSN, PORT ...)
+	err := godotenv.Load(".env")
+	if err != nil {
+		log.Println("No .env file. Using environment variables only")
+	}
+
+	err = InitDB()
 	if err != nil {
 		log.Println(err.Error())
 		panic("DB init error")
 	}
-	err = godotenv.Load(".env")
+
+	// myapi migrate [up|down [n]|status]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = RunMigrateCommand(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// myapi purge [days]
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		err = RunPurgeCommand(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// AUTO_MIGRATE=false 이면 서버 시작시 Migration을 적용하지 않음
+	if os.Getenv("AUTO_MIGRATE") != "false" {
+		err = MigrateUp(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = InitKeyRing()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatal(err)
 	}
 
 	r := SetupRouter()
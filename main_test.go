@@ -1 +1,45 @@
 package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+/* 테스트용 DB 세팅. DB_DRIVER가 없으면 임시 폴더의 SQLite 파일을 사용 */
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	var tmpDir string
+	if os.Getenv("DB_DRIVER") == "" {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "myapi-test")
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Setenv("DB_DRIVER", "sqlite")
+		os.Setenv("DB_DSN", filepath.Join(tmpDir, "test.db"))
+	}
+
+	err := InitDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = MigrateUp(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = InitKeyRing()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	if tmpDir != "" {
+		os.RemoveAll(tmpDir)
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 하나의 스키마 변경 단위. Version 순서대로 Up, 역순으로 Down 실행
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// 적용된 Migration 기록 Table
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migrate status 출력용
+type MigrationState struct {
+	Version   uint       `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+/* 등록된 Migration을 Version 순서로 정렬해서 반환 */
+func sortedMigrations() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
+
+/* 적용된 Migration 기록을 Version을 key로 가져옴 */
+func appliedMigrations(conn *gorm.DB) (applied map[uint]SchemaMigration, err error) {
+	err = conn.AutoMigrate(&SchemaMigration{})
+	if err != nil {
+		return
+	}
+
+	var records []SchemaMigration
+	err = conn.Find(&records).Error
+	if err != nil {
+		return
+	}
+
+	applied = make(map[uint]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return
+}
+
+/* 아직 적용되지 않은 Migration을 모두 적용 */
+func MigrateUp(conn *gorm.DB) error {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range sortedMigrations() {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		err = conn.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) up: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migration %d (%s) applied", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+/* 마지막으로 적용된 Migration부터 steps개를 되돌림 */
+func MigrateDown(conn *gorm.DB, steps int) error {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return err
+	}
+
+	sorted := sortedMigrations()
+	for i := len(sorted) - 1; i >= 0 && steps > 0; i-- {
+		m := sorted[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		err = conn.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) down: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migration %d (%s) rolled back", m.Version, m.Name)
+		steps--
+	}
+
+	return nil
+}
+
+/* 등록된 모든 Migration의 적용 여부 */
+func MigrationStatus(conn *gorm.DB) (states []MigrationState, err error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return
+	}
+
+	for _, m := range sortedMigrations() {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &record.AppliedAt
+		}
+		states = append(states, state)
+	}
+	return
+}
+
+/* `myapi migrate [up|down [n]|status]` 서브커맨드 처리 */
+func RunMigrateCommand(args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		return MigrateUp(db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return errors.New("migrate down: steps should be a positive number")
+			}
+			steps = n
+		}
+		return MigrateDown(db, steps)
+	case "status":
+		states, err := MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			status := "pending"
+			if state.Applied {
+				status = "applied " + state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d %-30s %s\n", state.Version, state.Name, status)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown migrate command: %s (use up, down [n], status)", command)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+/* 다른 테스트와 겹치지 않도록 Migration 테스트는 별도의 DB 사용 */
+func openMigrateTestDB(t *testing.T) *gorm.DB {
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{})
+	assert.NoError(t, err)
+	return conn
+}
+
+func TestMigrateUpAndStatus(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	err := MigrateUp(conn)
+	assert.NoError(t, err)
+	assert.True(t, conn.Migrator().HasTable("employees"))
+	assert.True(t, conn.Migrator().HasTable("employee_departments"))
+
+	// 두 번 실행해도 이미 적용된 Migration은 건너뜀
+	err = MigrateUp(conn)
+	assert.NoError(t, err)
+
+	states, err := MigrationStatus(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, len(migrations), len(states))
+	for _, state := range states {
+		assert.True(t, state.Applied)
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	err := MigrateUp(conn)
+	assert.NoError(t, err)
+
+	err = MigrateDown(conn, len(migrations))
+	assert.NoError(t, err)
+	assert.False(t, conn.Migrator().HasTable("employees"))
+
+	states, err := MigrationStatus(conn)
+	assert.NoError(t, err)
+	for _, state := range states {
+		assert.False(t, state.Applied)
+	}
+}
+
+func TestMigrateUpExistingTables(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	// 예전 /init 으로 만들어진 Table이 있는 경우
+	err := conn.AutoMigrate(&accountV1{}, &departmentV1{}, &employeeV1{}, &employeeDepartmentV1{})
+	assert.NoError(t, err)
+
+	err = MigrateUp(conn)
+	assert.NoError(t, err)
+}
+
+func TestMigrateEmployeeProfile(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	err := MigrateUp(conn)
+	assert.NoError(t, err)
+	err = MigrateDown(conn, 1)
+	assert.NoError(t, err)
+
+	// 0005 이전에 있던 사원
+	err = conn.Create(&employeeV1{Employee_Name: "Old Employee"}).Error
+	assert.NoError(t, err)
+
+	err = MigrateUp(conn)
+	assert.NoError(t, err)
+
+	var employee Employee
+	conn.Where("Employee_Name = ?", "Old Employee").Find(&employee)
+	assert.Equal(t, StatusActive, employee.Status)
+	assert.Equal(t, EmploymentFullTime, employee.EmploymentType)
+	assert.Nil(t, employee.ManagerID)
+}
+
+func TestMigrateAssignments(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	err := MigrateUp(conn)
+	assert.NoError(t, err)
+	err = MigrateDown(conn, len(migrations)-6) // 0007 이후를 모두 되돌림
+	assert.NoError(t, err)
+
+	// 0007 이전의 소속
+	employee := employeeV1{Employee_Name: "Old Employee"}
+	conn.Create(&employee)
+	departments := []departmentV1{{Department_Name: "Old A"}, {Department_Name: "Old B"}}
+	conn.Create(&departments)
+	for _, department := range departments {
+		err = conn.Create(&employeeDepartmentV1{EmployeeID: employee.ID, DepartmentID: department.ID}).Error
+		assert.NoError(t, err)
+	}
+
+	err = MigrateUp(conn)
+	assert.NoError(t, err)
+
+	var assignments []Assignment
+	conn.Where("employee_id = ?", employee.ID).Order("id").Find(&assignments)
+	if assert.Equal(t, 2, len(assignments)) {
+		assert.True(t, assignments[0].IsPrimary)
+		assert.False(t, assignments[1].IsPrimary)
+		assert.Equal(t, 100, assignments[0].Allocation)
+		assert.False(t, assignments[0].StartDate.IsZero())
+	}
+
+	// 끝난 소속은 되돌리지 않음
+	conn.Model(&assignments[1]).Update("end_date", time.Now())
+	err = MigrateDown(conn, len(migrations)-6)
+	assert.NoError(t, err)
+	var count int64
+	conn.Model(&employeeDepartmentV1{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMigrateEmployeeHistories(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	err := MigrateUp(conn)
+	assert.NoError(t, err)
+	err = MigrateDown(conn, len(migrations)-7)
+	assert.NoError(t, err)
+
+	// 0008 이전에 있던 사원은 입사일에 만들어진 기록이 생김
+	employee := employeeV1{Employee_Name: "Old Employee", EntryTime: time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)}
+	conn.Create(&employee)
+
+	err = MigrateUp(conn)
+	assert.NoError(t, err)
+
+	var histories []EmployeeHistory
+	conn.Where("employee_id = ?", employee.ID).Find(&histories)
+	if assert.Equal(t, 1, len(histories)) {
+		assert.Equal(t, HistoryCreated, histories[0].Action)
+		assert.Equal(t, "Old Employee", histories[0].Employee_Name)
+		assert.True(t, employee.EntryTime.Equal(histories[0].ChangedAt))
+	}
+}
+
+func TestMigrateEmployeeSearch(t *testing.T) {
+	conn := openMigrateTestDB(t)
+
+	err := MigrateUp(conn)
+	assert.NoError(t, err)
+	err = MigrateDown(conn, len(migrations)-10)
+	assert.NoError(t, err)
+
+	// 0011 이전에 있던 사원도 색인
+	employee := employeeV1{Employee_Name: "José Müller"}
+	conn.Create(&employee)
+
+	err = MigrateUp(conn)
+	assert.NoError(t, err)
+
+	var search employeeSearchV11
+	conn.Where("employee_id = ?", employee.ID).Find(&search)
+	assert.Equal(t, "jose muller", search.Name)
+	var count int64
+	conn.Model(&employeeTrigramV11{}).Where("employee_id = ? AND trigram = ?", employee.ID, " mu").Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 스키마 변경 목록. 새로운 변경은 Version을 1씩 올려서 맨 뒤에 추가한다.
+// Migration 안에서는 현재 Model이 아닌 그 시점의 구조체를 사용해야
+// Model이 바뀌어도 예전 Migration의 결과가 달라지지 않는다.
+var migrations = []Migration{
+	{Version: 1, Name: "create_initial_tables", Up: upInitialTables, Down: downInitialTables},
+	{Version: 2, Name: "add_account_role", Up: upAccountRole, Down: downAccountRole},
+	{Version: 3, Name: "create_session_tables", Up: upSessionTables, Down: downSessionTables},
+	{Version: 4, Name: "add_local_accounts_and_api_keys", Up: upLocalAccounts, Down: downLocalAccounts},
+	{Version: 5, Name: "add_employee_profile", Up: upEmployeeProfile, Down: downEmployeeProfile},
+	{Version: 6, Name: "add_department_parent", Up: upDepartmentParent, Down: downDepartmentParent},
+	{Version: 7, Name: "create_assignments", Up: upAssignments, Down: downAssignments},
+	{Version: 8, Name: "create_employee_histories", Up: upEmployeeHistories, Down: downEmployeeHistories},
+	{Version: 9, Name: "create_audit_logs", Up: upAuditLogs, Down: downAuditLogs},
+	{Version: 10, Name: "add_soft_delete", Up: upSoftDelete, Down: downSoftDelete},
+	{Version: 11, Name: "create_employee_search", Up: upEmployeeSearch, Down: downEmployeeSearch},
+}
+
+/* 0001: Account, Department, Employee, employee_departments */
+type accountV1 struct {
+	gorm.Model
+	Email string
+	CA    string
+}
+
+func (accountV1) TableName() string { return "accounts" }
+
+type departmentV1 struct {
+	ID              uint   `gorm:"primaryKey"`
+	Department_Name string `gorm:"unique"`
+}
+
+func (departmentV1) TableName() string { return "departments" }
+
+type employeeV1 struct {
+	ID            uint      `gorm:"primaryKey"`
+	EntryTime     time.Time `gorm:"autoCreateTime"`
+	Employee_Name string
+}
+
+func (employeeV1) TableName() string { return "employees" }
+
+type employeeDepartmentV1 struct {
+	EmployeeID   uint `gorm:"primaryKey;autoIncrement:false"`
+	DepartmentID uint `gorm:"primaryKey;autoIncrement:false"`
+	Employee     employeeV1
+	Department   departmentV1
+}
+
+func (employeeDepartmentV1) TableName() string { return "employee_departments" }
+
+// 예전에 /init으로 만든 Table이 있어도 AutoMigrate라 그대로 사용 가능
+func upInitialTables(tx *gorm.DB) error {
+	return tx.AutoMigrate(&accountV1{}, &departmentV1{}, &employeeV1{}, &employeeDepartmentV1{})
+}
+
+func downInitialTables(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&employeeDepartmentV1{}, &employeeV1{}, &departmentV1{}, &accountV1{})
+}
+
+/* 0002: Account.Role. 기존 계정은 viewer */
+type accountV2 struct {
+	Role string `gorm:"size:32;default:viewer"`
+}
+
+func (accountV2) TableName() string { return "accounts" }
+
+func upAccountRole(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&accountV2{}, "Role")
+}
+
+func downAccountRole(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&accountV2{}, "Role")
+}
+
+/* 0003: Refresh Token, 로그아웃된 Access Token 목록 */
+type refreshTokenV3 struct {
+	ID         uint   `gorm:"primaryKey"`
+	AccountID  uint   `gorm:"index"`
+	TokenHash  string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy uint
+	CreatedAt  time.Time
+}
+
+func (refreshTokenV3) TableName() string { return "refresh_tokens" }
+
+type revokedTokenV3 struct {
+	Jti       string `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time
+}
+
+func (revokedTokenV3) TableName() string { return "revoked_tokens" }
+
+func upSessionTables(tx *gorm.DB) error {
+	return tx.AutoMigrate(&refreshTokenV3{}, &revokedTokenV3{})
+}
+
+func downSessionTables(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&refreshTokenV3{}, &revokedTokenV3{})
+}
+
+/* 0004: 아이디/비밀번호 계정, API Key */
+type accountV4 struct {
+	PasswordHash string
+}
+
+func (accountV4) TableName() string { return "accounts" }
+
+type apiKeyV4 struct {
+	ID         uint `gorm:"primaryKey"`
+	AccountID  uint `gorm:"index"`
+	Name       string
+	Prefix     string `gorm:"size:16"`
+	KeyHash    string `gorm:"size:64;uniqueIndex"`
+	Role       string `gorm:"size:32"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (apiKeyV4) TableName() string { return "api_keys" }
+
+func upLocalAccounts(tx *gorm.DB) error {
+	err := tx.Migrator().AddColumn(&accountV4{}, "PasswordHash")
+	if err != nil {
+		return err
+	}
+	return tx.AutoMigrate(&apiKeyV4{})
+}
+
+func downLocalAccounts(tx *gorm.DB) error {
+	err := tx.Migrator().DropTable(&apiKeyV4{})
+	if err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&accountV4{}, "PasswordHash")
+}
+
+/* 0005: 사원 연락처, 직책, 고용 형태, 상태, 상사, 추가 항목 */
+type employeeV5 struct {
+	Email          string `gorm:"size:255;index"`
+	Phone          string `gorm:"size:32"`
+	JobTitle       string `gorm:"size:128"`
+	EmploymentType string `gorm:"size:32;default:full-time"`
+	Status         string `gorm:"size:32;default:active;index"`
+	ManagerID      *uint  `gorm:"index"`
+	Attributes     JSONMap
+}
+
+func (employeeV5) TableName() string { return "employees" }
+
+var employeeV5Columns = []string{"Email", "Phone", "JobTitle", "EmploymentType", "Status", "ManagerID", "Attributes"}
+
+func upEmployeeProfile(tx *gorm.DB) error {
+	for _, column := range employeeV5Columns {
+		err := tx.Migrator().AddColumn(&employeeV5{}, column)
+		if err != nil {
+			return err
+		}
+	}
+	for _, index := range []string{"Email", "Status", "ManagerID"} {
+		err := tx.Migrator().CreateIndex(&employeeV5{}, index)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 기존 사원은 정규직, 재직 중으로 간주
+	return tx.Model(&employeeV5{}).Where("status IS NULL OR status = ''").Updates(map[string]interface{}{
+		"employment_type": "full-time",
+		"status":          "active",
+	}).Error
+}
+
+func downEmployeeProfile(tx *gorm.DB) error {
+	for _, index := range []string{"Email", "Status", "ManagerID"} {
+		err := tx.Migrator().DropIndex(&employeeV5{}, index)
+		if err != nil {
+			return err
+		}
+	}
+	for i := len(employeeV5Columns) - 1; i >= 0; i-- {
+		err := tx.Migrator().DropColumn(&employeeV5{}, employeeV5Columns[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* 0006: 상위 부서 */
+type departmentV6 struct {
+	ParentID *uint `gorm:"index"`
+}
+
+func (departmentV6) TableName() string { return "departments" }
+
+func upDepartmentParent(tx *gorm.DB) error {
+	err := tx.Migrator().AddColumn(&departmentV6{}, "ParentID")
+	if err != nil {
+		return err
+	}
+	return tx.Migrator().CreateIndex(&departmentV6{}, "ParentID")
+}
+
+func downDepartmentParent(tx *gorm.DB) error {
+	err := tx.Migrator().DropIndex(&departmentV6{}, "ParentID")
+	if err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&departmentV6{}, "ParentID")
+}
+
+/* 0007: employee_departments에 소속 정보(역할, 비중, 주 소속, 기간)와 자체 ID 추가 */
+type assignmentV7 struct {
+	ID           uint   `gorm:"primaryKey"`
+	EmployeeID   uint   `gorm:"index"`
+	DepartmentID uint   `gorm:"index"`
+	Role         string `gorm:"size:64"`
+	Allocation   int    `gorm:"default:100"`
+	IsPrimary    bool
+	StartDate    time.Time
+	EndDate      *time.Time `gorm:"index"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (assignmentV7) TableName() string { return "employee_departments" }
+
+// 기본 키가 바뀌므로 새 Table을 만들어 옮김
+func upAssignments(tx *gorm.DB) error {
+	err := tx.Migrator().RenameTable("employee_departments", "employee_departments_v6")
+	if err != nil {
+		return err
+	}
+	err = tx.AutoMigrate(&assignmentV7{})
+	if err != nil {
+		return err
+	}
+
+	// 기존 소속은 입사일부터 시작한 것으로 간주
+	now := time.Now()
+	err = tx.Exec(`INSERT INTO employee_departments (employee_id, department_id, role, allocation, is_primary, start_date, created_at, updated_at)
+		SELECT ed.employee_id, ed.department_id, '', 100, ?, e.entry_time, ?, ?
+		FROM employee_departments_v6 ed JOIN employees e ON e.id = ed.employee_id
+		ORDER BY ed.employee_id, ed.department_id`, false, now, now).Error
+	if err != nil {
+		return err
+	}
+
+	// 사원마다 첫 소속이 주 소속. MySQL은 같은 Table을 바로 subquery로 쓸 수 없어서 한 번 더 감쌈
+	err = tx.Exec(`UPDATE employee_departments SET is_primary = ?
+		WHERE id IN (SELECT id FROM (SELECT MIN(id) AS id FROM employee_departments GROUP BY employee_id) AS firsts)`, true).Error
+	if err != nil {
+		return err
+	}
+	return tx.Migrator().DropTable("employee_departments_v6")
+}
+
+// 끝난 소속은 버리고 현재 소속만 되돌림
+func downAssignments(tx *gorm.DB) error {
+	err := tx.Migrator().RenameTable("employee_departments", "employee_departments_v7")
+	if err != nil {
+		return err
+	}
+	err = tx.AutoMigrate(&employeeDepartmentV1{})
+	if err != nil {
+		return err
+	}
+	err = tx.Exec(`INSERT INTO employee_departments (employee_id, department_id)
+		SELECT DISTINCT employee_id, department_id FROM employee_departments_v7 WHERE end_date IS NULL`).Error
+	if err != nil {
+		return err
+	}
+	return tx.Migrator().DropTable("employee_departments_v7")
+}
+
+/* 0008: 사원 정보 변경 이력 */
+type employeeHistoryV8 struct {
+	ID             uint      `gorm:"primaryKey"`
+	EmployeeID     uint      `gorm:"index"`
+	Action         string    `gorm:"size:16"`
+	ChangedAt      time.Time `gorm:"index"`
+	Changes        JSONMap
+	EntryTime      time.Time
+	Employee_Name  string
+	Email          string `gorm:"size:255"`
+	Phone          string `gorm:"size:32"`
+	JobTitle       string `gorm:"size:128"`
+	EmploymentType string `gorm:"size:32"`
+	Status         string `gorm:"size:32"`
+	ManagerID      *uint
+	Attributes     JSONMap
+}
+
+func (employeeHistoryV8) TableName() string { return "employee_histories" }
+
+// 기존 사원은 입사일에 지금 정보로 만들어진 것으로 간주
+func upEmployeeHistories(tx *gorm.DB) error {
+	err := tx.AutoMigrate(&employeeHistoryV8{})
+	if err != nil {
+		return err
+	}
+	return tx.Exec(`INSERT INTO employee_histories (employee_id, action, changed_at, entry_time, employee_name, email, phone, job_title, employment_type, status, manager_id, attributes)
+		SELECT id, ?, entry_time, entry_time, employee_name, email, phone, job_title, employment_type, status, manager_id, attributes
+		FROM employees ORDER BY id`, "created").Error
+}
+
+func downEmployeeHistories(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&employeeHistoryV8{})
+}
+
+/* 0009: 감사 기록 */
+type auditLogV9 struct {
+	ID         uint      `gorm:"primaryKey"`
+	CreatedAt  time.Time `gorm:"index"`
+	ActorEmail string    `gorm:"size:255;index"`
+	ActorCA    string    `gorm:"size:32"`
+	Action     string    `gorm:"size:16"`
+	EntityType string    `gorm:"size:32;index:idx_audit_logs_entity"`
+	EntityID   uint      `gorm:"index:idx_audit_logs_entity"`
+	Before     JSONMap
+	After      JSONMap
+	Changes    JSONMap
+	RequestID  string `gorm:"size:64"`
+}
+
+func (auditLogV9) TableName() string { return "audit_logs" }
+
+func upAuditLogs(tx *gorm.DB) error {
+	return tx.AutoMigrate(&auditLogV9{})
+}
+
+func downAuditLogs(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&auditLogV9{})
+}
+
+/* 0010: 사원, 부서 soft delete */
+type employeeV10 struct {
+	ID        uint       `gorm:"primaryKey"`
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (employeeV10) TableName() string { return "employees" }
+
+type departmentV10 struct {
+	ID        uint       `gorm:"primaryKey"`
+	DeletedAt *time.Time `gorm:"index"`
+}
+
+func (departmentV10) TableName() string { return "departments" }
+
+func upSoftDelete(tx *gorm.DB) error {
+	for _, model := range []interface{}{&employeeV10{}, &departmentV10{}} {
+		err := tx.Migrator().AddColumn(model, "DeletedAt")
+		if err != nil {
+			return err
+		}
+		err = tx.Migrator().CreateIndex(model, "DeletedAt")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 되돌리면 삭제된 Row가 다시 보이므로 삭제된 사원, 부서와 그 소속은 완전히 지움
+func downSoftDelete(tx *gorm.DB) error {
+	err := tx.Exec(`DELETE FROM employee_departments
+		WHERE employee_id IN (SELECT id FROM employees WHERE deleted_at IS NOT NULL)
+		OR department_id IN (SELECT id FROM departments WHERE deleted_at IS NOT NULL)`).Error
+	if err != nil {
+		return err
+	}
+
+	for table, model := range map[string]interface{}{"employees": &employeeV10{}, "departments": &departmentV10{}} {
+		err = tx.Where("deleted_at IS NOT NULL").Delete(model).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Migrator().DropIndex(model, "DeletedAt")
+		if err != nil {
+			return err
+		}
+		// sqlite의 Migrator().DropColumn은 Table을 다시 만들면서 다른 index를 잃어버림
+		err = tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: table}, clause.Column{Name: "deleted_at"}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* 0011: 사원 검색 색인. 기존 사원(삭제된 사원 포함)을 색인 */
+type employeeSearchV11 struct {
+	EmployeeID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name       string `gorm:"size:255"`
+	Email      string `gorm:"size:255"`
+	JobTitle   string `gorm:"size:128"`
+	Phone      string `gorm:"size:32"`
+}
+
+func (employeeSearchV11) TableName() string { return "employee_search" }
+
+type employeeTrigramV11 struct {
+	ID         uint   `gorm:"primaryKey"`
+	EmployeeID uint   `gorm:"index"`
+	Trigram    string `gorm:"size:16;index"`
+}
+
+func (employeeTrigramV11) TableName() string { return "employee_trigrams" }
+
+// 색인할 때 읽는 사원 column. deleted_at이 없으므로 삭제된 사원도 포함
+type employeeV11 struct {
+	ID           uint
+	EmployeeName string
+	Email        string
+	JobTitle     string
+	Phone        string
+}
+
+func (employeeV11) TableName() string { return "employees" }
+
+// 0011 시점의 악센트 변환. search.go의 accentFolding이 바뀌어도 이 Migration은 그대로
+var accentFoldingV11 = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z", 'æ': "ae", 'œ': "oe",
+}
+
+/* 0011 시점의 normalizeSearch */
+func normalizeSearchV11(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if folded, ok := accentFoldingV11[r]; ok {
+			b.WriteString(folded)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '@' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+/* 0011 시점의 trigrams */
+func trigramsV11(value string) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(value) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			trigram := string(runes[i : i+3])
+			if !seen[trigram] {
+				seen[trigram] = true
+				result = append(result, trigram)
+			}
+		}
+	}
+	return result
+}
+
+func upEmployeeSearch(tx *gorm.DB) error {
+	err := tx.AutoMigrate(&employeeSearchV11{}, &employeeTrigramV11{})
+	if err != nil {
+		return err
+	}
+
+	var employees []employeeV11
+	return tx.Select("id", "employee_name", "email", "job_title", "phone").
+		FindInBatches(&employees, 500, func(batch *gorm.DB, _ int) error {
+			searches := make([]employeeSearchV11, 0, len(employees))
+			var grams []employeeTrigramV11
+			for _, employee := range employees {
+				search := employeeSearchV11{
+					EmployeeID: employee.ID,
+					Name:       normalizeSearchV11(employee.EmployeeName),
+					Email:      normalizeSearchV11(employee.Email),
+					JobTitle:   normalizeSearchV11(employee.JobTitle),
+					Phone:      normalizeSearchV11(employee.Phone),
+				}
+				searches = append(searches, search)
+				// 전화번호는 오타 검색에서 제외
+				for _, trigram := range trigramsV11(search.Name + " " + search.Email + " " + search.JobTitle) {
+					grams = append(grams, employeeTrigramV11{EmployeeID: employee.ID, Trigram: trigram})
+				}
+			}
+			err := tx.Create(&searches).Error
+			if err == nil && len(grams) > 0 {
+				err = tx.CreateInBatches(&grams, 500).Error
+			}
+			return err
+		}).Error
+}
+
+func downEmployeeSearch(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&employeeTrigramV11{}, &employeeSearchV11{})
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/facebook"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	oauthStateCookie = "oauth_state"
+	oauthStateTTL    = 10 * time.Minute // 로그인 페이지에서 callback까지 허용 시간
+)
+
+// OAuth 제공자(CA) 설정
+type OAuthProvider struct {
+	CA          string
+	Config      *oauth2.Config
+	UserInfoURL string
+	EmailsURL   string // GitHub처럼 email이 비공개일 수 있는 경우 추가로 조회
+	PKCE        bool
+	VerifyEmail bool // email 확인 여부(email_verified, verified)를 알려주는 제공자. 아니면 admin이 될 수 없음
+}
+
+// 제공자가 확인하지 않은 email
+var errEmailNotVerified = errors.New("email is not verified")
+
+// 로그인 시작 시 cookie에 저장하고 callback에서 확인하는 값 (KeyRing으로 서명)
+type oauthStateClaim struct {
+	State    string
+	Verifier string
+	CA       string
+	Type     string `json:"typ"` // tokenTypeOAuthState. access token으로 쓸 수 없도록 구분
+	jwt.StandardClaims
+}
+
+var oauthDefaults = map[string]struct {
+	endpoint    oauth2.Endpoint
+	scopes      []string
+	userInfoURL string
+	emailsURL   string
+}{
+	"GOOGLE":   {google.Endpoint, []string{"https://www.googleapis.com/auth/userinfo.email"}, "https://www.googleapis.com/oauth2/v3/userinfo", ""},
+	"FACEBOOK": {facebook.Endpoint, []string{"email"}, "https://graph.facebook.com/me?locale=en_US&fields=name,email", ""},
+	"GITHUB":   {github.Endpoint, []string{"user:email"}, "https://api.github.com/user", "https://api.github.com/user/emails"},
+}
+
+/*
+CA 이름으로 OAuth 설정을 만듦. <CA>_CLIENT_ID, <CA>_CLIENT_SECRET, <CA>_REDIRECT_URL 환경변수 사용.
+<CA>_AUTH_URL, <CA>_TOKEN_URL, <CA>_USERINFO_URL 로 주소를 바꿀 수 있음 (테스트용 가짜 서버 등)
+*/
+func GetOAuthProvider(ca string) (*OAuthProvider, error) {
+	ca = strings.ToUpper(ca)
+	defaults, ok := oauthDefaults[ca]
+	if !ok {
+		return nil, errors.New("use google, facebook or github")
+	}
+
+	endpoint := defaults.endpoint
+	if url := os.Getenv(ca + "_AUTH_URL"); url != "" {
+		endpoint.AuthURL = url
+	}
+	if url := os.Getenv(ca + "_TOKEN_URL"); url != "" {
+		endpoint.TokenURL = url
+	}
+
+	provider := &OAuthProvider{
+		CA: ca,
+		Config: &oauth2.Config{
+			ClientID:     os.Getenv(ca + "_CLIENT_ID"),
+			ClientSecret: os.Getenv(ca + "_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(ca + "_REDIRECT_URL"),
+			Scopes:       defaults.scopes,
+			Endpoint:     endpoint,
+		},
+		UserInfoURL: defaults.userInfoURL,
+		EmailsURL:   defaults.emailsURL,
+		PKCE:        ca != "FACEBOOK", // Google, GitHub는 PKCE 지원
+		VerifyEmail: ca != "FACEBOOK", // Facebook은 확인 여부를 알려주지 않음
+	}
+	if url := os.Getenv(ca + "_USERINFO_URL"); url != "" {
+		provider.UserInfoURL = url
+		provider.EmailsURL = ""
+	}
+
+	return provider, nil
+}
+
+/* 로그인 페이지 주소와 cookie에 저장할 서명된 state를 만듦 */
+func (provider *OAuthProvider) AuthCodeURL() (url string, signedState string, err error) {
+	state, err := newRandomToken(24)
+	if err != nil {
+		return
+	}
+
+	claims := &oauthStateClaim{
+		State: state,
+		CA:    provider.CA,
+		Type:  tokenTypeOAuthState,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(oauthStateTTL).Unix(),
+		},
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}
+	if provider.PKCE {
+		claims.Verifier, err = newRandomToken(32)
+		if err != nil {
+			return
+		}
+		challenge := sha256.Sum256([]byte(claims.Verifier))
+		opts = append(opts,
+			oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		)
+	}
+
+	ring, err := currentKeyRing()
+	if err != nil {
+		return
+	}
+	signedState, err = ring.sign(claims)
+	if err != nil {
+		return
+	}
+
+	url = provider.Config.AuthCodeURL(state, opts...)
+	return
+}
+
+/* cookie의 state를 검증하고 callback으로 받은 state와 비교 */
+func (provider *OAuthProvider) CheckState(signedState string, state string) (claims *oauthStateClaim, err error) {
+	ring, err := currentKeyRing()
+	if err != nil {
+		return
+	}
+
+	claims = &oauthStateClaim{}
+	_, err = jwt.ParseWithClaims(signedState, claims, ring.verifyKeyFunc) // 만료 시간도 함께 검증
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth state: %w", err)
+	}
+	if claims.Type != tokenTypeOAuthState {
+		return nil, errors.New("invalid oauth state: not a state token")
+	}
+
+	if state == "" || claims.State != state || claims.CA != provider.CA {
+		return nil, errors.New("oauth state mismatch")
+	}
+	return
+}
+
+/* code를 토큰으로 교환한 뒤 사용자 email 조회. 확인되지 않은 email이면 errEmailNotVerified. verified는 제공자가 확인했다고 알려준 경우만 true */
+func (provider *OAuthProvider) FetchEmail(ctx context.Context, code string, verifier string) (email string, verified bool, err error) {
+	var opts []oauth2.AuthCodeOption
+	if verifier != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("code_verifier", verifier))
+	}
+
+	token, err := provider.Config.Exchange(ctx, code, opts...)
+	if err != nil {
+		return "", false, errors.New("error on get token")
+	}
+	client := provider.Config.Client(ctx, token)
+
+	if provider.EmailsURL == "" {
+		var userInfo struct {
+			Email         string `json:"email"`
+			EmailVerified bool   `json:"email_verified"`
+		}
+		err = getJSON(client, provider.UserInfoURL, &userInfo)
+		if err != nil {
+			return "", false, err
+		}
+		if userInfo.Email != "" && provider.VerifyEmail && !userInfo.EmailVerified {
+			return "", false, errEmailNotVerified
+		}
+		return userInfo.Email, provider.VerifyEmail, nil
+	}
+
+	// GitHub의 /user email은 확인 여부가 없으므로 목록에서 확인된 primary email을 사용
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	err = getJSON(client, provider.EmailsURL, &emails)
+	if err != nil {
+		return "", false, err
+	}
+	for _, item := range emails {
+		if item.Primary {
+			if !item.Verified {
+				return "", false, errEmailNotVerified
+			}
+			return item.Email, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return errors.New("error on call api with get method")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error on call api: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
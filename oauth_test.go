@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/* 로그인 callback까지 진행한 응답 */
+func finishOAuthLogin(t *testing.T, handler http.Handler, challenge *string) *httptest.ResponseRecorder {
+	query, cookie := startOAuthLogin(t, handler)
+	*challenge = query.Get("code_challenge")
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/auth/callback/google?code=good-code&state="+query.Get("state"), nil)
+	request.AddCookie(cookie)
+	handler.ServeHTTP(w, request)
+	return w
+}
+
+/* code_challenge를 확인하는 가짜 OAuth 제공자 */
+func newFakeOAuthServer(t *testing.T, email string, verified bool, challenge *string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if r.Form.Get("code") != "good-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != *challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "fake-token", "token_type": "Bearer"}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"email": email, "email_verified": verified})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	t.Setenv("GOOGLE_CLIENT_ID", "client-id")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "client-secret")
+	t.Setenv("GOOGLE_REDIRECT_URL", "http://localhost/auth/callback/google")
+	t.Setenv("GOOGLE_AUTH_URL", server.URL+"/authorize")
+	t.Setenv("GOOGLE_TOKEN_URL", server.URL+"/token")
+	t.Setenv("GOOGLE_USERINFO_URL", server.URL+"/userinfo")
+	return server
+}
+
+/* /login/google 호출 후 redirect 주소의 query와 state cookie 반환 */
+func startOAuthLogin(t *testing.T, handler http.Handler) (url.Values, *http.Cookie) {
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/login/google", nil)
+	handler.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	assert.NoError(t, err)
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oauthStateCookie {
+			cookie = c
+		}
+	}
+	assert.NotNil(t, cookie)
+	return location.Query(), cookie
+}
+
+func TestOAuthLoginCallback(t *testing.T) {
+	var challenge string
+	newFakeOAuthServer(t, "oauth@test.com", true, &challenge)
+	router := SetupRouter()
+
+	query, cookie := startOAuthLogin(t, router)
+	challenge = query.Get("code_challenge")
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	var result map[string]interface{}
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/auth/callback/google?code=good-code&state="+query.Get("state"), nil)
+	request.AddCookie(cookie)
+	router.ServeHTTP(w, request)
+
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, result["new_account"]) // 처음 가입해도 바로 토큰 발급
+
+	claims, err := ValidateToken(result["JWT"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, "oauth@test.com", claims.Email)
+	assert.Equal(t, "GOOGLE", claims.CA)
+
+	var account Account
+	db.Where("Email = ? AND CA = ?", "oauth@test.com", "GOOGLE").Find(&account)
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestOAuthLoginCallbackInvalidState(t *testing.T) {
+	var challenge string
+	newFakeOAuthServer(t, "oauth@test.com", true, &challenge)
+	router := SetupRouter()
+
+	query, cookie := startOAuthLogin(t, router)
+	challenge = query.Get("code_challenge")
+
+	// state가 다름
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/auth/callback/google?code=good-code&state=forged", nil)
+	request.AddCookie(cookie)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// cookie가 없음
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", "/auth/callback/google?code=good-code&state="+query.Get("state"), nil)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// google에서 시작한 로그인을 다른 CA의 callback으로 보냄
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", "/auth/callback/github?code=good-code&state="+query.Get("state"), nil)
+	request.AddCookie(cookie)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOAuthLoginUnverifiedEmail(t *testing.T) {
+	var challenge string
+	newFakeOAuthServer(t, "unverified@test.com", false, &challenge)
+	router := SetupRouter()
+
+	w := finishOAuthLogin(t, router, &challenge)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), CodeEmailNotVerified)
+
+	var count int64
+	db.Model(&Account{}).Where("Email = ?", "unverified@test.com").Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestOAuthBootstrapAdmin(t *testing.T) {
+	var challenge string
+	newFakeOAuthServer(t, "oauth-admin@test.com", true, &challenge)
+	t.Setenv("ADMIN_EMAILS", "oauth-admin@test.com")
+	router := SetupRouter()
+	role := func() string {
+		var account Account
+		db.Where("Email = ? AND CA = ?", "oauth-admin@test.com", "GOOGLE").Find(&account)
+		db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+		db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+		return account.Role
+	}
+
+	// 기본 ADMIN_CA(GOOGLE)가 확인한 email이면 admin
+	w := finishOAuthLogin(t, router, &challenge)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, RoleAdmin, role())
+
+	// 다른 제공자로 지정되어 있으면 같은 email이어도 viewer
+	t.Setenv("ADMIN_CA", "GITHUB")
+	w = finishOAuthLogin(t, router, &challenge)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, RoleViewer, role())
+}
+
+func TestOAuthStateIsNotAccessToken(t *testing.T) {
+	var challenge string
+	newFakeOAuthServer(t, "oauth@test.com", true, &challenge)
+	router := SetupRouter()
+
+	// 로그인 시작만 하면 누구나 받을 수 있는 state를 Bearer 토큰으로 사용
+	_, cookie := startOAuthLogin(t, router)
+	_, err := ValidateToken(cookie.Value)
+	assert.Error(t, err)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/api/v2/employee/", nil)
+	request.Header.Add("Authorization", "Bearer "+cookie.Value)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 반대로 access token은 state로 쓸 수 없음
+	token, err := GenerateToken("gotest", "GOOGLE", RoleViewer)
+	assert.NoError(t, err)
+	provider, err := GetOAuthProvider("google")
+	assert.NoError(t, err)
+	_, err = provider.CheckState(token, "")
+	assert.Error(t, err)
+}
+
+func TestOAuthLoginInvalidCA(t *testing.T) {
+	router := SetupRouter()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/login/naver", nil)
+	router.ServeHTTP(w, request)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI 문서의 operation 하나. SetupRouter에 route를 추가하면 여기에도 추가 (openapi_test에서 확인)
+type apiOperation struct {
+	Method   string
+	Path     string // gin 형식. API route는 /api/{버전} 아래의 경로
+	Versions []int  // 등록되는 API 버전. 비어있으면 /api 밖의 route
+	Tag      string
+	Summary  string
+	Auth     bool        // AuthorizeAccount 사용
+	Role     string      // RequireRole로 필요한 권한
+	Query    []string    // query parameter 이름. specQueryParams에 설명
+	Request  interface{} // body 타입. nil이면 없음
+	Status   int
+	Response interface{} // 응답 타입. nil이면 body 없음
+	Partial  bool        // 일부만 성공하면 207
+}
+
+// 문서에만 사용하는 응답 형식
+type specPage struct{ Item interface{} } // PageResponse. data는 Item 배열
+type specList struct{ Item interface{} } // Item 배열
+type specObject map[string]interface{}   // gin.H 응답. 값의 타입이 항목의 schema
+type specFile []string                   // 파일 다운로드. 값은 Content-Type
+type specUpload struct{}                 // csv, xlsx 파일 (multipart의 file 또는 body)
+type specHTML struct{}                   // HTML 페이지
+type specRedirect struct{}               // 302 redirect
+
+// 자주 쓰는 query parameter 묶음
+var (
+	pagingQuery = []string{"page", "limit", "sort", "cursor"}
+	readQuery   = []string{"include", "fields"}
+)
+
+// query parameter 설명. type이 비어있으면 string
+var specQueryParams = map[string][2]string{
+	"page":            {"integer", "Page number (from 1). Cannot be used with cursor"},
+	"limit":           {"integer", "Items per page"},
+	"sort":            {"", "Comma separated columns. Prefix - for descending (e.g. -entry_time,employee_name)"},
+	"cursor":          {"", "Cursor from next/prev. Empty value starts cursor paging"},
+	"include":         {"", "Comma separated related data to include. Empty value includes nothing"},
+	"fields":          {"", "Comma separated response fields (e.g. id,name,manager.name)"},
+	"filter":          {"", "Conditions field op value[,value] separated by ;. Operators are = != > >= < <= ~ (contains) !~. Several values mean IN. e.g. employees " + specEmployeeFilterExample + ", departments " + specDepartmentFilterExample},
+	"include_deleted": {"boolean", "Include soft deleted rows"},
+	"as_of":           {"", "Read the state at this time (2006-01-02 or RFC3339)"},
+	"recursive":       {"boolean", "Include employees of descendant departments"},
+	"strategy":        {"", "How to handle child departments: reparent or cascade"},
+	"atomic":          {"boolean", "false creates each item separately. Default true"},
+	"q":               {"", "Search text. Matches name, email, job title and phone with typos"},
+	"department":      {"", "Limit to this department and its descendants"},
+	"include_ended":   {"boolean", "Include ended assignments"},
+	"depth":           {"integer", "Levels of reports to include"},
+	"dry_run":         {"boolean", "Validate only. Nothing is imported"},
+	"format":          {"", "File format"},
+	"mapping":         {"", "JSON object of file column to field (e.g. {\"Name\":\"dname\"})"},
+	"retention_days":  {"integer", "Purge rows deleted before this many days"},
+	"actor":           {"", "Actor email"},
+	"entity_type":     {"", "Audited entity"},
+	"entity_id":       {"integer", "Entity id"},
+	"action":          {"", "Audit action"},
+	"from":            {"", "From this time (2006-01-02 or RFC3339)"},
+	"to":              {"", "To this time (2006-01-02 or RFC3339). A date includes the whole day"},
+	"state":           {"", "OAuth state"},
+	"code":            {"", "OAuth authorization code"},
+}
+
+// 값이 정해진 query parameter
+var specQueryEnums = map[string][]string{
+	"action":      auditActions,
+	"entity_type": auditEntities,
+}
+
+// ?filter= 예시. filter.go의 형식과 항목 이름 (openapi_test에서 ParseFilter로 확인)
+const (
+	specEmployeeFilterExample   = "entry_time>=2024-01-01;department=Sales,HR"
+	specDepartmentFilterExample = "parent=HQ;name~team"
+)
+
+// path parameter 중 숫자인 것
+var specIntegerParams = map[string]bool{"id": true, "eid": true, "days": true}
+
+// 모든 route의 문서. SetupRouter, registerAPI와 같은 순서
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/auth/callback/google", Tag: "auth", Summary: "Google OAuth callback. Logs in or registers the account", Query: []string{"state", "code"}, Status: http.StatusOK, Response: sessionResponse},
+	{Method: "GET", Path: "/auth/callback/facebook", Tag: "auth", Summary: "Facebook OAuth callback. Logs in or registers the account", Query: []string{"state", "code"}, Status: http.StatusOK, Response: sessionResponse},
+	{Method: "GET", Path: "/auth/callback/github", Tag: "auth", Summary: "GitHub OAuth callback. Logs in or registers the account", Query: []string{"state", "code"}, Status: http.StatusOK, Response: sessionResponse},
+	{Method: "GET", Path: "/login/:CA", Tag: "auth", Summary: "Redirect to the OAuth login page (google, facebook or github)", Status: http.StatusFound, Response: specRedirect{}},
+	{Method: "POST", Path: "/auth/register", Tag: "auth", Summary: "Register an email/password account and issue tokens", Request: passwordData{}, Status: http.StatusCreated, Response: sessionResponse},
+	{Method: "POST", Path: "/auth/token", Tag: "auth", Summary: "Issue tokens with email and password", Request: passwordData{}, Status: http.StatusOK, Response: sessionResponse},
+	{Method: "POST", Path: "/auth/refresh", Tag: "auth", Summary: "Issue a new access token with a refresh token. The refresh token is rotated", Request: refreshData{}, Status: http.StatusOK, Response: specObject{"JWT": "", "refresh_token": ""}},
+	{Method: "POST", Path: "/auth/logout", Tag: "auth", Summary: "Revoke the access token and the refresh token", Auth: true, Request: logoutData{}, Status: http.StatusOK, Response: msgResponse},
+	{Method: "GET", Path: "/auth/apikeys/", Tag: "auth", Summary: "List api keys of the account", Auth: true, Status: http.StatusOK, Response: specList{ApiKey{}}},
+	{Method: "POST", Path: "/auth/apikeys/", Tag: "auth", Summary: "Create an api key. The key is shown only once", Auth: true, Request: apiKeyData{}, Status: http.StatusCreated, Response: specObject{"key": "", "apiKey": ApiKey{}}},
+	{Method: "DELETE", Path: "/auth/apikeys/:id", Tag: "auth", Summary: "Revoke an api key", Auth: true, Status: http.StatusOK, Response: msgResponse},
+	{Method: "GET", Path: "/.well-known/jwks.json", Tag: "auth", Summary: "Public keys to verify access tokens", Status: http.StatusOK, Response: specObject{"keys": []JSONMap{}}},
+	{Method: "GET", Path: "/openapi.json", Tag: "docs", Summary: "This OpenAPI document", Status: http.StatusOK, Response: JSONMap{}},
+	{Method: "GET", Path: "/docs", Tag: "docs", Summary: "Swagger UI. Needs SWAGGER_UI_DIR", Status: http.StatusOK, Response: specHTML{}},
+	{Method: "GET", Path: "/docs/:file", Tag: "docs", Summary: "Swagger UI files", Status: http.StatusOK, Response: specFile{"text/css", "application/javascript"}},
+
+	{Method: "GET", Path: "/department/", Versions: []int{1, 2}, Tag: "department", Summary: "List departments. Employees are included by default", Auth: true, Role: RoleViewer, Query: concatQuery(pagingQuery, readQuery, "filter", "include_deleted"), Status: http.StatusOK, Response: specPage{DepartmentResponse{}}},
+	{Method: "GET", Path: "/department/:name", Versions: []int{1, 2}, Tag: "department", Summary: "Departments with the name", Auth: true, Role: RoleViewer, Query: concatQuery(readQuery, "as_of"), Status: http.StatusOK, Response: specList{DepartmentResponse{}}},
+	{Method: "GET", Path: "/department/:name/employee", Versions: []int{1, 2}, Tag: "department", Summary: "Employees in the department", Auth: true, Role: RoleViewer, Query: concatQuery(pagingQuery, readQuery, "recursive", "as_of", "include_deleted"), Status: http.StatusOK, Response: specPage{EmployeeResponse{}}},
+	{Method: "GET", Path: "/department/:name/children", Versions: []int{1, 2}, Tag: "department", Summary: "Direct child departments", Auth: true, Role: RoleViewer, Status: http.StatusOK, Response: specList{DepartmentResponse{}}},
+	{Method: "GET", Path: "/department/:name/descendants", Versions: []int{1, 2}, Tag: "department", Summary: "All descendant departments", Auth: true, Role: RoleViewer, Status: http.StatusOK, Response: specList{DepartmentResponse{}}},
+	{Method: "PUT", Path: "/department/:name/parent", Versions: []int{1, 2}, Tag: "department", Summary: "Move the department under another parent. Empty parent makes it top level", Auth: true, Role: RoleEditor, Request: parentData{}, Status: http.StatusOK, Response: specObject{"msg": "", "parent": ""}},
+	{Method: "POST", Path: "/department/", Versions: []int{1}, Tag: "department", Summary: "Create departments", Auth: true, Role: RoleEditor, Request: dData{}, Status: http.StatusOK, Response: specObject{"msg": []string{}}},
+	{Method: "POST", Path: "/department/", Versions: []int{2}, Tag: "department", Summary: "Create departments", Auth: true, Role: RoleEditor, Request: departmentCreateRequest{}, Status: http.StatusCreated, Response: specObject{"data": []DepartmentResponse{}}},
+	{Method: "POST", Path: "/department/:name/restore", Versions: []int{1, 2}, Tag: "department", Summary: "Restore a deleted department", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: specObject{"msg": "", "department": DepartmentResponse{}}},
+	{Method: "DELETE", Path: "/department/:name", Versions: []int{1, 2}, Tag: "department", Summary: "Delete a department. Needs strategy if it has children", Auth: true, Role: RoleEditor, Query: []string{"strategy"}, Status: http.StatusOK, Response: specObject{"msg": "", "deleted": 0}},
+	{Method: "GET", Path: "/department/only", Versions: []int{1}, Tag: "department", Summary: "List departments without employees", Auth: true, Role: RoleViewer, Query: concatQuery(pagingQuery, readQuery, "filter", "include_deleted"), Status: http.StatusOK, Response: specPage{DepartmentResponse{}}},
+	{Method: "PUT", Path: "/department/", Versions: []int{1}, Tag: "department", Summary: "Rename a department", Auth: true, Role: RoleEditor, Request: UpdateData{}, Status: http.StatusOK, Response: msgResponse},
+	{Method: "PUT", Path: "/department/:name", Versions: []int{2}, Tag: "department", Summary: "Rename a department", Auth: true, Role: RoleEditor, Request: departmentUpdateRequest{}, Status: http.StatusOK, Response: DepartmentResponse{}},
+
+	{Method: "GET", Path: "/employee/", Versions: []int{1, 2}, Tag: "employee", Summary: "List employees. Departments are included by default", Auth: true, Role: RoleViewer, Query: concatQuery(pagingQuery, readQuery, "filter", "include_deleted", "as_of"), Status: http.StatusOK, Response: specPage{EmployeeResponse{}}},
+	{Method: "GET", Path: "/employee/name/:name", Versions: []int{1, 2}, Tag: "employee", Summary: "Employees with the name", Auth: true, Role: RoleViewer, Query: concatQuery(readQuery, "include_deleted"), Status: http.StatusOK, Response: specList{EmployeeResponse{}}},
+	{Method: "GET", Path: "/employee/search", Versions: []int{1, 2}, Tag: "employee", Summary: "Search employees by relevance", Auth: true, Role: RoleViewer, Query: []string{"q", "department", "page", "limit", "include_deleted"}, Status: http.StatusOK, Response: specPage{SearchHit{}}},
+	{Method: "GET", Path: "/employee/day/:days", Versions: []int{1, 2}, Tag: "employee", Summary: "Employees who joined in the last days", Auth: true, Role: RoleViewer, Query: concatQuery(pagingQuery, readQuery, "filter", "include_deleted"), Status: http.StatusOK, Response: specPage{EmployeeResponse{}}},
+	{Method: "GET", Path: "/employee/:id/assignments", Versions: []int{1, 2}, Tag: "employee", Summary: "Department assignments of the employee", Auth: true, Role: RoleViewer, Query: concatQuery(readQuery, "include_ended"), Status: http.StatusOK, Response: specList{AssignmentResponse{}}},
+	{Method: "GET", Path: "/employee/:id/history", Versions: []int{1, 2}, Tag: "employee", Summary: "Changes and assignments of the employee in time order", Auth: true, Role: RoleViewer, Status: http.StatusOK, Response: specList{HistoryEvent{}}},
+	{Method: "PUT", Path: "/employee/:id", Versions: []int{1}, Tag: "employee", Summary: "Update an employee. Only sent fields are changed", Auth: true, Role: RoleEditor, Request: eUpdateData{}, Status: http.StatusOK, Response: msgResponse},
+	{Method: "PUT", Path: "/employee/:id", Versions: []int{2}, Tag: "employee", Summary: "Update an employee. Only sent fields are changed", Auth: true, Role: RoleEditor, Request: employeeUpdateRequest{}, Status: http.StatusOK, Response: EmployeeResponse{}},
+	{Method: "POST", Path: "/employee/", Versions: []int{1}, Tag: "employee", Summary: "Create employees", Auth: true, Role: RoleEditor, Query: []string{"atomic"}, Request: []eData{}, Status: http.StatusOK, Response: specObject{"msg": []string{}, "results": []bulkResult{}}, Partial: true},
+	{Method: "POST", Path: "/employee/", Versions: []int{2}, Tag: "employee", Summary: "Create employees", Auth: true, Role: RoleEditor, Query: []string{"atomic"}, Request: []employeeRequest{}, Status: http.StatusCreated, Response: specObject{"results": []employeeResult{}}, Partial: true},
+	{Method: "POST", Path: "/employee/:id/restore", Versions: []int{1, 2}, Tag: "employee", Summary: "Restore a deleted employee", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: specObject{"msg": "", "employee": EmployeeResponse{}}},
+	{Method: "DELETE", Path: "/employee/:name", Versions: []int{1}, Tag: "employee", Summary: "Delete an employee by name. Fails if the name is not unique", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: msgResponse},
+	{Method: "DELETE", Path: "/employee/id/:id", Versions: []int{1}, Tag: "employee", Summary: "Delete an employee", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: msgResponse},
+	{Method: "DELETE", Path: "/employee/:id", Versions: []int{2}, Tag: "employee", Summary: "Delete an employee", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: msgResponse},
+
+	{Method: "GET", Path: "/org/", Versions: []int{1, 2}, Tag: "org", Summary: "Org chart from employees without a manager", Auth: true, Role: RoleViewer, Query: []string{"depth"}, Status: http.StatusOK, Response: specList{OrgNode{}}},
+	{Method: "GET", Path: "/org/:id/reports", Versions: []int{1, 2}, Tag: "org", Summary: "Direct reports", Auth: true, Role: RoleViewer, Status: http.StatusOK, Response: specList{OrgNode{}}},
+	{Method: "GET", Path: "/org/:id/subtree", Versions: []int{1, 2}, Tag: "org", Summary: "Org chart under the employee", Auth: true, Role: RoleViewer, Query: []string{"depth"}, Status: http.StatusOK, Response: OrgNode{}},
+	{Method: "GET", Path: "/org/:id/chain", Versions: []int{1, 2}, Tag: "org", Summary: "Managers from the direct manager to the top", Auth: true, Role: RoleViewer, Status: http.StatusOK, Response: specList{OrgNode{}}},
+	{Method: "PUT", Path: "/org/:id/manager", Versions: []int{1}, Tag: "org", Summary: "Change the manager. 0 removes the manager", Auth: true, Role: RoleEditor, Request: managerData{}, Status: http.StatusOK, Response: specObject{"msg": "", "employee": OrgNode{}}},
+	{Method: "PUT", Path: "/org/:id/manager", Versions: []int{2}, Tag: "org", Summary: "Change the manager. 0 removes the manager", Auth: true, Role: RoleEditor, Request: managerRequest{}, Status: http.StatusOK, Response: EmployeeResponse{}},
+
+	{Method: "POST", Path: "/assign/:name/:department", Versions: []int{1}, Tag: "assign", Summary: "Assign an employee (by name) to a department", Auth: true, Role: RoleEditor, Request: assignData{}, Status: http.StatusOK, Response: assignResponse},
+	{Method: "POST", Path: "/assign/id/:eid/:department", Versions: []int{1}, Tag: "assign", Summary: "Assign an employee to a department", Auth: true, Role: RoleEditor, Request: assignData{}, Status: http.StatusOK, Response: assignResponse},
+	{Method: "PUT", Path: "/assign/id/:eid/:department", Versions: []int{1}, Tag: "assign", Summary: "Update the current assignment. Only sent fields are changed", Auth: true, Role: RoleEditor, Request: assignUpdateData{}, Status: http.StatusOK, Response: specObject{"msg": "", "assignment": AssignmentResponse{}}},
+	{Method: "DELETE", Path: "/assign/:name/:department", Versions: []int{1}, Tag: "assign", Summary: "End the assignment of an employee (by name). The record is kept", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: endAssignResponse},
+	{Method: "DELETE", Path: "/assign/id/:eid/:department", Versions: []int{1}, Tag: "assign", Summary: "End the assignment. The record is kept", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: endAssignResponse},
+	{Method: "POST", Path: "/assign/:eid/:department", Versions: []int{2}, Tag: "assign", Summary: "Assign an employee to a department", Auth: true, Role: RoleEditor, Request: assignRequest{}, Status: http.StatusCreated, Response: AssignmentResponse{}},
+	{Method: "PUT", Path: "/assign/:eid/:department", Versions: []int{2}, Tag: "assign", Summary: "Update the current assignment. Only sent fields are changed", Auth: true, Role: RoleEditor, Request: assignUpdateRequest{}, Status: http.StatusOK, Response: AssignmentResponse{}},
+	{Method: "DELETE", Path: "/assign/:eid/:department", Versions: []int{2}, Tag: "assign", Summary: "End the assignment. The record is kept", Auth: true, Role: RoleEditor, Status: http.StatusOK, Response: AssignmentResponse{}},
+
+	{Method: "POST", Path: "/import/employees", Versions: []int{1, 2}, Tag: "import", Summary: "Import employees from csv or xlsx. All rows or nothing", Auth: true, Role: RoleEditor, Query: []string{"dry_run", "format", "mapping"}, Request: specUpload{}, Status: http.StatusOK, Response: importFileResponse},
+	{Method: "POST", Path: "/import/departments", Versions: []int{1, 2}, Tag: "import", Summary: "Import departments from csv or xlsx. All rows or nothing", Auth: true, Role: RoleEditor, Query: []string{"dry_run", "format", "mapping"}, Request: specUpload{}, Status: http.StatusOK, Response: importFileResponse},
+
+	{Method: "GET", Path: "/export/employees", Versions: []int{1, 2}, Tag: "export", Summary: "Export employees", Auth: true, Role: RoleViewer, Query: []string{"format", "sort", "filter", "include_deleted", "as_of"}, Status: http.StatusOK, Response: exportFile},
+	{Method: "GET", Path: "/export/departments", Versions: []int{1, 2}, Tag: "export", Summary: "Export departments", Auth: true, Role: RoleViewer, Query: []string{"format", "sort", "filter", "include_deleted"}, Status: http.StatusOK, Response: exportFile},
+	{Method: "GET", Path: "/export/assignments", Versions: []int{1, 2}, Tag: "export", Summary: "Export assignments", Auth: true, Role: RoleViewer, Query: []string{"format", "include_ended"}, Status: http.StatusOK, Response: exportFile},
+
+	{Method: "GET", Path: "/admin/account", Versions: []int{1, 2}, Tag: "admin", Summary: "List accounts", Auth: true, Role: RoleAdmin, Status: http.StatusOK, Response: specList{AccountResponse{}}},
+	{Method: "PUT", Path: "/admin/account/:id/role", Versions: []int{1, 2}, Tag: "admin", Summary: "Grant a role. Applied after re-login", Auth: true, Role: RoleAdmin, Request: roleData{}, Status: http.StatusOK, Response: roleResponse},
+	{Method: "DELETE", Path: "/admin/account/:id/role", Versions: []int{1, 2}, Tag: "admin", Summary: "Reset the role to viewer", Auth: true, Role: RoleAdmin, Status: http.StatusOK, Response: roleResponse},
+	{Method: "GET", Path: "/admin/migrations", Versions: []int{1, 2}, Tag: "admin", Summary: "Migration status", Auth: true, Role: RoleAdmin, Status: http.StatusOK, Response: specList{MigrationState{}}},
+	{Method: "POST", Path: "/admin/purge", Versions: []int{1, 2}, Tag: "admin", Summary: "Hard delete employees and departments deleted before the retention period", Auth: true, Role: RoleAdmin, Query: []string{"retention_days"}, Status: http.StatusOK, Response: purgeResult{}},
+
+	{Method: "GET", Path: "/audit/", Versions: []int{1, 2}, Tag: "audit", Summary: "Audit logs. Newest first by default", Auth: true, Role: RoleAdmin, Query: concatQuery(pagingQuery, "actor", "entity_type", "entity_id", "action", "from", "to"), Status: http.StatusOK, Response: specPage{AuditLog{}}},
+}
+
+// 여러 route에서 같은 형식의 응답
+var (
+	msgResponse        = specObject{"msg": ""}
+	sessionResponse    = specObject{"JWT": "", "refresh_token": "", "account": AccountResponse{}, "new_account": false}
+	assignResponse     = specObject{"employee": "", "department": "", "assignment": AssignmentResponse{}}
+	endAssignResponse  = specObject{"msg": "", "employee": "", "department": "", "assignment": AssignmentResponse{}}
+	roleResponse       = specObject{"msg": "", "account": AccountResponse{}}
+	importFileResponse = specObject{"dryRun": false, "valid": false, "rows": 0, "failed": 0, "ignored": []string{}, "results": []importResult{}}
+	exportFile         = specFile{"text/csv", "application/x-ndjson"}
+)
+
+var ginParamPattern = regexp.MustCompile(`[:*]([A-Za-z_]+)`)
+
+/* query 이름 목록 합치기 */
+func concatQuery(groups ...interface{}) []string {
+	var names []string
+	for _, group := range groups {
+		switch value := group.(type) {
+		case []string:
+			names = append(names, value...)
+		case string:
+			names = append(names, value)
+		}
+	}
+	return names
+}
+
+/* gin route 경로를 OpenAPI 경로로 (:id -> {id}) */
+func openapiPath(path string) string {
+	return ginParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+// components/schemas를 만들면서 Go 타입을 schema로 변환
+type specBuilder struct {
+	schemas gin.H
+}
+
+/* OpenAPI 3 문서 생성. API route는 버전마다, v1은 버전 없는 /api에도 등록 */
+func BuildOpenAPI() gin.H {
+	builder := &specBuilder{schemas: gin.H{}}
+	builder.schema(reflect.TypeOf(ApiError{}))
+
+	paths := map[string]gin.H{}
+	add := func(path string, operation apiOperation, tag string, deprecated bool) {
+		path = openapiPath(path)
+		if paths[path] == nil {
+			paths[path] = gin.H{}
+		}
+		paths[path][strings.ToLower(operation.Method)] = builder.operation(path, operation, tag, deprecated)
+	}
+
+	versions := apiVersions()
+	for _, operation := range apiOperations {
+		if len(operation.Versions) == 0 {
+			add(operation.Path, operation, operation.Tag, false)
+			continue
+		}
+		for _, version := range versions {
+			if containsInt(operation.Versions, version.Number) {
+				add("/api/"+version.Name+operation.Path, operation, version.Name+" "+operation.Tag, !version.Deprecated.IsZero())
+			}
+		}
+		if containsInt(operation.Versions, versions[0].Number) {
+			add("/api"+operation.Path, operation, versions[0].Name+" "+operation.Tag, true)
+		}
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":       "myapi",
+			"version":     versions[len(versions)-1].Name,
+			"description": "Employee and department API. Errors are application/problem+json. /api/v1 and /api are deprecated; use /api/v2.",
+		},
+		"paths": paths,
+		"components": gin.H{
+			"schemas": builder.schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth": gin.H{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+func containsInt(values []int, value int) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* operation 하나의 문서 */
+func (builder *specBuilder) operation(path string, operation apiOperation, tag string, deprecated bool) gin.H {
+	doc := gin.H{
+		"tags":    []string{tag},
+		"summary": operation.Summary,
+	}
+	if deprecated {
+		doc["deprecated"] = true
+	}
+
+	parameters := []gin.H{}
+	for _, match := range ginParamPattern.FindAllStringSubmatch(operation.Path, -1) {
+		schema := gin.H{"type": "string"}
+		if specIntegerParams[match[1]] {
+			schema = gin.H{"type": "integer"}
+		}
+		parameters = append(parameters, gin.H{"name": match[1], "in": "path", "required": true, "schema": schema})
+	}
+	for _, name := range operation.Query {
+		param := specQueryParams[name]
+		schema := gin.H{"type": "string"}
+		if param[0] != "" {
+			schema = gin.H{"type": param[0]}
+		}
+		if values, ok := specQueryEnums[name]; ok {
+			schema["enum"] = values
+		}
+		parameters = append(parameters, gin.H{"name": name, "in": "query", "description": param[1], "schema": schema})
+	}
+	if len(parameters) > 0 {
+		doc["parameters"] = parameters
+	}
+
+	if operation.Request != nil {
+		doc["requestBody"] = builder.requestBody(operation.Request)
+	}
+	if operation.Auth {
+		doc["security"] = []gin.H{{"bearerAuth": []string{}}, {"apiKeyAuth": []string{}}}
+	}
+
+	responses := gin.H{}
+	success := builder.response(operation.Status, operation.Response)
+	responses[strconv.Itoa(operation.Status)] = success
+	if operation.Partial {
+		partial := builder.response(http.StatusMultiStatus, operation.Response)
+		partial["description"] = "Some items failed. See results"
+		responses[strconv.Itoa(http.StatusMultiStatus)] = partial
+	}
+	if operation.Auth {
+		responses[strconv.Itoa(http.StatusUnauthorized)] = builder.errorResponse("No or invalid token or api key")
+	}
+	if operation.Role != "" {
+		responses[strconv.Itoa(http.StatusForbidden)] = builder.errorResponse("Needs " + operation.Role + " role")
+	}
+	responses["default"] = builder.errorResponse("Error")
+	doc["responses"] = responses
+	return doc
+}
+
+/* 요청 body 문서 */
+func (builder *specBuilder) requestBody(request interface{}) gin.H {
+	if _, ok := request.(specUpload); ok {
+		binary := gin.H{"type": "string", "format": "binary"}
+		return gin.H{
+			"required": true,
+			"content": gin.H{
+				"multipart/form-data": gin.H{"schema": gin.H{
+					"type":       "object",
+					"required":   []string{"file"},
+					"properties": gin.H{"file": binary},
+				}},
+				"text/csv": gin.H{"schema": binary},
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": gin.H{"schema": binary},
+			},
+		}
+	}
+	return gin.H{
+		"required": true,
+		"content":  gin.H{"application/json": gin.H{"schema": builder.value(request)}},
+	}
+}
+
+/* 성공 응답 문서 */
+func (builder *specBuilder) response(status int, response interface{}) gin.H {
+	doc := gin.H{"description": http.StatusText(status)}
+	switch value := response.(type) {
+	case nil:
+	case specRedirect:
+		doc["description"] = "Redirect to the login page"
+		doc["headers"] = gin.H{"Location": gin.H{"schema": gin.H{"type": "string"}}}
+	case specHTML:
+		doc["content"] = gin.H{"text/html": gin.H{"schema": gin.H{"type": "string"}}}
+	case specFile:
+		content := gin.H{}
+		for _, contentType := range value {
+			content[contentType] = gin.H{"schema": gin.H{"type": "string", "format": "binary"}}
+		}
+		doc["content"] = content
+	default:
+		doc["content"] = gin.H{"application/json": gin.H{"schema": builder.value(response)}}
+	}
+	return doc
+}
+
+/* 에러 응답 문서 */
+func (builder *specBuilder) errorResponse(description string) gin.H {
+	return gin.H{
+		"description": description,
+		"content":     gin.H{problemContentType: gin.H{"schema": gin.H{"$ref": "#/components/schemas/ApiError"}}},
+	}
+}
+
+/* 응답, 요청 값의 schema. 문서용 타입이면 그 형식대로 */
+func (builder *specBuilder) value(value interface{}) gin.H {
+	switch value := value.(type) {
+	case specPage:
+		return gin.H{
+			"type":     "object",
+			"required": []string{"data", "limit"},
+			"properties": gin.H{
+				"data":  gin.H{"type": "array", "items": builder.value(value.Item)},
+				"total": gin.H{"type": "integer", "description": "Not counted with cursor paging"},
+				"limit": gin.H{"type": "integer"},
+				"page":  gin.H{"type": "integer"},
+				"next":  gin.H{"type": "string"},
+				"prev":  gin.H{"type": "string"},
+			},
+		}
+	case specList:
+		return gin.H{"type": "array", "items": builder.value(value.Item)}
+	case specObject:
+		properties := gin.H{}
+		for name, item := range value {
+			properties[name] = builder.value(item)
+		}
+		return gin.H{"type": "object", "properties": properties}
+	}
+	return builder.schema(reflect.TypeOf(value))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+/* Go 타입의 schema. 이름 있는 구조체는 components에 등록하고 $ref */
+func (builder *specBuilder) schema(t reflect.Type) gin.H {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := builder.schema(t.Elem())
+		if _, ok := schema["$ref"]; !ok {
+			schema["nullable"] = true
+		}
+		return schema
+	case reflect.Bool:
+		return gin.H{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return gin.H{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return gin.H{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return gin.H{"type": "number"}
+	case reflect.String:
+		return gin.H{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return gin.H{"type": "array", "items": builder.schema(t.Elem())}
+	case reflect.Map:
+		return gin.H{"type": "object", "additionalProperties": true}
+	case reflect.Interface:
+		return gin.H{}
+	case reflect.Struct:
+		if t == timeType {
+			return gin.H{"type": "string", "format": "date-time"}
+		}
+		ref := gin.H{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := builder.schemas[t.Name()]; ok {
+			return ref
+		}
+		// 자기 자신을 포함하는 타입(OrgNode)이 있으므로 먼저 등록
+		builder.schemas[t.Name()] = gin.H{}
+		builder.schemas[t.Name()] = builder.object(t)
+		return ref
+	}
+	return gin.H{}
+}
+
+/* 구조체의 json 항목과 binding 규칙으로 object schema 생성 */
+func (builder *specBuilder) object(t reflect.Type) gin.H {
+	properties := gin.H{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = field.Name
+		}
+
+		schema := builder.schema(field.Type)
+		if _, ok := schema["$ref"]; ok && field.Type.Kind() == reflect.Ptr {
+			schema = gin.H{"allOf": []gin.H{schema}, "nullable": true}
+		}
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			key, value := rule, ""
+			if index := strings.Index(rule, "="); index >= 0 {
+				key, value = rule[:index], rule[index+1:]
+			}
+			switch key {
+			case "required":
+				required = append(required, name)
+			case "email":
+				schema["format"] = "email"
+			case "oneof":
+				schema["enum"] = strings.Fields(value)
+			case "min", "max":
+				limit, err := strconv.Atoi(value)
+				if name := specLimitName(field.Type, key); name != "" && err == nil {
+					schema[name] = limit
+				}
+			}
+		}
+		properties[name] = schema
+	}
+
+	schema := gin.H{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+/* min, max binding에 해당하는 schema 항목 이름. 문자열은 길이, 배열은 개수, 숫자는 값 */
+func specLimitName(t reflect.Type, rule string) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return rule + "Length"
+	case reflect.Slice:
+		return rule + "Items"
+	case reflect.Int, reflect.Uint:
+		return rule + "imum"
+	}
+	return ""
+}
+
+/* OpenAPI 문서 (/openapi.json) */
+func ReadOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, BuildOpenAPI())
+}
+
+// Swagger UI 파일. 외부 CDN의 script를 API origin에서 실행하지 않도록 SWAGGER_UI_DIR(swagger-ui-dist 폴더)에서 직접 제공
+var swaggerUIFiles = map[string]bool{"swagger-ui.css": true, "swagger-ui-bundle.js": true}
+
+// /docs의 CSP. script는 같은 origin의 파일만 (inline script 없음)
+const docsCSP = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'"
+
+const swaggerUIInitializer = `window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+`
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>myapi docs</title>
+<link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="/docs/swagger-ui-bundle.js"></script>
+<script src="/docs/swagger-initializer.js"></script>
+</body>
+</html>
+`
+
+// SWAGGER_UI_DIR이 없을 때. 문서 주소만 안내
+const swaggerUIMissingPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>myapi docs</title>
+</head>
+<body>
+<p>Swagger UI is not installed. Set SWAGGER_UI_DIR to a swagger-ui-dist folder.</p>
+<p>OpenAPI document: <a href="/openapi.json">/openapi.json</a></p>
+</body>
+</html>
+`
+
+/* Swagger UI (/docs). SWAGGER_UI_DIR이 없으면 /openapi.json 안내 */
+func ReadDocs(c *gin.Context) {
+	page := swaggerUIPage
+	if os.Getenv("SWAGGER_UI_DIR") == "" {
+		page = swaggerUIMissingPage
+	}
+	c.Header("Content-Security-Policy", docsCSP)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+/* Swagger UI 파일 (/docs/:file). swaggerUIFiles에 있는 파일만 */
+func ReadDocsAsset(c *gin.Context) {
+	file := c.Param("file")
+	if file == "swagger-initializer.js" {
+		c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(swaggerUIInitializer))
+		return
+	}
+
+	dir := os.Getenv("SWAGGER_UI_DIR")
+	if dir == "" || !swaggerUIFiles[file] {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeNotFound, "No such file"))
+		return
+	}
+	c.File(filepath.Join(dir, file))
+}
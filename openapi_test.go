@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/* 응답과 같은 형식으로 보도록 문서를 json으로 다시 읽음 */
+func decodeOpenAPI(t *testing.T, spec interface{}) {
+	b, err := json.Marshal(BuildOpenAPI())
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(b, spec))
+}
+
+func openapiPaths(t *testing.T) map[string]map[string]interface{} {
+	var spec struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	decodeOpenAPI(t, &spec)
+	return spec.Paths
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	router := SetupRouter()
+	paths := openapiPaths(t)
+
+	// 등록된 route는 모두 문서에 있어야 함
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path := openapiPath(route.Path)
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		assert.Contains(t, paths[path], method, "%s %s is not in apiOperations", route.Method, route.Path)
+	}
+
+	// 문서에만 있는 route도 없어야 함
+	for path, operations := range paths {
+		for method := range operations {
+			assert.True(t, registered[method+" "+path], "%s %s is not registered", method, path)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	paths := openapiPaths(t)
+
+	// v1과 버전 없는 /api 는 deprecated
+	assert.Equal(t, true, paths["/api/v1/employee/{id}"]["put"].(map[string]interface{})["deprecated"])
+	assert.Equal(t, true, paths["/api/employee/{id}"]["put"].(map[string]interface{})["deprecated"])
+	assert.NotContains(t, paths["/api/v2/employee/{id}"]["put"], "deprecated")
+
+	// 요청 body는 버전별 타입
+	body := func(path string, method string) string {
+		operation := paths[path][method].(map[string]interface{})
+		content := operation["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+		return content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})["$ref"].(string)
+	}
+	assert.Equal(t, "#/components/schemas/eUpdateData", body("/api/v1/employee/{id}", "put"))
+	assert.Equal(t, "#/components/schemas/employeeUpdateRequest", body("/api/v2/employee/{id}", "put"))
+	assert.Equal(t, "#/components/schemas/UpdateData", body("/api/v1/department/", "put"))
+
+	// 인증이 필요한 route는 Bearer 또는 API Key
+	operation := paths["/api/v2/employee/"]["get"].(map[string]interface{})
+	assert.Len(t, operation["security"], 2)
+	assert.Contains(t, operation["responses"], "401")
+	assert.Contains(t, operation["responses"], "403")
+	assert.NotContains(t, paths["/auth/token"]["post"], "security")
+
+	// path parameter
+	parameters := paths["/api/v2/assign/{eid}/{department}"]["post"].(map[string]interface{})["parameters"].([]interface{})
+	assert.Equal(t, "eid", parameters[0].(map[string]interface{})["name"])
+	assert.Equal(t, "department", parameters[1].(map[string]interface{})["name"])
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	decodeOpenAPI(t, &spec)
+	schemas := spec.Components.Schemas
+
+	// binding 규칙이 required, enum 등으로
+	eData := schemas["eData"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"ename"}, eData["required"])
+	properties := eData["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"full-time", "part-time", "contract", "intern"}, properties["employment_type"].(map[string]interface{})["enum"])
+	assert.Equal(t, "email", properties["email"].(map[string]interface{})["format"])
+	assert.Equal(t, float64(255), properties["ename"].(map[string]interface{})["maxLength"])
+
+	dData := schemas["dData"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"dname"}, dData["required"])
+	assert.Equal(t, "array", dData["properties"].(map[string]interface{})["dname"].(map[string]interface{})["type"])
+
+	// json:"-" 는 제외하고, 자기 자신을 포함하는 타입도 만들 수 있어야 함
+	apiKey := schemas["ApiKey"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.NotContains(t, apiKey, "KeyHash")
+	assert.NotContains(t, apiKey, "-")
+	reports := schemas["OrgNode"].(map[string]interface{})["properties"].(map[string]interface{})["reports"]
+	assert.Equal(t, "#/components/schemas/OrgNode", reports.(map[string]interface{})["items"].(map[string]interface{})["$ref"])
+	assert.Contains(t, schemas, "ApiError")
+}
+
+func TestOpenAPIAuditEnums(t *testing.T) {
+	paths := openapiPaths(t)
+	enums := map[string]interface{}{}
+	for _, parameter := range paths["/api/v2/audit/"]["get"].(map[string]interface{})["parameters"].([]interface{}) {
+		parameter := parameter.(map[string]interface{})
+		enums[parameter["name"].(string)] = parameter["schema"].(map[string]interface{})["enum"]
+	}
+	// recordAudit이 쓰는 동작이 모두 있어야 함
+	assert.Equal(t, []interface{}{"create", "update", "delete", "assign", "unassign", "restore", "purge"}, enums["action"])
+	assert.Equal(t, []interface{}{"employee", "department", "assignment"}, enums["entity_type"])
+}
+
+func TestOpenAPIFilterExample(t *testing.T) {
+	// 문서의 예시를 그대로 보내도 동작해야 함
+	_, apiErr := ParseFilter(specEmployeeFilterExample, employeeFilterFields(nil))
+	assert.Nil(t, apiErr)
+	_, apiErr = ParseFilter(specDepartmentFilterExample, departmentFilterFields)
+	assert.Nil(t, apiErr)
+}
+
+func TestOpenAPIServe(t *testing.T) {
+	router := SetupRouter()
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/openapi.json", nil)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec["openapi"])
+	assert.Contains(t, spec["components"].(map[string]interface{})["securitySchemes"], "bearerAuth")
+}
+
+func TestOpenAPIDocs(t *testing.T) {
+	router := SetupRouter()
+	request := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, request)
+		return w
+	}
+
+	// swagger-ui가 없으면 안내만. 외부 script를 불러오지 않음
+	t.Setenv("SWAGGER_UI_DIR", "")
+	w := request("/docs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Equal(t, docsCSP, w.Header().Get("Content-Security-Policy"))
+	assert.Contains(t, w.Body.String(), "/openapi.json")
+	assert.NotContains(t, w.Body.String(), "<script")
+	assert.Equal(t, http.StatusNotFound, request("/docs/swagger-ui-bundle.js").Code)
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "swagger-ui-bundle.js"), []byte("var SwaggerUIBundle;"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "swagger-ui.css"), []byte("body {}"), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0600))
+	t.Setenv("SWAGGER_UI_DIR", dir)
+
+	// 모든 파일은 같은 origin에서
+	w = request("/docs")
+	assert.Contains(t, w.Body.String(), `src="/docs/swagger-ui-bundle.js"`)
+	assert.NotContains(t, w.Body.String(), "https://")
+	w = request("/docs/swagger-ui-bundle.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "var SwaggerUIBundle;", w.Body.String())
+	w = request("/docs/swagger-initializer.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "/openapi.json")
+	// 목록에 없는 파일은 제공하지 않음
+	assert.Equal(t, http.StatusNotFound, request("/docs/secret.txt").Code)
+	assert.Equal(t, http.StatusNotFound, request("/docs/..%2fopenapi.go").Code)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 조직도 조회 깊이의 기본값과 최대값
+const (
+	defaultOrgDepth = 5
+	maxOrgDepth     = 20
+)
+
+// 조직도의 사원 한 명과 부하 직원
+type OrgNode struct {
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	JobTitle string     `json:"jobTitle,omitempty"`
+	Status   string     `json:"status"`
+	Reports  []*OrgNode `json:"reports,omitempty"`
+	HasMore  bool       `json:"hasMore,omitempty"` // depth 제한으로 표시하지 않은 부하가 있음
+}
+
+type managerData struct {
+	ManagerID uint `json:"manager_id"` // 0이면 상사 없음
+}
+
+func newOrgNode(employee Employee) *OrgNode {
+	return &OrgNode{ID: employee.ID, Name: employee.Employee_Name, JobTitle: employee.JobTitle, Status: employee.Status}
+}
+
+/* 상사를 지정할 수 있는지 확인. 자기 자신이나 자기 부하를 상사로 지정하면 순환이 생김. 상사를 바꾸는 transaction 안에서 호출 */
+func validateManager(tx *gorm.DB, employeeID uint, managerID uint) *ApiError {
+	if managerID == employeeID {
+		return InvalidParameter("manager_id", "Employee cannot be own manager")
+	}
+	if apiErr := checkManager(tx, managerID); apiErr != nil {
+		return apiErr
+	}
+	// 사원과 새 상사부터 위의 사원들을 잠가서 동시에 서로를 상사로 지정해도 순환이 생기지 않음
+	locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+	locked.Select("id").Where("id = ?", employeeID).Find(&Employee{})
+
+	// 새 상사부터 위로 올라가면서 employee가 나오면 순환
+	visited := map[uint]bool{}
+	current := managerID
+	for current != 0 && !visited[current] {
+		if current == employeeID {
+			return NewApiError(http.StatusConflict, CodeManagerCycle, "Manager is one of the employee's reports")
+		}
+		visited[current] = true
+
+		var manager Employee
+		locked.Select("id", "manager_id").Where("id = ?", current).Find(&manager)
+		current = 0
+		if manager.ManagerID != nil {
+			current = *manager.ManagerID
+		}
+	}
+	return nil
+}
+
+/* 삭제된 사원의 부하는 삭제된 사원의 상사에게 보고 */
+func reparentReports(tx *gorm.DB, employee Employee) error {
+	var ids []uint
+	err := tx.Model(&Employee{}).Where("manager_id = ?", employee.ID).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	err = tx.Model(&Employee{}).Where("id IN ?", ids).Update("manager_id", employee.ManagerID).Error
+	if err != nil {
+		return err
+	}
+	return recordEmployeeHistory(tx, HistoryUpdated, ids...)
+}
+
+/* root들의 부하를 depth 단계까지 채움. 단계마다 한 번씩 조회 */
+func fillReports(tx *gorm.DB, roots []*OrgNode, depth int) error {
+	level := roots
+	visited := map[uint]bool{}
+	for _, node := range roots {
+		visited[node.ID] = true
+	}
+
+	for d := 0; len(level) > 0; d++ {
+		ids := make([]uint, 0, len(level))
+		nodes := make(map[uint]*OrgNode, len(level))
+		for _, node := range level {
+			ids = append(ids, node.ID)
+			nodes[node.ID] = node
+		}
+
+		var reports []Employee
+		err := tx.Where("manager_id IN ?", ids).Order("employee_name asc, id asc").Find(&reports).Error
+		if err != nil {
+			return err
+		}
+
+		next := make([]*OrgNode, 0, len(reports))
+		for _, report := range reports {
+			manager := nodes[*report.ManagerID]
+			if d >= depth {
+				manager.HasMore = true
+				continue
+			}
+			if visited[report.ID] { // 데이터가 잘못되어 순환이 있어도 멈추도록
+				continue
+			}
+			visited[report.ID] = true
+
+			node := newOrgNode(report)
+			manager.Reports = append(manager.Reports, node)
+			next = append(next, node)
+		}
+		if d >= depth {
+			break
+		}
+		level = next
+	}
+	return nil
+}
+
+/* depth query. 없으면 기본값, 최대값보다 크면 최대값 */
+func orgDepth(c *gin.Context) (int, *ApiError) {
+	value := c.Query("depth")
+	if value == "" {
+		return defaultOrgDepth, nil
+	}
+
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 1 {
+		return 0, InvalidParameter("depth", "depth should be a positive number")
+	}
+	if depth > maxOrgDepth {
+		depth = maxOrgDepth
+	}
+	return depth, nil
+}
+
+func findOrgEmployee(c *gin.Context) (employee Employee, ok bool) {
+	db.Where("id = ?", c.Param("id")).Find(&employee)
+	if employee.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeEmployeeNotFound, "No such employee"))
+		return employee, false
+	}
+	return employee, true
+}
+
+/* 바로 아래 부하 직원 목록 */
+func ReadDirectReports(c *gin.Context) {
+	employee, ok := findOrgEmployee(c)
+	if !ok {
+		return
+	}
+
+	var reports []Employee
+	result := db.Where("manager_id = ?", employee.ID).Order("employee_name asc, id asc").Find(&reports)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
+
+	nodes := make([]*OrgNode, 0, len(reports))
+	for _, report := range reports {
+		nodes = append(nodes, newOrgNode(report))
+	}
+	c.JSON(http.StatusOK, nodes)
+}
+
+/* 사원 아래의 전체 조직. ?depth= 단계까지 */
+func ReadSubtree(c *gin.Context) {
+	depth, apiErr := orgDepth(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	employee, ok := findOrgEmployee(c)
+	if !ok {
+		return
+	}
+
+	root := newOrgNode(employee)
+	err := fillReports(db, []*OrgNode{root}, depth)
+	if err != nil {
+		AbortWithInternalError(c, err, "Read Error")
+		return
+	}
+
+	c.JSON(http.StatusOK, root)
+}
+
+/* 바로 위 상사부터 최상위까지의 보고 라인 */
+func ReadManagementChain(c *gin.Context) {
+	employee, ok := findOrgEmployee(c)
+	if !ok {
+		return
+	}
+
+	chain := make([]*OrgNode, 0)
+	visited := map[uint]bool{employee.ID: true}
+	current := employee.ManagerID
+	for current != nil && !visited[*current] {
+		visited[*current] = true
+
+		var manager Employee
+		db.Where("id = ?", *current).Find(&manager)
+		if manager.ID == 0 {
+			break
+		}
+		chain = append(chain, newOrgNode(manager))
+		current = manager.ManagerID
+	}
+
+	c.JSON(http.StatusOK, chain)
+}
+
+/* 상사가 없는 사원부터 시작하는 전체 조직도 */
+func ExportOrgChart(c *gin.Context) {
+	depth, apiErr := orgDepth(c)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	var employees []Employee
+	result := db.Where("manager_id IS NULL").Order("employee_name asc, id asc").Find(&employees)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
+
+	roots := make([]*OrgNode, 0, len(employees))
+	for _, employee := range employees {
+		roots = append(roots, newOrgNode(employee))
+	}
+	err := fillReports(db, roots, depth-1)
+	if err != nil {
+		AbortWithInternalError(c, err, "Read Error")
+		return
+	}
+
+	c.JSON(http.StatusOK, roots)
+}
+
+/* 상사 변경. 순환이 생기면 409 */
+func UpdateManager(c *gin.Context) {
+	var data managerData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	employee, _, ok := updateManager(c, data.ManagerID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":      "Manager Update Complete",
+		"employee": newOrgNode(employee),
+	})
+}
+
+// v2 상사 변경 body
+type managerRequest struct {
+	ManagerID uint `json:"managerId"` // 0이면 상사 없음
+}
+
+/* v2 상사 변경. 바뀐 사원을 상사와 함께 응답 */
+func UpdateManagerV2(c *gin.Context) {
+	var data managerRequest
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	_, after, ok := updateManager(c, data.ManagerID)
+	if !ok {
+		return
+	}
+
+	db.Where("id = ?", after.ID).Preload("Manager").Find(&after)
+	c.JSON(http.StatusOK, newEmployeeResponse(after))
+}
+
+/* :id 사원의 상사 변경. 바뀌기 전과 후의 사원을 돌려줌. 실패하면 에러 응답 후 false */
+func updateManager(c *gin.Context, newManagerID uint) (employee Employee, after Employee, ok bool) {
+	employee, ok = findOrgEmployee(c)
+	if !ok {
+		return
+	}
+
+	var managerID interface{}
+	if newManagerID != 0 {
+		managerID = newManagerID
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if newManagerID != 0 {
+			if apiErr := validateManager(tx, employee.ID, newManagerID); apiErr != nil {
+				return apiErr
+			}
+		}
+		err := tx.Model(&Employee{}).Where("id = ?", employee.ID).Update("manager_id", managerID).Error
+		if err != nil {
+			return err
+		}
+		err = recordEmployeeHistory(tx, HistoryUpdated, employee.ID)
+		if err != nil {
+			return err
+		}
+
+		tx.Where("id = ?", employee.ID).Find(&after)
+		return recordAudit(c, tx, AuditUpdate, EntityEmployee, employee.ID, employee, after)
+	})
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		AbortWithError(c, apiErr)
+		return employee, after, false
+	} else if err != nil {
+		AbortWithInternalError(c, err, "Update error")
+		return employee, after, false
+	}
+	return employee, after, true
+}
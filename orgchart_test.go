@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+/* A -> B -> C -> D, A -> E 구조의 조직 */
+func orgTestData(t *testing.T) (*gin.Engine, string, map[string]*Employee) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/org/", ExportOrgChart)
+	router.GET("/api/org/:id/reports", ReadDirectReports)
+	router.GET("/api/org/:id/subtree", ReadSubtree)
+	router.GET("/api/org/:id/chain", ReadManagementChain)
+	router.PUT("/api/org/:id/manager", UpdateManager)
+	router.DELETE("/api/employee/id/:id", DeleteEmployeById)
+
+	employees := map[string]*Employee{}
+	for _, pair := range [][2]string{{"A", ""}, {"B", "A"}, {"C", "B"}, {"D", "C"}, {"E", "A"}} {
+		employee := &Employee{Employee_Name: "Org " + pair[0]}
+		if manager, ok := employees[pair[1]]; ok {
+			employee.ManagerID = &manager.ID
+		}
+		db.Create(employee)
+		employees[pair[0]] = employee
+	}
+	t.Cleanup(func() {
+		for _, employee := range employees {
+			db.Unscoped().Delete(employee)
+		}
+	})
+
+	return router, token, employees
+}
+
+func orgRequest(router *gin.Engine, token string, method string, url string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	return w
+}
+
+func TestOrgReportsAndChain(t *testing.T) {
+	router, token, employees := orgTestData(t)
+
+	w := orgRequest(router, token, "GET", fmt.Sprintf("/api/org/%d/reports", employees["A"].ID), "")
+	var reports []OrgNode
+	err := json.Unmarshal(w.Body.Bytes(), &reports)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 2, len(reports)) {
+		assert.Equal(t, "Org B", reports[0].Name)
+		assert.Equal(t, "Org E", reports[1].Name)
+	}
+
+	w = orgRequest(router, token, "GET", fmt.Sprintf("/api/org/%d/chain", employees["D"].ID), "")
+	var chain []OrgNode
+	err = json.Unmarshal(w.Body.Bytes(), &chain)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 3, len(chain)) {
+		assert.Equal(t, employees["C"].ID, chain[0].ID)
+		assert.Equal(t, employees["A"].ID, chain[2].ID)
+	}
+
+	w = orgRequest(router, token, "GET", "/api/org/-1/chain", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestOrgSubtreeAndExport(t *testing.T) {
+	router, token, employees := orgTestData(t)
+
+	// depth=2면 C까지, D는 hasMore로 표시
+	w := orgRequest(router, token, "GET", fmt.Sprintf("/api/org/%d/subtree?depth=2", employees["A"].ID), "")
+	var root OrgNode
+	err := json.Unmarshal(w.Body.Bytes(), &root)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	if assert.Equal(t, 2, len(root.Reports)) && assert.Equal(t, 1, len(root.Reports[0].Reports)) {
+		c := root.Reports[0].Reports[0]
+		assert.Equal(t, "Org C", c.Name)
+		assert.Empty(t, c.Reports)
+		assert.True(t, c.HasMore)
+	}
+
+	w = orgRequest(router, token, "GET", "/api/org/?depth=0", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = orgRequest(router, token, "GET", "/api/org/", "")
+	var roots []OrgNode
+	err = json.Unmarshal(w.Body.Bytes(), &roots)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	found := false
+	for _, node := range roots {
+		if node.ID == employees["A"].ID {
+			found = true
+			assert.Equal(t, "Org D", node.Reports[0].Reports[0].Reports[0].Name)
+		}
+		assert.NotEqual(t, employees["B"].ID, node.ID)
+	}
+	assert.True(t, found)
+}
+
+func TestOrgUpdateManager(t *testing.T) {
+	router, token, employees := orgTestData(t)
+
+	// A의 상사를 A의 부하(D)로 바꾸면 순환
+	w := orgRequest(router, token, "PUT", fmt.Sprintf("/api/org/%d/manager", employees["A"].ID), fmt.Sprintf(`{"manager_id": %d}`, employees["D"].ID))
+	var apiErr ApiError
+	err := json.Unmarshal(w.Body.Bytes(), &apiErr)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodeManagerCycle, apiErr.Code)
+
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/org/%d/manager", employees["A"].ID), fmt.Sprintf(`{"manager_id": %d}`, employees["A"].ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// D를 E 아래로
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/org/%d/manager", employees["D"].ID), fmt.Sprintf(`{"manager_id": %d}`, employees["E"].ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result Employee
+	db.Where("id = ?", employees["D"].ID).Find(&result)
+	assert.Equal(t, employees["E"].ID, *result.ManagerID)
+
+	// 상사 없음
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/org/%d/manager", employees["D"].ID), `{"manager_id": 0}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var cleared Employee
+	db.Where("id = ?", employees["D"].ID).Find(&cleared)
+	assert.Nil(t, cleared.ManagerID)
+
+	// 삭제된 사원의 부하는 그 위 상사에게
+	w = orgRequest(router, token, "DELETE", fmt.Sprintf("/api/employee/id/%d", employees["B"].ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var reparented Employee
+	db.Where("id = ?", employees["C"].ID).Find(&reparented)
+	if assert.NotNil(t, reparented.ManagerID) {
+		assert.Equal(t, employees["A"].ID, *reparented.ManagerID)
+	}
+}
+
+func TestUpdateManagerV2(t *testing.T) {
+	router, token, employees := orgTestData(t)
+	router.PUT("/api/v2/org/:id/manager", UpdateManagerV2)
+
+	w := orgRequest(router, token, "PUT", fmt.Sprintf("/api/v2/org/%d/manager", employees["D"].ID), fmt.Sprintf(`{"managerId": %d}`, employees["E"].ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var employee EmployeeResponse
+	err := json.Unmarshal(w.Body.Bytes(), &employee)
+	assert.NoError(t, err)
+	assert.Equal(t, employees["E"].ID, *employee.ManagerID)
+	if assert.NotNil(t, employee.Manager) {
+		assert.Equal(t, "Org E", employee.Manager.Name)
+	}
+
+	// 순환은 v1과 같이 409
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/v2/org/%d/manager", employees["A"].ID), fmt.Sprintf(`{"managerId": %d}`, employees["C"].ID))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = orgRequest(router, token, "PUT", fmt.Sprintf("/api/v2/org/%d/manager", employees["D"].ID), `{"managerId": 0}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	employee = EmployeeResponse{}
+	json.Unmarshal(w.Body.Bytes(), &employee)
+	assert.Nil(t, employee.ManagerID)
+	assert.Nil(t, employee.Manager)
+}
+
+func TestValidateManagerInTransaction(t *testing.T) {
+	_, _, employees := orgTestData(t)
+	d, e := employees["D"], employees["E"]
+
+	// 같은 transaction에서 먼저 바꾼 상사까지 보고 순환을 확인
+	err := db.Transaction(func(tx *gorm.DB) error {
+		assert.Nil(t, validateManager(tx, d.ID, e.ID))
+		assert.NoError(t, tx.Model(&Employee{}).Where("id = ?", d.ID).Update("manager_id", e.ID).Error)
+
+		apiErr := validateManager(tx, e.ID, d.ID)
+		if assert.NotNil(t, apiErr) {
+			assert.Equal(t, http.StatusConflict, apiErr.Status)
+			assert.Equal(t, CodeManagerCycle, apiErr.Code)
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	var after Employee
+	db.Where("id = ?", d.ID).Find(&after)
+	assert.Equal(t, employees["C"].ID, *after.ManagerID)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const LocalCA = "LOCAL" // 아이디/비밀번호로 가입한 계정의 CA
+
+type passwordData struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=72"` // bcrypt는 72byte까지만 사용
+}
+
+/* 비밀번호를 bcrypt hash로 변환 */
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+/* 저장된 hash와 비밀번호 비교. 다르면 error */
+func (account *Account) CheckPassword(password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password))
+}
+
+/* 아이디/비밀번호 계정 가입 후 바로 토큰 발급 */
+func Register(c *gin.Context) {
+	var data passwordData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(data.Email))
+
+	var dbAccount Account
+	db.Where("Email = ? AND CA = ?", email, LocalCA).Find(&dbAccount)
+	if dbAccount.ID != 0 {
+		AbortWithError(c, NewApiError(http.StatusConflict, CodeAccountExists, "Account already exists"))
+		return
+	}
+
+	hash, err := HashPassword(data.Password)
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on Creating Account")
+		return
+	}
+
+	// email 소유를 확인하지 않으므로 ADMIN_EMAILS에 있어도 viewer로 가입
+	account := Account{Email: email, CA: LocalCA, Role: RoleViewer, PasswordHash: hash}
+
+	result := db.Create(&account)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Error on Creating Account")
+		return
+	}
+
+	IssueSession(c, http.StatusCreated, account, true)
+}
+
+/* 아이디/비밀번호로 토큰 발급 */
+func PasswordLogin(c *gin.Context) {
+	var data passwordData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+
+	var account Account
+	db.Where("Email = ? AND CA = ?", strings.ToLower(strings.TrimSpace(data.Email)), LocalCA).Find(&account)
+	if account.ID == 0 || account.CheckPassword(data.Password) != nil {
+		// 계정이 없는지 비밀번호가 틀렸는지 구분하지 않음
+		abortUnauthorized(c, "Incorrect email or password")
+		return
+	}
+
+	IssueSession(c, http.StatusOK, account, false)
+}
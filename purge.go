@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 삭제된 사원, 부서를 보관하는 기본 기간(일). PURGE_RETENTION_DAYS로 변경
+const defaultPurgeRetentionDays = 30
+
+// purge 결과
+type purgeResult struct {
+	Before      time.Time `json:"before"` // 이 시간 전에 삭제된 Row를 지움
+	Employees   int       `json:"employees"`
+	Departments int       `json:"departments"`
+}
+
+/* 보존 기간(일). 0이면 삭제된 Row를 모두 지움 */
+func purgeRetentionDays(value string) (int, error) {
+	if value == "" {
+		value = os.Getenv("PURGE_RETENTION_DAYS")
+	}
+	if value == "" {
+		return defaultPurgeRetentionDays, nil
+	}
+
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		return 0, errors.New("retention days should be 0 or a positive number")
+	}
+	return days, nil
+}
+
+/* before 전에 삭제(soft delete)된 사원, 부서와 그 소속을 완전히 삭제. 이력과 감사 기록은 남김 */
+func purgeDeleted(c *gin.Context, before time.Time) (result purgeResult, err error) {
+	result.Before = before
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var employees []Employee
+		err := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&employees).Error
+		if err != nil {
+			return err
+		}
+		for _, employee := range employees {
+			// 지워지는 사원을 상사로 가진 사원(삭제된 사원 포함)은 상사 없음
+			err = clearManager(c, tx, employee.ID)
+			if err != nil {
+				return err
+			}
+			err = tx.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Where("employee_id = ?", employee.ID).Delete(&EmployeeSearch{}).Error
+			if err == nil {
+				err = tx.Where("employee_id = ?", employee.ID).Delete(&EmployeeTrigram{}).Error
+			}
+			if err != nil {
+				return err
+			}
+			err = tx.Unscoped().Delete(&employee).Error
+			if err != nil {
+				return err
+			}
+			err = recordAudit(c, tx, AuditPurge, EntityEmployee, employee.ID, employee, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		var departments []Department
+		err = tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&departments).Error
+		if err != nil {
+			return err
+		}
+		for _, department := range departments {
+			// 하위 부서(삭제된 부서 포함)는 최상위 부서가 됨
+			err = reparentChildren(c, tx, department.ID, nil, true)
+			if err != nil {
+				return err
+			}
+			err = tx.Unscoped().Where("department_id = ?", department.ID).Delete(&Assignment{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Unscoped().Delete(&department).Error
+			if err != nil {
+				return err
+			}
+			err = recordAudit(c, tx, AuditPurge, EntityDepartment, department.ID, department, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Employees = len(employees)
+		result.Departments = len(departments)
+		return nil
+	})
+	return
+}
+
+/* managerID 사원의 부하(삭제된 사원 포함)를 상사 없음으로 바꾸고 사원마다 감사 기록과 이력을 남김 */
+func clearManager(c *gin.Context, tx *gorm.DB, managerID uint) error {
+	var reports []Employee
+	err := tx.Unscoped().Where("manager_id = ?", managerID).Find(&reports).Error
+	if err != nil || len(reports) == 0 {
+		return err
+	}
+
+	ids := make([]uint, len(reports))
+	for i, report := range reports {
+		ids[i] = report.ID
+		err = tx.Unscoped().Model(&Employee{}).Where("id = ?", report.ID).Update("manager_id", nil).Error
+		if err != nil {
+			return err
+		}
+		after := report
+		after.ManagerID = nil
+		err = recordAudit(c, tx, AuditUpdate, EntityEmployee, report.ID, report, after)
+		if err != nil {
+			return err
+		}
+	}
+	// 삭제된 사원은 이력을 남기지 않음
+	return recordEmployeeHistory(tx, HistoryUpdated, ids...)
+}
+
+/* 보존 기간이 지난 삭제된 사원, 부서를 완전히 삭제. ?retention_days=로 기간 변경 */
+func PurgeDeleted(c *gin.Context) {
+	days, err := purgeRetentionDays(c.Query("retention_days"))
+	if err != nil {
+		AbortWithError(c, InvalidParameter("retention_days", err.Error()))
+		return
+	}
+
+	result, err := purgeDeleted(c, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		AbortWithInternalError(c, err, "Purge error")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+/* myapi purge [days]. cron 등에서 주기적으로 실행 */
+func RunPurgeCommand(args []string) error {
+	value := ""
+	if len(args) > 0 {
+		value = args[0]
+	}
+	days, err := purgeRetentionDays(value)
+	if err != nil {
+		return err
+	}
+
+	result, err := purgeDeleted(nil, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("purged %d employees, %d departments deleted before %s\n", result.Employees, result.Departments, result.Before.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeDeleted(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/api/admin/purge", PurgeDeleted)
+
+	old := Employee{Employee_Name: "Purge Old"}
+	recent := Employee{Employee_Name: "Purge Recent"}
+	report := Employee{Employee_Name: "Purge Report"}
+	db.Create(&old)
+	db.Create(&recent)
+	report.ManagerID = &old.ID
+	db.Create(&report)
+	department := Department{Department_Name: "Purge Department"}
+	db.Create(&department)
+	child := Department{Department_Name: "Purge Child", ParentID: &department.ID}
+	db.Create(&child)
+	db.Create(&Assignment{EmployeeID: old.ID, DepartmentID: department.ID})
+	t.Cleanup(func() {
+		db.Unscoped().Delete(&[]Employee{old, recent, report})
+		db.Unscoped().Delete(&[]Department{department, child})
+	})
+
+	// 40일 전에 삭제된 사원, 부서와 방금 삭제된 사원
+	db.Delete(&old)
+	db.Delete(&recent)
+	db.Delete(&department)
+	db.Unscoped().Model(&Employee{}).Where("id = ?", old.ID).Update("deleted_at", time.Now().AddDate(0, 0, -40))
+	db.Unscoped().Model(&Department{}).Where("id = ?", department.ID).Update("deleted_at", time.Now().AddDate(0, 0, -40))
+
+	w := orgRequest(router, token, "POST", "/api/admin/purge", "")
+	var result purgeResult
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, result.Employees, 1)
+	assert.GreaterOrEqual(t, result.Departments, 1)
+
+	var count int64
+	db.Unscoped().Model(&Employee{}).Where("id IN ?", []uint{old.ID, recent.ID}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Unscoped().Model(&Department{}).Where("id = ?", department.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+	db.Unscoped().Model(&Assignment{}).Where("employee_id = ?", old.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	var orphan Employee
+	db.Where("id = ?", report.ID).Find(&orphan)
+	assert.Nil(t, orphan.ManagerID)
+
+	var logs []AuditLog
+	db.Where("action = ? AND entity_type = ? AND entity_id = ?", AuditPurge, EntityEmployee, old.ID).Find(&logs)
+	assert.Equal(t, 1, len(logs))
+
+	// 상사가 없어진 사원과 상위 부서가 없어진 부서도 감사 기록과 이력
+	db.Where("action = ? AND entity_type = ? AND entity_id = ?", AuditUpdate, EntityEmployee, report.ID).Order("id desc").Limit(1).Find(&logs)
+	if assert.Equal(t, 1, len(logs)) {
+		assert.Equal(t, map[string]interface{}{"from": float64(old.ID), "to": nil}, logs[0].Changes["managerId"])
+	}
+	var history EmployeeHistory
+	db.Where("employee_id = ?", report.ID).Order("id desc").Limit(1).Find(&history)
+	assert.Contains(t, history.Changes, "managerId")
+	db.Where("action = ? AND entity_type = ? AND entity_id = ?", AuditUpdate, EntityDepartment, child.ID).Order("id desc").Limit(1).Find(&logs)
+	if assert.Equal(t, 1, len(logs)) {
+		assert.Equal(t, map[string]interface{}{"from": float64(department.ID), "to": nil}, logs[0].Changes["parentId"])
+	}
+
+	w = orgRequest(router, token, "POST", "/api/admin/purge?retention_days=-1", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRunPurgeCommand(t *testing.T) {
+	err := RunPurgeCommand([]string{"abc"})
+	assert.Error(t, err)
+
+	err = RunPurgeCommand([]string{"3650"})
+	assert.NoError(t, err)
+}
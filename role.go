@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Account 권한. admin > hr-editor > viewer 순서로 상위 권한은 하위 권한을 포함
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "hr-editor"
+	RoleAdmin  = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+type roleData struct {
+	Role string `json:"role" binding:"required"`
+}
+
+/* role이 required 이상의 권한인지 확인 */
+func HasRole(role string, required string) bool {
+	return roleLevels[role] > 0 && roleLevels[role] >= roleLevels[required]
+}
+
+// ADMIN_EMAILS를 확인할 OAuth 제공자 기본값. ADMIN_CA로 변경
+const defaultAdminCA = "GOOGLE"
+
+/* ADMIN_EMAILS 환경변수(콤마로 구분)에 포함된 ADMIN_CA 계정인지 확인. 최초 admin 생성용이고 email 확인은 호출하는 쪽에서 */
+func IsBootstrapAdmin(email string, ca string) bool {
+	adminCA := os.Getenv("ADMIN_CA")
+	if adminCA == "" {
+		adminCA = defaultAdminCA
+	}
+	if !strings.EqualFold(ca, adminCA) {
+		return false
+	}
+
+	for _, adminEmail := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		adminEmail = strings.TrimSpace(adminEmail)
+		if adminEmail != "" && strings.EqualFold(adminEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+/* AuthorizeAccount 뒤에서 사용. 토큰의 role이 required 이상이어야 통과 */
+func RequireRole(required string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasRole(c.GetString("role"), required) {
+			AbortWithError(c, NewApiError(http.StatusForbidden, CodeForbidden, "Permission denied. Required role: "+required))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+/* 전체 Account 목록 (admin) */
+func ReadAccount(c *gin.Context) {
+	var accounts []Account
+
+	result := db.Order("id asc").Find(&accounts)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Read Error")
+		return
+	}
+
+	c.JSON(http.StatusOK, newAccountResponses(accounts))
+}
+
+/* Account에 role 부여 (admin) */
+func GrantRole(c *gin.Context) {
+	var data roleData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+
+	if _, ok := roleLevels[data.Role]; !ok {
+		AbortWithError(c, invalidRole("No such role: "+data.Role))
+		return
+	}
+
+	setAccountRole(c, data.Role)
+}
+
+/* Account의 role을 기본값(viewer)으로 되돌림 (admin) */
+func RevokeRole(c *gin.Context) {
+	setAccountRole(c, RoleViewer)
+}
+
+func setAccountRole(c *gin.Context, role string) {
+	var account Account
+	db.Where("id = ?", c.Param("id")).Find(&account)
+	if account.ID == 0 {
+		AbortWithError(c, NewApiError(http.StatusNotFound, CodeAccountNotFound, "No such account"))
+		return
+	}
+
+	// 마지막 admin이 사라지면 다시 권한을 줄 수 있는 계정이 없어짐
+	if account.Role == RoleAdmin && role != RoleAdmin {
+		var admins int64
+		db.Model(&Account{}).Where("role = ?", RoleAdmin).Count(&admins)
+		if admins <= 1 {
+			AbortWithError(c, NewApiError(http.StatusConflict, CodeConflict, "Cannot revoke the last admin"))
+			return
+		}
+	}
+
+	result := db.Model(&account).Update("role", role)
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Update error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg":     "Role Update Complete. New role is applied after re-login",
+		"account": newAccountResponse(account),
+	})
+}
+
+/* Migration 적용 현황 (admin) */
+func ReadMigrationStatus(c *gin.Context) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		AbortWithInternalError(c, err, "Cannot read migration status")
+		return
+	}
+
+	c.JSON(http.StatusOK, states)
+}
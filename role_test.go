@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/employee/", RequireRole(RoleViewer), ReadEmployee)
+	router.POST("/api/employee/", RequireRole(RoleEditor), AddEmployee)
+
+	token, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	// viewer는 조회 가능
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/api/employee/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// viewer는 추가 불가
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/api/employee/", bytes.NewBufferString(`[{"ename": "Role Test"}]`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// role이 없는 토큰은 조회도 불가
+	token, err = GenerateToken("gotest", "myCA", "")
+	assert.NoError(t, err)
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", "/api/employee/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGrantAndRevokeRole(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount(), RequireRole(RoleAdmin))
+	router.PUT("/api/admin/account/:id/role", GrantRole)
+	router.DELETE("/api/admin/account/:id/role", RevokeRole)
+
+	account := Account{Email: "role@test.com", CA: "GOOGLE", Role: RoleViewer}
+	db.Create(&account)
+	requrl := "/api/admin/account/" + strconv.FormatUint(uint64(account.ID), 10) + "/role"
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("PUT", requrl, bytes.NewBufferString(`{"role": "hr-editor"}`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	var result Account
+	db.Where("id = ?", account.ID).Find(&result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, RoleEditor, result.Role)
+
+	// 없는 role
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("PUT", requrl, bytes.NewBufferString(`{"role": "owner"}`))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("DELETE", requrl, nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	db.Where("id = ?", account.ID).Find(&result)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, RoleViewer, result.Role)
+
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestRevokeLastAdmin(t *testing.T) {
+	token, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount(), RequireRole(RoleAdmin))
+	router.DELETE("/api/admin/account/:id/role", RevokeRole)
+
+	account := Account{Email: "admin@test.com", CA: "GOOGLE", Role: RoleAdmin}
+	db.Create(&account)
+
+	w := httptest.NewRecorder()
+	requrl := "/api/admin/account/" + strconv.FormatUint(uint64(account.ID), 10) + "/role"
+	request, _ := http.NewRequest("DELETE", requrl, nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestIsBootstrapAdmin(t *testing.T) {
+	t.Setenv("ADMIN_EMAILS", "boss@test.com, second@test.com")
+	assert.True(t, IsBootstrapAdmin("Boss@test.com", "GOOGLE"))
+	assert.True(t, IsBootstrapAdmin("second@test.com", "GOOGLE"))
+	assert.False(t, IsBootstrapAdmin("other@test.com", "GOOGLE"))
+	// ADMIN_CA 외의 CA는 email이 같아도 아님
+	assert.False(t, IsBootstrapAdmin("boss@test.com", "GITHUB"))
+	assert.False(t, IsBootstrapAdmin("boss@test.com", LocalCA))
+
+	t.Setenv("ADMIN_CA", "github")
+	assert.True(t, IsBootstrapAdmin("boss@test.com", "GITHUB"))
+	assert.False(t, IsBootstrapAdmin("boss@test.com", "GOOGLE"))
+}
@@ -7,51 +7,132 @@ import (
 /* API 세팅 */
 func SetupRouter() *gin.Engine {
 	r := gin.Default()
+	r.Use(RequestID())
+	r.NoRoute(NoRoute)
 
 	loginFunc := Login()
-	callbackFunc := LoginCallback()
 
 	// callback by oauth CA
-	r.GET("/auth/callback/google", callbackFunc)
-	r.GET("/auth/callback/facebook", callbackFunc)
-	r.GET("/auth/callback/github", callbackFunc)
+	r.GET("/auth/callback/google", LoginCallback("GOOGLE"))
+	r.GET("/auth/callback/facebook", LoginCallback("FACEBOOK"))
+	r.GET("/auth/callback/github", LoginCallback("GITHUB"))
 
-	r.POST("/init", InitTable)
-	r.DELETE("/delete", DeleteTable)
 	r.GET("/login/:CA", loginFunc)
+	r.POST("/auth/register", Register)
+	r.POST("/auth/token", PasswordLogin)
+	r.POST("/auth/refresh", RefreshSession)
+	r.POST("/auth/logout", AuthorizeAccount(), Logout)
 
-	// To run in Postman
-	api := r.Group("/api")
-	{
-		// Use를 통해 Middleware인 AuthorizeAccount를 가져와 MiddleWare에서 검증 진행
-		department := api.Group("/department").Use(AuthorizeAccount())
-		{
-			department.GET("/only", ReadDepartmentOnly)
-			department.GET("/", ReadDepartment)
-			department.GET("/:name", SearchDepartmentByName)
-			department.GET("/:name/employee", ReadEmployeeInDepartment) // 부서에 속한 직원 명단 가져오기
-			department.PUT("/", UpdateDepartment)
-			department.POST("/", AddDepartment)
-			department.DELETE("/:name", DeleteDepartment)
-		}
-		employee := api.Group("/employee").Use(AuthorizeAccount())
-		{
-			employee.GET("/", ReadEmployee)
-			employee.GET("/name/:name", SearchEmployeeByName)
-			employee.GET("/day/:days", SearchEmployeeByDay)
-			employee.PUT("/:id", UpdateEmployee)
-			employee.POST("/", AddEmployee)
-			employee.DELETE("/:name", DeleteEmployee)
-			employee.DELETE("/id/:id", DeleteEmployeById)
-		}
-		assign := api.Group("/assign").Use(AuthorizeAccount())
-		{
-			assign.POST("/:name/:department", AddEmployeeDepartment)
-			assign.POST("/id/:eid/:department", AddEmployeeDepartmentById)
-			assign.DELETE("/:name/:department", DeleteEmployeeDepartment)
-			assign.DELETE("/id/:eid/:department", DeleteEmployeeDepartmentById)
-		}
+	apiKey := r.Group("/auth/apikeys").Use(AuthorizeAccount())
+	{
+		apiKey.GET("/", ReadApiKey)
+		apiKey.POST("/", AddApiKey)
+		apiKey.DELETE("/:id", DeleteApiKey)
+	}
+	r.GET("/.well-known/jwks.json", ReadJWKS)
+
+	// API 문서. route를 추가하면 openapi.go의 apiOperations에도 추가
+	r.GET("/openapi.json", ReadOpenAPI)
+	r.GET("/docs", ReadDocs)
+	r.GET("/docs/:file", ReadDocsAsset)
+
+	// /api/v1, /api/v2. 버전마다 같은 route 목록을 등록하고 형식이 다른 route만 handler를 나눔
+	versions := apiVersions()
+	for _, version := range versions {
+		registerAPI(r.Group("/api/"+version.Name, version.Headers()), version)
 	}
+	// 버전 없는 /api 는 v1 (이전 client 호환)
+	registerAPI(r.Group("/api", versions[0].Headers()), versions[0])
 
 	return r
 }
+
+/* /api 아래 route 등록. version.Handler(v1, v2)는 버전별로 형식이 다른 handler. v2는 body와 응답이 camelCase이고 사원 삭제, 부서 소속 route는 사원 이름 대신 id 사용. 부서는 v2에서도 이름으로 찾음 */
+func registerAPI(api *gin.RouterGroup, version apiVersion) {
+	// 권한별 Middleware. AuthorizeAccount 뒤에 사용
+	viewer := RequireRole(RoleViewer) // 조회
+	editor := RequireRole(RoleEditor) // 추가, 수정, 삭제
+	admin := RequireRole(RoleAdmin)   // 계정 권한, Table 관리
+
+	// Use를 통해 Middleware인 AuthorizeAccount를 가져와 MiddleWare에서 검증 진행
+	department := api.Group("/department").Use(AuthorizeAccount())
+	{
+		department.GET("/", viewer, ReadDepartment)
+		department.GET("/:name", viewer, SearchDepartmentByName)
+		department.GET("/:name/employee", viewer, ReadEmployeeInDepartment) // 부서에 속한 직원 명단 가져오기
+		department.GET("/:name/children", viewer, ReadDepartmentChildren)
+		department.GET("/:name/descendants", viewer, ReadDepartmentDescendants)
+		department.PUT("/:name/parent", editor, MoveDepartment)
+		department.POST("/", editor, version.Handler(AddDepartment, AddDepartmentV2))
+		department.POST("/:name/restore", editor, RestoreDepartment)
+		department.DELETE("/:name", editor, DeleteDepartment)
+		if version.Number == 1 {
+			department.GET("/only", viewer, ReadDepartmentOnly) // /api/department/?include= 와 같음
+			department.PUT("/", editor, UpdateDepartment)
+		} else {
+			department.PUT("/:name", editor, RenameDepartmentV2)
+		}
+	}
+	employee := api.Group("/employee").Use(AuthorizeAccount())
+	{
+		employee.GET("/", viewer, ReadEmployee)
+		employee.GET("/name/:name", viewer, SearchEmployeeByName)
+		employee.GET("/search", viewer, SearchEmployees) // ?q=. 이름, 이메일 등을 오타까지 허용해서 검색
+		employee.GET("/day/:days", viewer, SearchEmployeeByDay)
+		employee.GET("/:id/assignments", viewer, ReadAssignment) // ?include_ended=true면 끝난 소속 포함
+		employee.GET("/:id/history", viewer, ReadEmployeeHistory)
+		employee.PUT("/:id", editor, version.Handler(UpdateEmployee, UpdateEmployeeV2))
+		employee.POST("/", editor, version.Handler(AddEmployee, AddEmployeeV2))
+		employee.POST("/:id/restore", editor, RestoreEmployee)
+		if version.Number == 1 {
+			employee.DELETE("/:name", editor, DeleteEmployee)
+			employee.DELETE("/id/:id", editor, DeleteEmployeById)
+		} else {
+			employee.DELETE("/:id", editor, DeleteEmployeById)
+		}
+	}
+	org := api.Group("/org").Use(AuthorizeAccount())
+	{
+		org.GET("/", viewer, ExportOrgChart) // 상사가 없는 사원부터 nested JSON
+		org.GET("/:id/reports", viewer, ReadDirectReports)
+		org.GET("/:id/subtree", viewer, ReadSubtree)
+		org.GET("/:id/chain", viewer, ReadManagementChain)
+		org.PUT("/:id/manager", editor, version.Handler(UpdateManager, UpdateManagerV2))
+	}
+	assign := api.Group("/assign").Use(AuthorizeAccount(), editor)
+	if version.Number == 1 {
+		assign.POST("/:name/:department", AddEmployeeDepartment)
+		assign.POST("/id/:eid/:department", AddEmployeeDepartmentById)
+		assign.PUT("/id/:eid/:department", UpdateAssignment)
+		assign.DELETE("/:name/:department", DeleteEmployeeDepartment) // 소속 종료. 기록은 남음
+		assign.DELETE("/id/:eid/:department", DeleteEmployeeDepartmentById)
+	} else {
+		assign.POST("/:eid/:department", AssignEmployeeV2)
+		assign.PUT("/:eid/:department", UpdateAssignmentV2)
+		assign.DELETE("/:eid/:department", EndAssignmentV2)
+	}
+	importGroup := api.Group("/import").Use(AuthorizeAccount(), editor)
+	{
+		importGroup.POST("/employees", ImportEmployees) // csv, xlsx. ?dry_run=true면 검증만
+		importGroup.POST("/departments", ImportDepartments)
+	}
+	export := api.Group("/export").Use(AuthorizeAccount(), viewer)
+	{
+		// ?format=csv|jsonl. 목록 조회와 같은 sort, include_deleted, as_of 사용
+		export.GET("/employees", ExportEmployees)
+		export.GET("/departments", ExportDepartments)
+		export.GET("/assignments", ExportAssignments)
+	}
+	adminGroup := api.Group("/admin").Use(AuthorizeAccount(), admin)
+	{
+		adminGroup.GET("/account", ReadAccount)
+		adminGroup.PUT("/account/:id/role", GrantRole)
+		adminGroup.DELETE("/account/:id/role", RevokeRole)
+		adminGroup.GET("/migrations", ReadMigrationStatus)
+		adminGroup.POST("/purge", PurgeDeleted) // 보존 기간이 지난 삭제된 사원, 부서를 완전히 삭제
+	}
+	audit := api.Group("/audit").Use(AuthorizeAccount(), admin)
+	{
+		audit.GET("/", ReadAudit) // ?actor=, ?entity_type=, ?entity_id=, ?action=, ?from=, ?to=
+	}
+}
@@ -3,8 +3,11 @@ package main
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -12,8 +15,61 @@ func TestRouter(t *testing.T) {
 	router := SetupRouter()
 
 	w := httptest.NewRecorder()
-	request, _ := http.NewRequest("GET", "/hi", nil)
+	request, _ := http.NewRequest("GET", "/api/employee/", nil) // 토큰 없이 요청
 	router.ServeHTTP(w, request)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRouterVersions(t *testing.T) {
+	router := SetupRouter()
+	request := func(method string, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		request, _ := http.NewRequest(method, url, nil)
+		router.ServeHTTP(w, request)
+		return w
+	}
+
+	// v1과 버전 없는 /api 는 deprecated
+	for _, url := range []string{"/api/v1/employee/", "/api/employee/"} {
+		w := request("GET", url)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, url)
+		assert.Equal(t, "@"+strconv.FormatInt(apiV2Released.Unix(), 10), w.Header().Get("Deprecation"), url)
+		assert.NotEmpty(t, w.Header().Get("Sunset"), url)
+	}
+	w := request("GET", "/api/v2/employee/")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+	assert.Empty(t, w.Header().Get("Sunset"))
+
+	// 버전에만 있는 route
+	for _, route := range [][2]string{{"PUT", "/api/v1/department/"}, {"DELETE", "/api/v1/employee/id/1"}, {"POST", "/api/v1/assign/id/1/Sales"}, {"PUT", "/api/v2/department/Sales"}, {"POST", "/api/v2/assign/1/Sales"}} {
+		w = request(route[0], route[1])
+		assert.Equal(t, http.StatusUnauthorized, w.Code, route)
+	}
+	for _, route := range [][2]string{{"PUT", "/api/v2/department/"}, {"DELETE", "/api/v2/employee/id/1"}, {"POST", "/api/v2/assign/id/1/Sales"}, {"PUT", "/api/v1/department/Sales"}} {
+		w = request(route[0], route[1])
+		assert.Equal(t, http.StatusNotFound, w.Code, route)
+	}
+}
+
+func TestApiVersions(t *testing.T) {
+	t.Setenv("API_V1_SUNSET", "2027-01-31")
+	versions := apiVersions()
+	if assert.Equal(t, 2, len(versions)) {
+		assert.Equal(t, time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC), versions[0].Sunset)
+		assert.True(t, versions[1].Deprecated.IsZero())
+	}
+
+	t.Setenv("API_V1_SUNSET", "soon")
+	assert.Equal(t, apiV2Released.AddDate(0, defaultV1SunsetMonths, 0), apiVersions()[0].Sunset)
+
+	// 새 버전에 handler가 없으면 이전 버전의 handler 사용
+	v1 := func(c *gin.Context) { c.String(http.StatusOK, "v1") }
+	for _, version := range []apiVersion{{Number: 1}, {Number: 2}, {Number: 3}} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		version.Handler(v1)(c)
+		assert.Equal(t, "v1", w.Body.String())
+	}
 }
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 사원 검색용 정규화된 문자열. 소문자, 악센트 제거
+type EmployeeSearch struct {
+	EmployeeID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name       string `gorm:"size:255"`
+	Email      string `gorm:"size:255"`
+	JobTitle   string `gorm:"size:128"`
+	Phone      string `gorm:"size:32"`
+}
+
+func (EmployeeSearch) TableName() string { return "employee_search" }
+
+// 오타를 허용하는 검색에 사용하는 trigram 색인
+type EmployeeTrigram struct {
+	ID         uint   `gorm:"primaryKey"`
+	EmployeeID uint   `gorm:"index"`
+	Trigram    string `gorm:"size:16;index"`
+}
+
+// 검색 결과의 한 사원
+type SearchHit struct {
+	Employee EmployeeResponse `json:"employee"`
+	Score    float64          `json:"score"`   // 0~1. 높을수록 가까움
+	Matched  []string         `json:"matched"` // 일치한 항목
+}
+
+// 검색 항목과 가중치. 이름이 가장 중요
+var searchFieldWeights = []struct {
+	Name   string
+	Weight float64
+}{
+	{"name", 1.0},
+	{"email", 0.8},
+	{"job_title", 0.6},
+	{"phone", 0.5},
+}
+
+// 검색 조건
+const (
+	searchMinScore      = 0.3  // 이보다 낮으면 결과에서 제외
+	searchTrigramRatio  = 0.3  // 검색어 trigram 중 이 비율 이상 겹치는 사원을 후보로
+	searchMaxCandidates = 1000 // 후보 수 제한. 점수 계산은 메모리에서
+)
+
+// 악센트가 있는 라틴 문자 -> 기본 문자
+var accentFolding = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z", 'æ': "ae", 'œ': "oe",
+}
+
+/* 소문자로 바꾸고 악센트를 제거. 문자, 숫자, @ . 외에는 공백으로 */
+func normalizeSearch(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if folded, ok := accentFolding[r]; ok {
+			b.WriteString(folded)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '@' || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+/* 단어마다 앞에 공백 2개, 뒤에 1개를 붙여 3글자씩 자름 (pg_trgm과 같은 방식) */
+func trigrams(value string) []string {
+	var result []string
+	for _, word := range strings.Fields(value) {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			trigram := string(runes[i : i+3])
+			if !containsString(result, trigram) {
+				result = append(result, trigram)
+			}
+		}
+	}
+	return result
+}
+
+func newEmployeeSearch(employee Employee) EmployeeSearch {
+	return EmployeeSearch{
+		EmployeeID: employee.ID,
+		Name:       normalizeSearch(employee.Employee_Name),
+		Email:      normalizeSearch(employee.Email),
+		JobTitle:   normalizeSearch(employee.JobTitle),
+		Phone:      normalizeSearch(employee.Phone),
+	}
+}
+
+func (s EmployeeSearch) fields() map[string]string {
+	return map[string]string{"name": s.Name, "email": s.Email, "job_title": s.JobTitle, "phone": s.Phone}
+}
+
+/* 사원들의 검색 색인을 다시 만듦. 사원 정보가 바뀌는 곳(recordEmployeeHistory)에서 호출 */
+func indexEmployees(tx *gorm.DB, employees []Employee) error {
+	if len(employees) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(employees))
+	for i, employee := range employees {
+		ids[i] = employee.ID
+	}
+	err := tx.Where("employee_id IN ?", ids).Delete(&EmployeeSearch{}).Error
+	if err == nil {
+		err = tx.Where("employee_id IN ?", ids).Delete(&EmployeeTrigram{}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	searches := make([]EmployeeSearch, 0, len(employees))
+	var grams []EmployeeTrigram
+	for _, employee := range employees {
+		search := newEmployeeSearch(employee)
+		searches = append(searches, search)
+		// 전화번호는 숫자라서 오타 검색에서 제외
+		for _, trigram := range trigrams(search.Name + " " + search.Email + " " + search.JobTitle) {
+			grams = append(grams, EmployeeTrigram{EmployeeID: employee.ID, Trigram: trigram})
+		}
+	}
+	err = tx.Create(&searches).Error
+	if err == nil && len(grams) > 0 {
+		err = tx.CreateInBatches(&grams, 500).Error
+	}
+	return err
+}
+
+/* 글자(rune) 단위 편집 거리 */
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+/* 단어 길이에 따라 허용하는 오타 수. 짧은 단어는 오타를 허용하지 않음 */
+func allowedTypos(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+/* 검색어 단어 하나와 항목 단어 하나의 점수 */
+func wordScore(token string, word string) float64 {
+	switch {
+	case word == token:
+		return 1
+	case strings.HasPrefix(word, token):
+		return 0.9
+	case strings.Contains(word, token):
+		return 0.7
+	}
+	distance := levenshtein(token, word)
+	// 항목 단어가 더 길면 같은 길이의 앞부분과도 비교 (접두어 오타)
+	if prefix := []rune(word); len(prefix) > len([]rune(token)) {
+		distance = minInt(distance, levenshtein(token, string(prefix[:len([]rune(token))])))
+	}
+	if distance > allowedTypos(token) {
+		return 0
+	}
+	return 0.8 * (1 - float64(distance)/float64(len([]rune(token))+1))
+}
+
+/* 정규화된 항목 값과 검색어의 점수. 전체 일치 > 앞부분 > 포함 > 단어별 일치, 오타 */
+func fieldScore(value string, query string) float64 {
+	switch {
+	case value == "":
+		return 0
+	case value == query:
+		return 1
+	case strings.HasPrefix(value, query):
+		return 0.95
+	case strings.Contains(value, query):
+		return 0.85
+	}
+
+	words := strings.Fields(value)
+	tokens := strings.Fields(query)
+	total := 0.0
+	for _, token := range tokens {
+		best := 0.0
+		for _, word := range words {
+			best = math.Max(best, wordScore(token, word))
+		}
+		total += best
+	}
+	return 0.8 * total / float64(len(tokens))
+}
+
+/* 사원 하나의 점수와 일치한 항목 */
+func scoreEmployee(search EmployeeSearch, query string) (float64, []string) {
+	values := search.fields()
+	score := 0.0
+	matched := []string{}
+	for _, field := range searchFieldWeights {
+		s := fieldScore(values[field.Name], query) * field.Weight
+		if s >= searchMinScore {
+			matched = append(matched, field.Name)
+		}
+		score = math.Max(score, s)
+	}
+	return math.Round(score*1000) / 1000, matched
+}
+
+/* 검색어가 포함되거나 trigram이 충분히 겹치는 사원 id */
+func searchCandidates(tx *gorm.DB, query string) ([]uint, error) {
+	var ids []uint
+	like := "%" + escapeLike(query) + "%"
+	err := tx.Model(&EmployeeSearch{}).
+		Where("name LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!' OR job_title LIKE ? ESCAPE '!' OR phone LIKE ? ESCAPE '!'", like, like, like, like).
+		Limit(searchMaxCandidates).Pluck("employee_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	grams := trigrams(query)
+	if len(grams) == 0 {
+		return ids, nil
+	}
+	minShared := int(math.Ceil(float64(len(grams)) * searchTrigramRatio))
+	var fuzzy []uint
+	err = tx.Model(&EmployeeTrigram{}).Select("employee_id").Where("trigram IN ?", grams).
+		Group("employee_id").Having("COUNT(*) >= ?", minShared).
+		Order("COUNT(*) DESC").Limit(searchMaxCandidates).Pluck("employee_id", &fuzzy).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range fuzzy {
+		if !containsUint(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+/* LIKE의 %, _ 를 문자 그대로 찾도록 */
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+func containsUint(list []uint, value uint) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* 이름, 이메일, 직함, 전화번호로 사원 검색(?q=). 대소문자, 악센트, 오타를 허용하고 점수순. ?department=로 부서(하위 부서 포함) 제한 */
+func SearchEmployees(c *gin.Context) {
+	query := normalizeSearch(c.Query("q"))
+	if query == "" {
+		AbortWithError(c, fieldError("q", "required", "q is required"))
+		return
+	}
+	pagination, apiErr := Paging(c, &Employee{})
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+	if pagination.UseCursor {
+		AbortWithError(c, InvalidParameter("cursor", "Search results are sorted by score. Use page and limit"))
+		return
+	}
+	scope, apiErr := includeDeleted(c, db)
+	if apiErr != nil {
+		AbortWithError(c, apiErr)
+		return
+	}
+
+	// 검색 결과와 관계없이 없는 부서는 404
+	var departmentIDs []uint
+	if name := c.Query("department"); name != "" {
+		var department Department
+		db.Where("Department_Name = ?", name).Find(&department)
+		if department.ID == 0 {
+			AbortWithError(c, NewApiError(http.StatusNotFound, CodeDepartmentNotFound, "No such department"))
+			return
+		}
+		descendants, err := findDescendants(db, department.ID)
+		if err != nil {
+			AbortWithInternalError(c, err, "Search error")
+			return
+		}
+		departmentIDs = []uint{department.ID}
+		for _, descendant := range descendants {
+			departmentIDs = append(departmentIDs, descendant.ID)
+		}
+	}
+
+	ids, err := searchCandidates(db, query)
+	if err != nil {
+		AbortWithInternalError(c, err, "Search error")
+		return
+	}
+
+	if len(departmentIDs) > 0 && len(ids) > 0 {
+		var members []uint
+		err = db.Model(&Assignment{}).Distinct("employee_id").
+			Where("employee_id IN ? AND department_id IN ?", ids, departmentIDs).Pluck("employee_id", &members).Error
+		if err != nil {
+			AbortWithInternalError(c, err, "Search error")
+			return
+		}
+		ids = members
+	}
+
+	// 삭제 여부는 employees Table 기준
+	var searches []EmployeeSearch
+	if len(ids) > 0 {
+		var visible []uint
+		err = scope.Model(&Employee{}).Where("id IN ?", ids).Pluck("id", &visible).Error
+		if err == nil {
+			err = db.Where("employee_id IN ?", visible).Find(&searches).Error
+		}
+		if err != nil {
+			AbortWithInternalError(c, err, "Search error")
+			return
+		}
+	}
+
+	hits := make([]SearchHit, 0, len(searches))
+	for _, search := range searches {
+		score, matched := scoreEmployee(search, query)
+		if score >= searchMinScore {
+			hits = append(hits, SearchHit{Employee: EmployeeResponse{ID: search.EmployeeID}, Score: score, Matched: matched})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Employee.ID < hits[j].Employee.ID
+	})
+
+	total := int64(len(hits))
+	start := minInt((pagination.Page-1)*pagination.Limit, len(hits))
+	hits = hits[start:minInt(start+pagination.Limit, len(hits))]
+
+	// 현재 페이지의 사원만 불러옴
+	if len(hits) > 0 {
+		pageIDs := make([]uint, len(hits))
+		for i, hit := range hits {
+			pageIDs[i] = hit.Employee.ID
+		}
+		var employees []Employee
+		err = scope.Where("id IN ?", pageIDs).Preload("Employee_Departments").Find(&employees).Error
+		if err != nil {
+			AbortWithInternalError(c, err, "Search error")
+			return
+		}
+		for i := range hits {
+			for _, employee := range employees {
+				if employee.ID == hits[i].Employee.ID {
+					hits[i].Employee = newEmployeeResponse(employee)
+				}
+			}
+		}
+	}
+
+	response := &PageResponse{Data: hits, Total: &total, Limit: pagination.Limit, Page: pagination.Page}
+	if int64(pagination.Page*pagination.Limit) < total {
+		response.Next = pagination.link(c, "page", strconv.Itoa(pagination.Page+1))
+	}
+	if pagination.Page > 1 {
+		response.Prev = pagination.link(c, "page", strconv.Itoa(pagination.Page-1))
+	}
+	setLinkHeader(c, response)
+	c.JSON(http.StatusOK, response)
+}
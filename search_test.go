@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func searchTestData(t *testing.T) (*gin.Engine, string) {
+	token, err := GenerateToken("gotest", "myCA", RoleViewer)
+	assert.NoError(t, err)
+
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.GET("/api/employee/search", SearchEmployees)
+	router.POST("/api/employee/", AddEmployee)
+
+	admin, err := GenerateToken("gotest", "myCA", RoleAdmin)
+	assert.NoError(t, err)
+	db.Create(&Department{Department_Name: "Search Root"})
+	var root Department
+	db.Where("Department_Name = ?", "Search Root").Find(&root)
+	db.Create(&Department{Department_Name: "Search Child", ParentID: &root.ID})
+	db.Create(&Department{Department_Name: "Search Other"})
+
+	// 색인은 사원 생성 API에서 만들어짐
+	w := orgRequest(router, admin, "POST", "/api/employee/", `[
+		{"ename": "Kim Minsu", "dname": "Search Child", "email": "minsu.kim@example.com", "job_title": "Backend Engineer"},
+		{"ename": "Minsu Searchlee", "dname": "Search Other", "job_title": "Designer"},
+		{"ename": "José Searchgarcía", "dname": "Search Root", "phone": "+82 10-5555-1234"}
+	]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	t.Cleanup(func() {
+		var employees []Employee
+		db.Unscoped().Where("Employee_Name IN ?", []string{"Kim Minsu", "Minsu Searchlee", "José Searchgarcía"}).Find(&employees)
+		for _, employee := range employees {
+			db.Unscoped().Where("employee_id = ?", employee.ID).Delete(&Assignment{})
+			db.Where("employee_id = ?", employee.ID).Delete(&EmployeeSearch{})
+			db.Where("employee_id = ?", employee.ID).Delete(&EmployeeTrigram{})
+			db.Unscoped().Delete(&employee)
+		}
+		db.Unscoped().Where("Department_Name IN ?", []string{"Search Child", "Search Other"}).Delete(&Department{})
+		db.Unscoped().Delete(&root)
+	})
+
+	return router, token
+}
+
+func searchEmployees(t *testing.T, router *gin.Engine, token string, query string) (int, []SearchHit, PageResponse) {
+	var hits []SearchHit
+	response := PageResponse{Data: &hits}
+	w := orgRequest(router, token, "GET", "/api/employee/search?"+query, "")
+	if w.Code == http.StatusOK {
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+	}
+	return w.Code, hits, response
+}
+
+func hitNames(hits []SearchHit) []string {
+	names := make([]string, len(hits))
+	for i, hit := range hits {
+		names[i] = hit.Employee.Name
+	}
+	return names
+}
+
+func TestSearchEmployees(t *testing.T) {
+	router, token := searchTestData(t)
+
+	// 대소문자 무시, 앞부분 일치가 우선
+	code, hits, response := searchEmployees(t, router, token, "q=kim")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotEmpty(t, hits) {
+		assert.Equal(t, "Kim Minsu", hits[0].Employee.Name)
+		assert.Contains(t, hits[0].Matched, "name")
+		assert.Equal(t, 1, len(hits[0].Employee.Departments))
+	}
+	assert.Equal(t, int64(len(hits)), *response.Total)
+
+	// 두 사원 모두 이름에 minsu가 있지만 앞부분이 일치하는 사원이 먼저
+	_, hits, _ = searchEmployees(t, router, token, "q=minsu+searchlee")
+	assert.Equal(t, "Minsu Searchlee", hits[0].Employee.Name)
+
+	// 악센트 무시
+	_, hits, _ = searchEmployees(t, router, token, "q="+url.QueryEscape("jose searchgarcia"))
+	assert.Contains(t, hitNames(hits), "José Searchgarcía")
+	_, hits, _ = searchEmployees(t, router, token, "q="+url.QueryEscape("Searchgarcía"))
+	assert.Contains(t, hitNames(hits), "José Searchgarcía")
+
+	// 오타 허용
+	_, hits, _ = searchEmployees(t, router, token, "q=searchlea")
+	assert.Contains(t, hitNames(hits), "Minsu Searchlee")
+	_, hits, _ = searchEmployees(t, router, token, "q=searchgracia")
+	assert.Contains(t, hitNames(hits), "José Searchgarcía")
+
+	// 이름 외의 항목
+	_, hits, _ = searchEmployees(t, router, token, "q=backend")
+	if assert.Contains(t, hitNames(hits), "Kim Minsu") {
+		assert.Contains(t, hits[0].Matched, "job_title")
+	}
+	_, hits, _ = searchEmployees(t, router, token, "q=5555")
+	assert.Contains(t, hitNames(hits), "José Searchgarcía")
+}
+
+func TestSearchEmployeesFilterAndPaging(t *testing.T) {
+	router, token := searchTestData(t)
+
+	// 하위 부서의 사원도 포함
+	_, hits, _ := searchEmployees(t, router, token, "q=kim&department=Search+Root")
+	assert.Contains(t, hitNames(hits), "Kim Minsu")
+	_, hits, _ = searchEmployees(t, router, token, "q=searchgarcia&department=Search+Root")
+	assert.Contains(t, hitNames(hits), "José Searchgarcía")
+	_, hits, _ = searchEmployees(t, router, token, "q=searchlee&department=Search+Root")
+	assert.Empty(t, hits)
+
+	_, hits, response := searchEmployees(t, router, token, "q=minsu&department=Search+Root&limit=1")
+	assert.Equal(t, 1, len(hits))
+	assert.Empty(t, response.Next)
+
+	_, hits, response = searchEmployees(t, router, token, "q=minsu&limit=1")
+	assert.Equal(t, 1, len(hits))
+	if assert.NotEmpty(t, response.Next) {
+		_, next, _ := searchEmployees(t, router, token, response.Next[len("/api/employee/search?"):])
+		assert.Equal(t, 1, len(next))
+		assert.NotEqual(t, hits[0].Employee.ID, next[0].Employee.ID)
+	}
+
+	code, _, _ := searchEmployees(t, router, token, "q=%20")
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	code, _, _ = searchEmployees(t, router, token, "q=kim&department=Search+Nowhere")
+	assert.Equal(t, http.StatusNotFound, code)
+	// 검색 결과가 없어도 같은 응답
+	code, _, _ = searchEmployees(t, router, token, "q=zzqxwv&department=Search+Nowhere")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = searchEmployees(t, router, token, "q=kim&cursor=")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSearchScore(t *testing.T) {
+	assert.Equal(t, "jose garcia o brien minsu.kim@example.com", normalizeSearch("  José GARCÍA O'Brien Minsu.Kim@example.com"))
+	assert.Equal(t, 2, levenshtein("searchgarcia", "searchgracia"))
+	assert.Equal(t, 0.0, wordScore("ab", "ac")) // 짧은 단어는 오타 허용 안 함
+	assert.True(t, fieldScore("kim minsu", "kim minsu") > fieldScore("kim minsu", "kim"))
+	assert.True(t, fieldScore("kim minsu", "kim") > fieldScore("kim minsu", "minsu"))
+	assert.True(t, fieldScore("kim minsu", "minsu") > fieldScore("kim minsu", "minso"))
+}
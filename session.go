@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const refreshTokenTTL = 14 * 24 * time.Hour // Refresh Token 유효기간 14일
+
+var (
+	errInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	errRefreshTokenReused  = errors.New("refresh token already used. All sessions are revoked")
+)
+
+// Refresh Token Table. 토큰 원문은 저장하지 않고 hash만 저장
+type RefreshToken struct {
+	ID         uint   `gorm:"primaryKey"`
+	AccountID  uint   `gorm:"index"`
+	TokenHash  string `gorm:"size:64;uniqueIndex"`
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy uint // 회전(rotation)으로 새로 발급된 토큰의 ID
+	CreatedAt  time.Time
+}
+
+// 로그아웃된 Access Token의 jti. 토큰 만료 시간까지만 보관
+type RevokedToken struct {
+	Jti       string `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time
+}
+
+type refreshData struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type logoutData struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+/* 랜덤 문자열 토큰 생성 (jti, refresh token) */
+func newRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/* 새 Refresh Token을 발급해서 저장하고 원문을 반환 */
+func IssueRefreshToken(tx *gorm.DB, accountID uint) (token string, record RefreshToken, err error) {
+	token, err = newRandomToken(32)
+	if err != nil {
+		return
+	}
+
+	record = RefreshToken{
+		AccountID: accountID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+	err = tx.Create(&record).Error
+	return
+}
+
+/* Access Token의 jti가 로그아웃으로 무효화 되었는지 확인 */
+func IsTokenRevoked(jti string) bool {
+	if jti == "" {
+		return false
+	}
+
+	var count int64
+	db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count)
+	return count > 0
+}
+
+/* Refresh Token으로 새 Access Token 발급. 사용한 Refresh Token은 폐기하고 새로 발급(rotation) */
+func RefreshSession(c *gin.Context) {
+	var data refreshData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		AbortWithBindError(c, err)
+		return
+	}
+
+	var account Account
+	var newToken string
+	reused := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var record RefreshToken
+		tx.Where("token_hash = ?", hashToken(data.RefreshToken)).Find(&record)
+		if record.ID == 0 || record.ExpiresAt.Before(time.Now()) {
+			return errInvalidRefreshToken
+		}
+
+		// 이미 사용된 토큰이 다시 오면 탈취로 보고 해당 계정의 모든 Refresh Token 폐기
+		if record.RevokedAt != nil {
+			reused = true
+			return revokeAllRefreshTokens(tx, record.AccountID)
+		}
+
+		tx.Where("id = ?", record.AccountID).Find(&account)
+		if account.ID == 0 {
+			return errInvalidRefreshToken
+		}
+
+		// 동시에 같은 토큰으로 요청이 와도 폐기에 성공한 요청만 새 토큰을 받음
+		now := time.Now()
+		result := tx.Model(&RefreshToken{}).Where("id = ? AND revoked_at IS NULL", record.ID).Update("revoked_at", &now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return revokeAllRefreshTokens(tx, record.AccountID)
+		}
+
+		token, next, err := IssueRefreshToken(tx, account.ID)
+		if err != nil {
+			return err
+		}
+		newToken = token
+
+		return tx.Model(&RefreshToken{}).Where("id = ?", record.ID).Update("replaced_by", next.ID).Error
+	})
+	if err == nil && reused {
+		err = errRefreshTokenReused
+	}
+	if errors.Is(err, errInvalidRefreshToken) || errors.Is(err, errRefreshTokenReused) {
+		AbortWithError(c, NewApiError(http.StatusUnauthorized, CodeInvalidRefreshToken, err.Error()))
+		return
+	} else if err != nil {
+		AbortWithInternalError(c, err, "Error on Refresh token")
+		return
+	}
+
+	// 권한이 바뀌었을 수 있으므로 DB의 role로 새 토큰 발급
+	jwtToken, err := GenerateToken(account.Email, account.CA, account.Role)
+	if err != nil {
+		AbortWithInternalError(c, err, "Error on Create JWT token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"JWT":           jwtToken,
+		"refresh_token": newToken,
+	})
+}
+
+/* 로그아웃. 현재 Access Token의 jti를 무효화하고, 받은 Refresh Token도 폐기 */
+func Logout(c *gin.Context) {
+	var data logoutData
+	if c.Request.ContentLength > 0 {
+		err := c.ShouldBindJSON(&data)
+		if err != nil {
+			AbortWithBindError(c, err)
+			return
+		}
+	}
+
+	now := time.Now()
+	jti := c.GetString("jti")
+	if jti != "" {
+		result := db.Create(&RevokedToken{Jti: jti, ExpiresAt: time.Unix(c.GetInt64("exp"), 0)})
+		if result.Error != nil {
+			AbortWithInternalError(c, result.Error, "Error on Logout")
+			return
+		}
+	}
+
+	if data.RefreshToken != "" {
+		// 다른 계정의 Refresh Token은 폐기하지 않음
+		account := currentAccount(c)
+		result := db.Model(&RefreshToken{}).
+			Where("token_hash = ? AND account_id = ? AND revoked_at IS NULL", hashToken(data.RefreshToken), account.ID).
+			Update("revoked_at", &now)
+		if result.Error != nil {
+			AbortWithInternalError(c, result.Error, "Error on Logout")
+			return
+		}
+	}
+
+	// 만료된 블랙리스트는 더 이상 필요 없음
+	result := db.Where("expires_at < ?", now).Delete(&RevokedToken{})
+	if result.Error != nil {
+		AbortWithInternalError(c, result.Error, "Error on Logout")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"msg": "Logout Complete",
+	})
+}
+
+func revokeAllRefreshTokens(tx *gorm.DB, accountID uint) error {
+	return tx.Model(&RefreshToken{}).Where("account_id = ? AND revoked_at IS NULL", accountID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRefreshSession(t *testing.T) {
+	router := gin.Default()
+	router.POST("/auth/refresh", RefreshSession)
+
+	account := Account{Email: "refresh@test.com", CA: "GOOGLE", Role: RoleEditor}
+	db.Create(&account)
+	refreshToken, _, err := IssueRefreshToken(db, account.ID)
+	assert.NoError(t, err)
+
+	var result map[string]interface{}
+	payload, _ := json.Marshal(refreshData{RefreshToken: refreshToken})
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(payload))
+	router.ServeHTTP(w, request)
+
+	err = json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, refreshToken, result["refresh_token"])
+
+	claims, err := ValidateToken(result["JWT"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, RoleEditor, claims.Role)
+
+	// 이미 사용한 Refresh Token을 다시 사용하면 새로 발급된 토큰까지 폐기
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(payload))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	payload, _ = json.Marshal(refreshData{RefreshToken: result["refresh_token"].(string)})
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(payload))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestRefreshSessionConcurrentReuse(t *testing.T) {
+	router := gin.Default()
+	router.POST("/auth/refresh", RefreshSession)
+
+	account := Account{Email: "refresh-race@test.com", CA: "GOOGLE", Role: RoleEditor}
+	db.Create(&account)
+	refreshToken, _, err := IssueRefreshToken(db, account.ID)
+	assert.NoError(t, err)
+
+	// 토큰을 읽은 직후 다른 요청이 먼저 같은 토큰을 사용한 상황
+	used := false
+	err = db.Callback().Query().After("gorm:query").Register("test:refresh_race", func(tx *gorm.DB) {
+		if used || tx.Statement.Table != "refresh_tokens" {
+			return
+		}
+		used = true
+		tx.Session(&gorm.Session{NewDB: true}).Model(&RefreshToken{}).
+			Where("token_hash = ?", hashToken(refreshToken)).Update("revoked_at", time.Now())
+	})
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Callback().Query().Remove("test:refresh_race") })
+
+	payload, _ := json.Marshal(refreshData{RefreshToken: refreshToken})
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/refresh", bytes.NewBuffer(payload))
+	router.ServeHTTP(w, request)
+	assert.True(t, used)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 재사용으로 보고 계정의 Refresh Token을 모두 폐기
+	var count int64
+	db.Model(&RefreshToken{}).Where("account_id = ? AND revoked_at IS NULL", account.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestRefreshSessionInvalidToken(t *testing.T) {
+	router := gin.Default()
+	router.POST("/auth/refresh", RefreshSession)
+
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/refresh", bytes.NewBufferString(`{"refresh_token": "invalid"}`))
+	router.ServeHTTP(w, request)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLogout(t *testing.T) {
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/auth/logout", Logout)
+	router.GET("/api/employee/", ReadEmployee)
+
+	account := Account{Email: "logout@test.com", CA: "GOOGLE", Role: RoleViewer}
+	db.Create(&account)
+	refreshToken, _, err := IssueRefreshToken(db, account.ID)
+	assert.NoError(t, err)
+
+	token, err := GenerateToken(account.Email, account.CA, account.Role)
+	assert.NoError(t, err)
+
+	payload, _ := json.Marshal(logoutData{RefreshToken: refreshToken})
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/logout", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 로그아웃한 토큰은 더 이상 사용 불가
+	w = httptest.NewRecorder()
+	request, _ = http.NewRequest("GET", "/api/employee/", nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var record RefreshToken
+	db.Where("token_hash = ?", hashToken(refreshToken)).Find(&record)
+	assert.NotNil(t, record.RevokedAt)
+
+	db.Where("account_id = ?", account.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id = ?", account.ID).Delete(&Account{})
+}
+
+func TestLogoutOtherAccountRefreshToken(t *testing.T) {
+	router := gin.Default()
+	router.Use(AuthorizeAccount())
+	router.POST("/auth/logout", Logout)
+
+	owner := Account{Email: "logout-owner@test.com", CA: "GOOGLE", Role: RoleViewer}
+	db.Create(&owner)
+	other := Account{Email: "logout-other@test.com", CA: "GOOGLE", Role: RoleViewer}
+	db.Create(&other)
+	refreshToken, _, err := IssueRefreshToken(db, owner.ID)
+	assert.NoError(t, err)
+
+	token, err := GenerateToken(other.Email, other.CA, other.Role)
+	assert.NoError(t, err)
+
+	// 다른 계정의 Refresh Token을 보내도 폐기되지 않음
+	payload, _ := json.Marshal(logoutData{RefreshToken: refreshToken})
+	w := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/auth/logout", bytes.NewBuffer(payload))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var record RefreshToken
+	db.Where("token_hash = ?", hashToken(refreshToken)).Find(&record)
+	assert.Nil(t, record.RevokedAt)
+
+	db.Where("account_id = ?", owner.ID).Delete(&RefreshToken{})
+	db.Unscoped().Where("id IN ?", []uint{owner.ID, other.ID}).Delete(&Account{})
+}
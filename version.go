@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// API 버전. 응답 형식이 바뀌면 새 버전을 추가하고 이전 버전은 Deprecation, Sunset header로 알림
+type apiVersion struct {
+	Number     int
+	Name       string    // path 이름 (/api/v1)
+	Deprecated time.Time // 다음 버전이 나온 시점. zero면 현재 버전
+	Sunset     time.Time // 제거 예정 시점. zero면 정해지지 않음
+}
+
+// v2가 나온 날. v1은 이때부터 deprecated
+var apiV2Released = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+// v1 제거 예정일 기본값(v2 이후 개월 수). API_V1_SUNSET(2006-01-02)으로 변경
+const defaultV1SunsetMonths = 6
+
+/* 지원하는 API 버전 목록 (오래된 버전부터) */
+func apiVersions() []apiVersion {
+	sunset := apiV2Released.AddDate(0, defaultV1SunsetMonths, 0)
+	if value := os.Getenv("API_V1_SUNSET"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			log.Println("API_V1_SUNSET should be 2006-01-02. Using", sunset.Format("2006-01-02"))
+		} else {
+			sunset = t
+		}
+	}
+
+	return []apiVersion{
+		{Number: 1, Name: "v1", Deprecated: apiV2Released, Sunset: sunset},
+		{Number: 2, Name: "v2"},
+	}
+}
+
+/* deprecated 버전이면 모든 응답에 Deprecation(RFC 9745), Sunset(RFC 8594) header 추가 */
+func (version apiVersion) Headers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !version.Deprecated.IsZero() {
+			c.Header("Deprecation", "@"+strconv.FormatInt(version.Deprecated.Unix(), 10))
+			if !version.Sunset.IsZero() {
+				c.Header("Sunset", version.Sunset.UTC().Format(http.TimeFormat))
+			}
+		}
+		c.Next()
+	}
+}
+
+/* 버전별 handler 선택. handlers는 v1부터 순서대로이고, 없는 버전은 마지막 handler를 그대로 사용 */
+func (version apiVersion) Handler(handlers ...gin.HandlerFunc) gin.HandlerFunc {
+	if version.Number > len(handlers) {
+		return handlers[len(handlers)-1]
+	}
+	return handlers[version.Number-1]
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// 엑셀 sheet의 최대 열, 행 수 (XFD1048576)
+const (
+	xlsxMaxColumns = 16384
+	xlsxMaxRows    = 1048576
+)
+
+// xlsx의 sharedStrings.xml. 셀의 문자열은 index로 참조
+type xlsxSharedStrings struct {
+	Items []xlsxString `xml:"si"`
+}
+
+// 문자열 하나. 서식이 있으면 여러 run으로 나뉨
+type xlsxString struct {
+	Text string    `xml:"t"`
+	Runs []xlsxRun `xml:"r"`
+}
+
+type xlsxRun struct {
+	Text string `xml:"t"`
+}
+
+// worksheet의 sheetData
+type xlsxSheet struct {
+	Rows []xlsxRow `xml:"sheetData>row"`
+}
+
+type xlsxRow struct {
+	Number int        `xml:"r,attr"` // 1부터 시작
+	Cells  []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref    string      `xml:"r,attr"` // A1 형식의 위치. 빈 셀은 생략됨
+	Type   string      `xml:"t,attr"` // s: 공유 문자열, inlineStr, str, b, n(기본)
+	Value  string      `xml:"v"`
+	Inline *xlsxString `xml:"is"`
+}
+
+// workbook.xml과 relationship. 첫 번째 sheet의 파일 위치를 찾을 때 사용
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+func (s xlsxString) text() string {
+	if len(s.Runs) == 0 {
+		return s.Text
+	}
+	var b strings.Builder
+	for _, run := range s.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+/* xlsx 파일의 첫 번째 sheet를 csv와 같은 [][]string으로 읽음. 수식은 저장된 결과 값을 사용 */
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("xlsx file is invalid")
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		err = decodeXLSXFile(file, &shared)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, errors.New("xlsx file has no worksheet")
+	}
+	var sheet xlsxSheet
+	err = decodeXLSXFile(file, &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		// 빈 행은 생략되므로 행 번호까지 채움
+		for row.Number > 0 && len(records) < row.Number-1 && len(records) < xlsxMaxRows {
+			records = append(records, nil)
+		}
+		var record []string
+		for i, cell := range row.Cells {
+			column := i
+			if ref := xlsxColumn(cell.Ref); ref >= 0 {
+				column = ref
+			}
+			if column >= xlsxMaxColumns {
+				return nil, errors.New("xlsx file has too many columns")
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, errors.New("xlsx file has invalid shared string in " + cell.Ref)
+				}
+				record[column] = shared.Items[index].text()
+			case "inlineStr":
+				if cell.Inline != nil {
+					record[column] = cell.Inline.text()
+				}
+			case "b":
+				record[column] = strconv.FormatBool(cell.Value == "1")
+			default:
+				record[column] = cell.Value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+/* workbook에 적힌 첫 번째 sheet. 찾지 못하면 기본 위치 */
+func firstSheetPath(files map[string]*zip.File) string {
+	const defaultPath = "xl/worksheets/sheet1.xml"
+
+	var workbook xlsxWorkbook
+	var relationships xlsxRelationships
+	file, ok := files["xl/workbook.xml"]
+	if !ok || decodeXLSXFile(file, &workbook) != nil || len(workbook.Sheets) == 0 {
+		return defaultPath
+	}
+	file, ok = files["xl/_rels/workbook.xml.rels"]
+	if !ok || decodeXLSXFile(file, &relationships) != nil {
+		return defaultPath
+	}
+	for _, item := range relationships.Items {
+		if item.ID == workbook.Sheets[0].RID {
+			if strings.HasPrefix(item.Target, "/") {
+				return strings.TrimPrefix(item.Target, "/")
+			}
+			return path.Join("xl", item.Target)
+		}
+	}
+	return defaultPath
+}
+
+func decodeXLSXFile(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	err = xml.NewDecoder(io.LimitReader(reader, maxImportSize*10)).Decode(v)
+	if err != nil {
+		return errors.New("xlsx file is invalid: " + file.Name)
+	}
+	return nil
+}
+
+/* "B12" -> 1. 열 이름(A, B, ..., AA)을 0부터 시작하는 index로. 열 이름이 없으면 -1 */
+func xlsxColumn(ref string) int {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}